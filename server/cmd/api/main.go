@@ -19,6 +19,7 @@ import (
 	"github.com/easyssh/server/internal/domain/auditlog"
 	"github.com/easyssh/server/internal/domain/auth"
 	"github.com/easyssh/server/internal/domain/batchtask"
+	"github.com/easyssh/server/internal/domain/cmdguard"
 	"github.com/easyssh/server/internal/domain/filetransfer"
 	"github.com/easyssh/server/internal/domain/monitor"
 	"github.com/easyssh/server/internal/domain/monitoring"
@@ -32,7 +33,9 @@ import (
 	"github.com/easyssh/server/internal/domain/sshkey"
 	"github.com/easyssh/server/internal/domain/sshsession"
 	"github.com/easyssh/server/internal/domain/tabsession"
+	"github.com/easyssh/server/internal/domain/tunnel"
 	"github.com/easyssh/server/internal/domain/user"
+	"github.com/easyssh/server/internal/domain/webproxy"
 	"github.com/easyssh/server/internal/infra/cache"
 	"github.com/easyssh/server/internal/infra/config"
 	"github.com/easyssh/server/internal/infra/db"
@@ -88,6 +91,13 @@ func main() {
 		&sshkey.SSHKey{},                 // SSH密钥表
 		&sshhostkey.SSHHostKey{},         // SSH主机密钥表（TOFU安全验证）
 		&tabsession.TabSessionSettings{}, // 标签/会话设置表
+
+		&script.ScriptVersion{},            // 脚本版本历史表
+		&batchtask.BatchTaskResult{},       // 批量任务单机执行结果表
+		&scheduledtask.ScheduledTaskRun{},  // 定时任务运行记录表
+		&sshsession.SSHSessionTranscript{}, // SSH会话终端输出文本表（全文检索）
+		&tunnel.Tunnel{},                   // 端口转发隧道表
+		&cmdguard.Rule{},                   // 危险命令规则表
 	); err != nil {
 		log.Fatalf("❌ Failed to migrate database: %v", err)
 	}
@@ -125,6 +135,9 @@ func main() {
 	}
 	log.Println("✅ Configuration manager initialized with 5-minute cache")
 
+	// 所有 SSH 连接（终端、SFTP、监控、批量任务等）按全局代理设置出站
+	ssh.SetProxyProvider(configManager.GetProxyConfig)
+
 	// IP 白名单服务
 	ipWhitelistRepo := settings.NewIPWhitelistRepository(database)
 	ipWhitelistService := settings.NewIPWhitelistService(ipWhitelistRepo)
@@ -183,8 +196,23 @@ func main() {
 	// SSH 会话管理器
 	sessionManager := ssh.NewSessionManager()
 
-	// 监控连接池（独立于终端会话）
-	monitorConnectionPool := monitor.NewConnectionPool(serverService, encryptor)
+	// SSH 连接管理器（终端、SFTP、监控、反向代理按用户和服务器共享连接）
+	sshConnManager := ssh.NewConnectionManager(encryptor, sshHostKeyService.GetHostKeyCallback())
+	defer sshConnManager.Close()
+
+	// 终端空闲超时（超过标签/会话配置中的非活动时间没有输入输出时断开）
+	// 配置中的会话超时（SessionTimeout）是前端无操作自动退出登录的时间，不限制终端会话的时长
+	idleWatcher := ssh.NewIdleWatcher(sessionManager, func(ctx context.Context) time.Duration {
+		tabConfig, err := tabSessionService.GetTabSessionConfig(ctx)
+		if err != nil {
+			return 0
+		}
+		return tabConfig.IdleTimeout()
+	})
+	idleWatcher.Start()
+
+	// 监控连接池（按监控页面计数，SSH 连接由连接管理器共享）
+	monitorConnectionPool := monitor.NewConnectionPool(serverService, sshConnManager)
 	defer monitorConnectionPool.Close() // 程序退出时关闭连接池
 
 	// 审计日志服务
@@ -192,24 +220,76 @@ func main() {
 	auditLogService := auditlog.NewService(auditLogRepo)
 
 	// 监控服务
-	monitoringService := monitoring.NewService(serverService, encryptor)
+	monitoringService := monitoring.NewService(serverService, sshConnManager)
 
 	// 脚本服务
 	scriptRepo := script.NewRepository(database)
 	scriptService := script.NewService(scriptRepo)
+	scriptExecutor := script.NewExecutor(serverService, encryptor, sshHostKeyService.GetHostKeyCallback())
+	if svc, ok := scriptService.(interface{ SetExecutor(*script.Executor) }); ok {
+		svc.SetExecutor(scriptExecutor)
+	}
 
 	// 批量任务服务
 	batchTaskRepo := batchtask.NewRepository(database)
 	batchTaskService := batchtask.NewService(batchTaskRepo)
 
+	// 批量任务执行器（通过 SSH 在目标服务器上执行命令/脚本）
+	batchTaskExecutor := batchtask.NewExecutor(batchTaskService, serverService, scriptService, encryptor, sshHostKeyService.GetHostKeyCallback())
+	if svc, ok := batchTaskService.(interface{ SetExecutor(*batchtask.Executor) }); ok {
+		svc.SetExecutor(batchTaskExecutor)
+	}
+	if svc, ok := batchTaskService.(interface{ SetScriptService(script.Service) }); ok {
+		svc.SetScriptService(scriptService)
+	}
+
+	// 批量任务实时输出 WebSocket 处理器
+	batchTaskStreamHandler := ws.NewBatchTaskStreamHandler(batchTaskService)
+	batchTaskExecutor.SetPublisher(batchTaskStreamHandler)
+	if err := batchTaskService.RecoverInterruptedTasks(); err != nil {
+		log.Printf("⚠️ Warning: Failed to recover interrupted batch tasks: %v", err)
+	}
+
 	// 定时任务服务
 	scheduledTaskRepo := scheduledtask.NewRepository(database)
 	scheduledTaskService := scheduledtask.NewService(scheduledTaskRepo)
 
+	// 定时任务调度器（进程内 Cron，按任务时区触发）
+	scheduledTaskExecutor := scheduledtask.NewExecutor(serverService, scriptService, batchTaskService, encryptor, sshHostKeyService.GetHostKeyCallback())
+	scheduledTaskScheduler := scheduledtask.NewScheduler(scheduledTaskRepo, scheduledTaskExecutor)
+	if svc, ok := scheduledTaskService.(interface {
+		SetScheduler(*scheduledtask.Scheduler)
+	}); ok {
+		svc.SetScheduler(scheduledTaskScheduler)
+	}
+	if svc, ok := scheduledTaskService.(interface{ SetScriptService(script.Service) }); ok {
+		svc.SetScriptService(scriptService)
+	}
+	scheduledTaskScheduler.SetLocker(redisClient) // 多副本部署时保证每次触发只执行一次
+	if err := scheduledTaskScheduler.Start(); err != nil {
+		log.Printf("⚠️ Warning: Failed to start task scheduler: %v", err)
+	}
+
 	// SSH会话服务
 	sshSessionRepo := sshsession.NewRepository(database)
 	sshSessionService := sshsession.NewService(sshSessionRepo)
 
+	// 终端会话录像（asciicast v2），按录像配置中的保留天数定期清理
+	recordingStore := sshsession.NewRecordingStore(cfg.Server.RecordingDir)
+	if svc, ok := sshSessionService.(interface {
+		SetRecordingStore(*sshsession.RecordingStore)
+	}); ok {
+		svc.SetRecordingStore(recordingStore)
+	}
+	recordingJanitor := sshsession.NewRecordingJanitor(sshSessionService, func(ctx context.Context) int {
+		recordingConfig, err := configManager.GetRecordingConfig(ctx)
+		if err != nil {
+			return 0
+		}
+		return recordingConfig.RetentionDays
+	})
+	recordingJanitor.Start()
+
 	// 文件传输服务
 	fileTransferRepo := filetransfer.NewRepository(database)
 	fileTransferService := filetransfer.NewService(fileTransferRepo)
@@ -222,6 +302,22 @@ func main() {
 	sshKeyRepo := sshkey.NewRepository(database)
 	sshKeyService := sshkey.NewService(sshKeyRepo, cfg.Server.EncryptionKey)
 
+	// 端口转发隧道（启动时恢复运行中的隧道，断线后自动重连）
+	tunnelRepo := tunnel.NewRepository(database)
+	tunnelManager := tunnel.NewManager(tunnelRepo, serverService, encryptor, sshHostKeyService.GetHostKeyCallback())
+	tunnelManager.SetAuditLogService(auditLogService)
+	tunnelManager.SetPolicy(tunnel.NewPolicy(cfg.Tunnel.MinPort, cfg.Tunnel.MaxPort, cfg.Tunnel.RemoteTargets), userService)
+	tunnelService := tunnel.NewService(tunnelRepo, serverService, tunnelManager)
+	if err := tunnelManager.Restore(); err != nil {
+		log.Printf("⚠️ Warning: Failed to restore tunnels: %v", err)
+	}
+
+	// Web 反向代理（经由 SSH 访问服务器本机的 HTTP/WebSocket 服务）
+	webProxyDialer := webproxy.NewDialer(sshConnManager)
+
+	// 危险命令防护（交互终端中按规则阻止、确认或告警）
+	cmdGuardService := cmdguard.NewService(cmdguard.NewRepository(database))
+
 	// SFTP 上传 WebSocket 处理器
 	sftpUploadWSHandler := ws.NewSFTPUploadHandler()
 
@@ -233,8 +329,14 @@ func main() {
 	authHandler := rest.NewAuthHandler(authService, jwtService, configManager, accessTokenTTLSeconds, refreshTokenTTLSeconds)
 	serverHandler := rest.NewServerHandler(serverService)
 	sshHandler := rest.NewSSHHandler(sessionManager)
-	sftpHandler := rest.NewSFTPHandler(serverService, serverRepo, encryptor, sftpUploadWSHandler, sshHostKeyService.GetHostKeyCallback())
-	terminalHandler := ws.NewTerminalHandler(serverService, serverRepo, sessionManager, encryptor, sshSessionService, sshHostKeyService.GetHostKeyCallback(), *configManager)
+	adminSSHHandler := rest.NewAdminSSHHandler(sessionManager, sshConnManager, serverRepo, userRepo)
+	sftpHandler := rest.NewSFTPHandler(serverService, serverRepo, sshConnManager, sftpUploadWSHandler)
+	terminalHandler := ws.NewTerminalHandler(serverService, serverRepo, sessionManager, sshConnManager, sshSessionService, configManager)
+	terminalHandler.SetRecordingStore(recordingStore)
+	terminalHandler.SetTabSessionService(tabSessionService)
+	terminalHandler.SetAuditLogService(auditLogService)
+	terminalHandler.SetCommandGuard(cmdGuardService, settingsService.SendAlert)
+	playbackHandler := ws.NewPlaybackHandler(sshSessionService)
 	monitorHandler := ws.NewMonitorHandler(monitorConnectionPool)
 	auditLogHandler := rest.NewAuditLogHandler(auditLogService)
 	monitoringHandler := rest.NewMonitoringHandler(monitoringService)
@@ -246,6 +348,9 @@ func main() {
 	userHandler := rest.NewUserHandler(userService)
 	settingsHandler := rest.NewSettingsHandler(settingsService, ipWhitelistService, tabSessionService)
 	sshKeyHandler := rest.NewSSHKeyHandler(sshKeyService)
+	tunnelHandler := rest.NewTunnelHandler(tunnelService)
+	webProxyHandler := rest.NewWebProxyHandler(serverService, webProxyDialer, auditLogService)
+	commandRuleHandler := rest.NewCommandRuleHandler(cmdGuardService)
 	avatarHandler := rest.NewAvatarHandler()
 
 	// 创建 Gin 路由
@@ -367,12 +472,39 @@ func main() {
 		{
 			// WebSocket 终端
 			sshRoutes.GET("/terminal/:server_id", terminalHandler.HandleSSH)
+			sshRoutes.GET("/sessions/:id/attach", terminalHandler.HandleAttach) // 重新连接或加入共享会话
 
 			// 会话管理 REST API
 			sshRoutes.GET("/sessions", sshHandler.ListSessions)        // 会话列表
 			sshRoutes.GET("/sessions/:id", sshHandler.GetSession)      // 会话详情
 			sshRoutes.DELETE("/sessions/:id", sshHandler.CloseSession) // 关闭会话
 			sshRoutes.GET("/statistics", sshHandler.GetStatistics)     // 统计信息
+
+			sshRoutes.GET("/sessions/shared", sshHandler.ListSharedSessions)          // 共享给我的会话
+			sshRoutes.GET("/sessions/:id/shares", sshHandler.ListShares)              // 共享授权列表
+			sshRoutes.POST("/sessions/:id/shares", sshHandler.ShareSession)           // 共享会话
+			sshRoutes.DELETE("/sessions/:id/shares/:user_id", sshHandler.RevokeShare) // 撤销共享
+		}
+
+		// 管理员实时会话监控（需要管理员权限）
+		adminSSHRoutes := v1.Group("/admin/ssh")
+		adminSSHRoutes.Use(middleware.AuthMiddleware(jwtService), middleware.RequireAdmin())
+		{
+			adminSSHRoutes.GET("/sessions", adminSSHHandler.ListSessions)                    // 所有用户的活跃会话
+			adminSSHRoutes.GET("/connections", adminSSHHandler.ListConnections)              // 共享 SSH 连接统计
+			adminSSHRoutes.GET("/sessions/:id/shadow", terminalHandler.HandleShadow)         // 只读实时观看（WebSocket）
+			adminSSHRoutes.POST("/sessions/:id/terminate", adminSSHHandler.TerminateSession) // 强制结束会话
+		}
+
+		// 危险命令规则管理（需要管理员权限）
+		commandRuleRoutes := v1.Group("/admin/command-rules")
+		commandRuleRoutes.Use(middleware.AuthMiddleware(jwtService), middleware.RequireAdmin())
+		{
+			commandRuleRoutes.GET("", commandRuleHandler.List)          // 规则列表
+			commandRuleRoutes.POST("", commandRuleHandler.Create)       // 创建规则
+			commandRuleRoutes.GET("/:id", commandRuleHandler.GetByID)   // 规则详情
+			commandRuleRoutes.PUT("/:id", commandRuleHandler.Update)    // 更新规则
+			commandRuleRoutes.DELETE("/:id", commandRuleHandler.Delete) // 删除规则
 		}
 
 		// 监控 WebSocket 路由（需要认证）
@@ -451,6 +583,11 @@ func main() {
 			scriptRoutes.PUT("/:id", scriptHandler.Update)           // 更新脚本
 			scriptRoutes.DELETE("/:id", scriptHandler.Delete)        // 删除脚本
 			scriptRoutes.POST("/:id/execute", scriptHandler.Execute) // 执行脚本
+
+			scriptRoutes.GET("/:id/versions", scriptHandler.ListVersions)        // 脚本版本历史
+			scriptRoutes.GET("/:id/versions/:version", scriptHandler.GetVersion) // 脚本指定版本
+			scriptRoutes.GET("/:id/diff", scriptHandler.Diff)                    // 版本差异对比
+			scriptRoutes.POST("/:id/rollback", scriptHandler.Rollback)           // 回滚到指定版本
 		}
 
 		// 批量任务路由（需要认证）
@@ -464,6 +601,9 @@ func main() {
 			batchTaskRoutes.PUT("/:id", batchTaskHandler.Update)               // 更新任务
 			batchTaskRoutes.DELETE("/:id", batchTaskHandler.Delete)            // 删除任务
 			batchTaskRoutes.POST("/:id/start", batchTaskHandler.Start)         // 启动任务
+
+			batchTaskRoutes.GET("/:id/results", batchTaskHandler.GetResults)    // 单机执行结果
+			batchTaskRoutes.GET("/:id/ws", batchTaskStreamHandler.HandleStream) // 实时输出（WebSocket）
 		}
 
 		// 定时任务路由（需要认证）
@@ -478,6 +618,8 @@ func main() {
 			scheduledTaskRoutes.DELETE("/:id", scheduledTaskHandler.Delete)            // 删除任务
 			scheduledTaskRoutes.POST("/:id/toggle", scheduledTaskHandler.Toggle)       // 启用/禁用
 			scheduledTaskRoutes.POST("/:id/trigger", scheduledTaskHandler.Trigger)     // 手动触发
+			scheduledTaskRoutes.GET("/:id/runs", scheduledTaskHandler.ListRuns)        // 运行记录
+			scheduledTaskRoutes.GET("/:id/runs/:run_id", scheduledTaskHandler.GetRun)  // 运行详情
 		}
 
 		// SSH会话路由（需要认证）
@@ -489,6 +631,10 @@ func main() {
 			sshSessionRoutes.GET("/:id", sshSessionHandler.GetByID)              // 会话详情
 			sshSessionRoutes.DELETE("/:id", sshSessionHandler.Delete)            // 删除会话
 			sshSessionRoutes.POST("/:id/close", sshSessionHandler.Close)         // 关闭会话
+
+			sshSessionRoutes.GET("/search", sshSessionHandler.Search)                   // 全文检索终端输出
+			sshSessionRoutes.GET("/:id/recording", sshSessionHandler.DownloadRecording) // 下载会话录像
+			sshSessionRoutes.GET("/:id/playback", playbackHandler.HandlePlayback)       // 录像回放（WebSocket）
 		}
 
 		// 文件传输路由（需要认证）
@@ -516,6 +662,19 @@ func main() {
 			sshKeyRoutes.DELETE("/:id", sshKeyHandler.DeleteSSHKey)      // 删除密钥
 		}
 
+		// 端口转发隧道路由（需要认证）
+		tunnelRoutes := v1.Group("/tunnels")
+		tunnelRoutes.Use(middleware.AuthMiddleware(jwtService))
+		{
+			tunnelRoutes.GET("", tunnelHandler.List)             // 隧道列表（含运行状态）
+			tunnelRoutes.POST("", tunnelHandler.Create)          // 创建隧道
+			tunnelRoutes.GET("/:id", tunnelHandler.GetByID)      // 隧道详情
+			tunnelRoutes.PUT("/:id", tunnelHandler.Update)       // 更新隧道
+			tunnelRoutes.DELETE("/:id", tunnelHandler.Delete)    // 删除隧道
+			tunnelRoutes.POST("/:id/start", tunnelHandler.Start) // 启动隧道
+			tunnelRoutes.POST("/:id/stop", tunnelHandler.Stop)   // 停止隧道
+		}
+
 		// 头像生成路由（需要认证）
 		avatarRoutes := v1.Group("/avatar")
 		avatarRoutes.Use(middleware.AuthMiddleware(jwtService))
@@ -524,6 +683,13 @@ func main() {
 		}
 	}
 
+	// Web 反向代理路由（需要认证，只允许服务器配置中列出的端口）
+	webProxyRoutes := r.Group("/proxy")
+	webProxyRoutes.Use(middleware.AuthMiddleware(jwtService))
+	{
+		webProxyRoutes.Any("/:server_id/:port/*path", webProxyHandler.Proxy)
+	}
+
 	// 静态文件服务（托管前端构建产物）
 	// 注意：必须在所有 API 路由之后注册
 	staticDir := "./static"
@@ -607,6 +773,21 @@ func main() {
 
 	log.Println("🛑 Shutting down server...")
 
+	// 停止定时任务调度（取消正在执行的任务）
+	scheduledTaskScheduler.Stop()
+
+	// 停止批量任务执行（未执行完的服务器记为失败）
+	batchTaskExecutor.Stop()
+
+	// 停止录像清理
+	recordingJanitor.Stop()
+
+	// 停止空闲会话检查
+	idleWatcher.Stop()
+
+	// 关闭所有隧道（期望状态保留在数据库中，下次启动时恢复）
+	tunnelManager.StopAll()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
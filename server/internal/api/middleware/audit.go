@@ -188,6 +188,47 @@ func determineAction(method, path string) auditlog.ActionType {
 		return auditlog.ActionSSHConnect
 	}
 
+	// 共享会话
+	if method == "POST" && path == "/api/v1/ssh/sessions/:id/shares" {
+		return auditlog.ActionSSHShareGrant
+	}
+	if method == "DELETE" && path == "/api/v1/ssh/sessions/:id/shares/:user_id" {
+		return auditlog.ActionSSHShareRevoke
+	}
+
+	// 管理员强制结束会话
+	if method == "POST" && path == "/api/v1/admin/ssh/sessions/:id/terminate" {
+		return auditlog.ActionSSHSessionTerminate
+	}
+
+	// 端口转发隧道
+	if method == "POST" && path == "/api/v1/tunnels" {
+		return auditlog.ActionTunnelCreate
+	}
+	if method == "PUT" && path == "/api/v1/tunnels/:id" {
+		return auditlog.ActionTunnelUpdate
+	}
+	if method == "DELETE" && path == "/api/v1/tunnels/:id" {
+		return auditlog.ActionTunnelDelete
+	}
+	if method == "POST" && path == "/api/v1/tunnels/:id/start" {
+		return auditlog.ActionTunnelStart
+	}
+	if method == "POST" && path == "/api/v1/tunnels/:id/stop" {
+		return auditlog.ActionTunnelStop
+	}
+
+	// 危险命令规则
+	if method == "POST" && path == "/api/v1/admin/command-rules" {
+		return auditlog.ActionCommandRuleCreate
+	}
+	if method == "PUT" && path == "/api/v1/admin/command-rules/:id" {
+		return auditlog.ActionCommandRuleUpdate
+	}
+	if method == "DELETE" && path == "/api/v1/admin/command-rules/:id" {
+		return auditlog.ActionCommandRuleDelete
+	}
+
 	// SFTP 操作
 	if method == "POST" && path == "/api/v1/sftp/:server_id/upload" {
 		return auditlog.ActionSFTPUpload
@@ -252,9 +252,58 @@ func (h *BatchTaskHandler) Start(c *gin.Context) {
 			RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
 			return
 		}
+		if err == batchtask.ErrExecutorUnavailable {
+			RespondError(c, http.StatusServiceUnavailable, "executor_unavailable", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "start_failed", err.Error())
 		return
 	}
 
 	RespondSuccess(c, gin.H{"message": "Batch task started successfully"})
 }
+
+// GetResults 获取批量任务的单机执行结果
+// GET /api/v1/batch-tasks/:id/results?status=failed
+func (h *BatchTaskHandler) GetResults(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid task ID format")
+		return
+	}
+
+	var req batchtask.ListBatchTaskResultsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	response, err := h.batchTaskService.ListBatchTaskResults(uid, id, &req)
+	if err != nil {
+		if err == batchtask.ErrBatchTaskNotFound {
+			RespondError(c, http.StatusNotFound, "not_found", "Batch task not found")
+			return
+		}
+		if err == batchtask.ErrUnauthorized {
+			RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
+			return
+		}
+		RespondError(c, http.StatusBadRequest, "list_results_failed", err.Error())
+		return
+	}
+
+	RespondSuccess(c, response)
+}
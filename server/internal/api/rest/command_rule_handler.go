@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/easyssh/server/internal/domain/cmdguard"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CommandRuleHandler 危险命令规则处理器（管理员）
+type CommandRuleHandler struct {
+	ruleService cmdguard.Service
+}
+
+// NewCommandRuleHandler 创建危险命令规则处理器实例
+func NewCommandRuleHandler(ruleService cmdguard.Service) *CommandRuleHandler {
+	return &CommandRuleHandler{
+		ruleService: ruleService,
+	}
+}
+
+// respondCommandRuleError 将规则服务错误转换为 HTTP 响应
+func respondCommandRuleError(c *gin.Context, err error, fallbackCode string) {
+	switch {
+	case errors.Is(err, cmdguard.ErrRuleNotFound):
+		RespondError(c, http.StatusNotFound, "not_found", "Command rule not found")
+	case errors.Is(err, cmdguard.ErrInvalidPattern):
+		RespondError(c, http.StatusBadRequest, "invalid_pattern", err.Error())
+	default:
+		RespondError(c, http.StatusInternalServerError, fallbackCode, err.Error())
+	}
+}
+
+// parseCommandRuleID 解析规则 ID
+func parseCommandRuleID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid rule ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// List 获取所有规则
+func (h *CommandRuleHandler) List(c *gin.Context) {
+	rules, err := h.ruleService.List()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "list_failed", err.Error())
+		return
+	}
+
+	RespondSuccess(c, rules)
+}
+
+// Create 创建规则
+func (h *CommandRuleHandler) Create(c *gin.Context) {
+	var req cmdguard.CreateRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		return
+	}
+
+	rule, err := h.ruleService.Create(userID, &req)
+	if err != nil {
+		respondCommandRuleError(c, err, "create_failed")
+		return
+	}
+
+	RespondSuccess(c, rule)
+}
+
+// GetByID 获取规则详情
+func (h *CommandRuleHandler) GetByID(c *gin.Context) {
+	id, ok := parseCommandRuleID(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.ruleService.Get(id)
+	if err != nil {
+		respondCommandRuleError(c, err, "get_failed")
+		return
+	}
+
+	RespondSuccess(c, rule)
+}
+
+// Update 更新规则
+func (h *CommandRuleHandler) Update(c *gin.Context) {
+	id, ok := parseCommandRuleID(c)
+	if !ok {
+		return
+	}
+
+	var req cmdguard.UpdateRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	rule, err := h.ruleService.Update(id, &req)
+	if err != nil {
+		respondCommandRuleError(c, err, "update_failed")
+		return
+	}
+
+	RespondSuccess(c, rule)
+}
+
+// Delete 删除规则
+func (h *CommandRuleHandler) Delete(c *gin.Context) {
+	id, ok := parseCommandRuleID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.ruleService.Delete(id); err != nil {
+		respondCommandRuleError(c, err, "delete_failed")
+		return
+	}
+
+	RespondSuccessWithMessage(c, nil, "Command rule deleted successfully")
+}
@@ -46,6 +46,18 @@ func (h *ScheduledTaskHandler) Create(c *gin.Context) {
 			RespondError(c, http.StatusBadRequest, "invalid_cron_expression", err.Error())
 			return
 		}
+		if err == scheduledtask.ErrInvalidTimezone {
+			RespondError(c, http.StatusBadRequest, "invalid_timezone", err.Error())
+			return
+		}
+		if err == scheduledtask.ErrInvalidHistoryRetention {
+			RespondError(c, http.StatusBadRequest, "invalid_history_retention", err.Error())
+			return
+		}
+		if err == scheduledtask.ErrInvalidMissedRunPolicy || err == scheduledtask.ErrInvalidOverlapPolicy {
+			RespondError(c, http.StatusBadRequest, "invalid_policy", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "create_failed", err.Error())
 		return
 	}
@@ -163,6 +175,18 @@ func (h *ScheduledTaskHandler) Update(c *gin.Context) {
 			RespondError(c, http.StatusBadRequest, "invalid_cron_expression", err.Error())
 			return
 		}
+		if err == scheduledtask.ErrInvalidTimezone {
+			RespondError(c, http.StatusBadRequest, "invalid_timezone", err.Error())
+			return
+		}
+		if err == scheduledtask.ErrInvalidHistoryRetention {
+			RespondError(c, http.StatusBadRequest, "invalid_history_retention", err.Error())
+			return
+		}
+		if err == scheduledtask.ErrInvalidMissedRunPolicy || err == scheduledtask.ErrInvalidOverlapPolicy {
+			RespondError(c, http.StatusBadRequest, "invalid_policy", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "update_failed", err.Error())
 		return
 	}
@@ -305,9 +329,106 @@ func (h *ScheduledTaskHandler) Trigger(c *gin.Context) {
 			RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
 			return
 		}
+		if err == scheduledtask.ErrSchedulerStopped {
+			RespondError(c, http.StatusServiceUnavailable, "scheduler_stopped", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "trigger_failed", err.Error())
 		return
 	}
 
 	RespondSuccess(c, gin.H{"message": "Task triggered successfully"})
 }
+
+// ListRuns 获取定时任务的运行记录
+// GET /api/v1/scheduled-tasks/:id/runs?status=failed&trigger_source=cron
+func (h *ScheduledTaskHandler) ListRuns(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid task ID format")
+		return
+	}
+
+	var req scheduledtask.ListScheduledTaskRunsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	response, err := h.scheduledTaskService.ListTaskRuns(uid, id, &req)
+	if err != nil {
+		if err == scheduledtask.ErrScheduledTaskNotFound {
+			RespondError(c, http.StatusNotFound, "not_found", "Scheduled task not found")
+			return
+		}
+		if err == scheduledtask.ErrUnauthorized {
+			RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
+			return
+		}
+		RespondError(c, http.StatusBadRequest, "list_runs_failed", err.Error())
+		return
+	}
+
+	RespondSuccess(c, response)
+}
+
+// GetRun 获取定时任务单次运行详情（含各服务器退出码和输出）
+// GET /api/v1/scheduled-tasks/:id/runs/:run_id
+func (h *ScheduledTaskHandler) GetRun(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid task ID format")
+		return
+	}
+
+	runID, err := uuid.Parse(c.Param("run_id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid run ID format")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	run, err := h.scheduledTaskService.GetTaskRun(uid, id, runID)
+	if err != nil {
+		if err == scheduledtask.ErrScheduledTaskNotFound {
+			RespondError(c, http.StatusNotFound, "not_found", "Scheduled task not found")
+			return
+		}
+		if err == scheduledtask.ErrRunNotFound {
+			RespondError(c, http.StatusNotFound, "not_found", "Run not found")
+			return
+		}
+		if err == scheduledtask.ErrUnauthorized {
+			RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "get_run_failed", err.Error())
+		return
+	}
+
+	RespondSuccess(c, run)
+}
@@ -1,7 +1,9 @@
 package rest
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/easyssh/server/internal/domain/script"
 	"github.com/gin-gonic/gin"
@@ -44,6 +46,10 @@ func (h *ScriptHandler) Create(c *gin.Context) {
 
 	createdScript, err := h.scriptService.CreateScript(uid, username, &req)
 	if err != nil {
+		if errors.Is(err, script.ErrInvalidParameters) {
+			RespondError(c, http.StatusBadRequest, "invalid_parameters", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "create_failed", err.Error())
 		return
 	}
@@ -87,6 +93,10 @@ func (h *ScriptHandler) Update(c *gin.Context) {
 			RespondError(c, http.StatusNotFound, "not_found", err.Error())
 			return
 		}
+		if errors.Is(err, script.ErrInvalidParameters) {
+			RespondError(c, http.StatusBadRequest, "invalid_parameters", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "update_failed", err.Error())
 		return
 	}
@@ -196,7 +206,8 @@ func (h *ScriptHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// Execute 执行脚本（增加执行计数）
+// Execute 在目标服务器上执行脚本
+// POST /api/v1/scripts/:id/execute
 func (h *ScriptHandler) Execute(c *gin.Context) {
 	scriptID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -204,6 +215,12 @@ func (h *ScriptHandler) Execute(c *gin.Context) {
 		return
 	}
 
+	var req script.ExecuteScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
 	userID, exists := c.Get("user_id")
 	if !exists {
 		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
@@ -216,7 +233,8 @@ func (h *ScriptHandler) Execute(c *gin.Context) {
 		return
 	}
 
-	if err := h.scriptService.ExecuteScript(uid, scriptID); err != nil {
+	response, err := h.scriptService.ExecuteScript(c.Request.Context(), uid, scriptID, &req)
+	if err != nil {
 		if err == script.ErrUnauthorized {
 			RespondError(c, http.StatusForbidden, "forbidden", err.Error())
 			return
@@ -225,9 +243,178 @@ func (h *ScriptHandler) Execute(c *gin.Context) {
 			RespondError(c, http.StatusNotFound, "not_found", err.Error())
 			return
 		}
+		if errors.Is(err, script.ErrMissingParameter) || errors.Is(err, script.ErrUnknownParameter) ||
+			errors.Is(err, script.ErrUnsupportedLanguage) || err == script.ErrInvalidScriptData {
+			RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "execute_failed", err.Error())
 		return
 	}
 
-	RespondSuccessWithMessage(c, nil, "script execution recorded")
+	RespondSuccess(c, response)
+}
+
+// ListVersions 获取脚本版本历史
+// GET /api/v1/scripts/:id/versions
+func (h *ScriptHandler) ListVersions(c *gin.Context) {
+	scriptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_script_id", err.Error())
+		return
+	}
+
+	var req script.ListScriptVersionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	response, err := h.scriptService.ListVersions(uid, scriptID, &req)
+	if err != nil {
+		h.respondVersionError(c, err, "list_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetVersion 获取脚本指定版本的内容
+// GET /api/v1/scripts/:id/versions/:version
+func (h *ScriptHandler) GetVersion(c *gin.Context) {
+	scriptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_script_id", err.Error())
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		RespondError(c, http.StatusBadRequest, "invalid_version", "version must be a positive integer")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	v, err := h.scriptService.GetVersion(uid, scriptID, version)
+	if err != nil {
+		h.respondVersionError(c, err, "get_failed")
+		return
+	}
+
+	RespondSuccess(c, v)
+}
+
+// Diff 比较脚本两个版本的内容
+// GET /api/v1/scripts/:id/diff?from=1&to=3（省略 to 时与当前版本比较）
+func (h *ScriptHandler) Diff(c *gin.Context) {
+	scriptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_script_id", err.Error())
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from < 1 {
+		RespondError(c, http.StatusBadRequest, "invalid_version", "from must be a positive integer")
+		return
+	}
+	to := 0
+	if raw := c.Query("to"); raw != "" {
+		to, err = strconv.Atoi(raw)
+		if err != nil || to < 1 {
+			RespondError(c, http.StatusBadRequest, "invalid_version", "to must be a positive integer")
+			return
+		}
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	diff, err := h.scriptService.DiffVersions(uid, scriptID, from, to)
+	if err != nil {
+		h.respondVersionError(c, err, "diff_failed")
+		return
+	}
+
+	RespondSuccess(c, diff)
+}
+
+// Rollback 将脚本回滚到指定版本
+// POST /api/v1/scripts/:id/rollback
+func (h *ScriptHandler) Rollback(c *gin.Context) {
+	scriptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_script_id", err.Error())
+		return
+	}
+
+	var req script.RollbackScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	scriptData, err := h.scriptService.RollbackScript(uid, scriptID, req.Version)
+	if err != nil {
+		h.respondVersionError(c, err, "rollback_failed")
+		return
+	}
+
+	RespondSuccess(c, scriptData)
+}
+
+// respondVersionError 版本相关接口的错误响应
+func (h *ScriptHandler) respondVersionError(c *gin.Context, err error, fallbackCode string) {
+	switch err {
+	case script.ErrUnauthorized:
+		RespondError(c, http.StatusForbidden, "forbidden", err.Error())
+	case script.ErrScriptNotFound, script.ErrVersionNotFound:
+		RespondError(c, http.StatusNotFound, "not_found", err.Error())
+	default:
+		RespondError(c, http.StatusInternalServerError, fallbackCode, err.Error())
+	}
 }
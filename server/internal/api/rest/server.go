@@ -40,6 +40,26 @@ func (h *ServerHandler) Create(c *gin.Context) {
 	// 创建服务器
 	srv, err := h.serverService.Create(c.Request.Context(), userID, &req)
 	if err != nil {
+		if isJumpServerError(err) {
+			RespondError(c, http.StatusBadRequest, "invalid_jump_server", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidProxy) {
+			RespondError(c, http.StatusBadRequest, "invalid_proxy", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidCertificate) {
+			RespondError(c, http.StatusBadRequest, "invalid_certificate", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidPrivateKey) || errors.Is(err, server.ErrWrongPassphrase) {
+			RespondError(c, http.StatusBadRequest, "invalid_private_key", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidWebPorts) {
+			RespondError(c, http.StatusBadRequest, "invalid_web_proxy_ports", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "create_failed", err.Error())
 		return
 	}
@@ -156,6 +176,26 @@ func (h *ServerHandler) Update(c *gin.Context) {
 			RespondError(c, http.StatusNotFound, "server_not_found", "Server not found")
 			return
 		}
+		if isJumpServerError(err) {
+			RespondError(c, http.StatusBadRequest, "invalid_jump_server", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidProxy) {
+			RespondError(c, http.StatusBadRequest, "invalid_proxy", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidCertificate) {
+			RespondError(c, http.StatusBadRequest, "invalid_certificate", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidPrivateKey) || errors.Is(err, server.ErrWrongPassphrase) {
+			RespondError(c, http.StatusBadRequest, "invalid_private_key", err.Error())
+			return
+		}
+		if errors.Is(err, server.ErrInvalidWebPorts) {
+			RespondError(c, http.StatusBadRequest, "invalid_web_proxy_ports", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "update_failed", err.Error())
 		return
 	}
@@ -186,6 +226,10 @@ func (h *ServerHandler) Delete(c *gin.Context) {
 			RespondError(c, http.StatusNotFound, "server_not_found", "Server not found")
 			return
 		}
+		if errors.Is(err, server.ErrJumpServerInUse) {
+			RespondError(c, http.StatusConflict, "jump_server_in_use", err.Error())
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "delete_failed", err.Error())
 		return
 	}
@@ -267,3 +311,10 @@ func getUserIDFromContext(c *gin.Context) (uuid.UUID, error) {
 
 	return uuid.Parse(userIDString)
 }
+
+// isJumpServerError 判断是否为跳板机配置错误
+func isJumpServerError(err error) bool {
+	return errors.Is(err, server.ErrInvalidJumpServer) ||
+		errors.Is(err, server.ErrJumpChainCycle) ||
+		errors.Is(err, server.ErrJumpChainTooLong)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/easyssh/server/internal/domain/settings"
 	"github.com/easyssh/server/internal/domain/tabsession"
+	"github.com/easyssh/server/internal/pkg/netproxy"
 	"github.com/gin-gonic/gin"
 )
 
@@ -80,6 +81,14 @@ func (h *SettingsHandler) RegisterRoutes(r *gin.RouterGroup) {
 			// Cookie 配置
 			advancedGroup.GET("/cookie", h.GetCookieConfig)
 			advancedGroup.POST("/cookie", h.SaveCookieConfig)
+
+			// 终端录像配置
+			advancedGroup.GET("/recording", h.GetRecordingConfig)
+			advancedGroup.POST("/recording", h.SaveRecordingConfig)
+
+			// SSH 出站代理配置
+			advancedGroup.GET("/proxy", h.GetProxyConfig)
+			advancedGroup.POST("/proxy", h.SaveProxyConfig)
 		}
 
 		// 通用设置 - 通配路由必须放在最后,避免拦截其他路由
@@ -580,11 +589,12 @@ type GetTabSessionConfigResponse struct {
 
 // SaveTabSessionConfigRequest 保存标签/会话配置请求
 type SaveTabSessionConfigRequest struct {
-	MaxTabs         int  `json:"max_tabs"`
-	InactiveMinutes int  `json:"inactive_minutes"`
-	Hibernate       bool `json:"hibernate"`
-	SessionTimeout  int  `json:"session_timeout"`
-	RememberLogin   bool `json:"remember_login"`
+	MaxTabs            int  `json:"max_tabs"`
+	InactiveMinutes    int  `json:"inactive_minutes"`
+	Hibernate          bool `json:"hibernate"`
+	SessionTimeout     int  `json:"session_timeout"`
+	RememberLogin      bool `json:"remember_login"`
+	DetachGraceMinutes int  `json:"detach_grace_minutes"` // 为 0 时保持现有设置
 }
 
 // GetTabSessionConfig 获取标签/会话配置
@@ -620,11 +630,12 @@ func (h *SettingsHandler) SaveTabSessionConfig(c *gin.Context) {
 	}
 
 	config := &tabsession.TabSessionSettings{
-		MaxTabs:         req.MaxTabs,
-		InactiveMinutes: req.InactiveMinutes,
-		Hibernate:       req.Hibernate,
-		SessionTimeout:  req.SessionTimeout,
-		RememberLogin:   req.RememberLogin,
+		MaxTabs:            req.MaxTabs,
+		InactiveMinutes:    req.InactiveMinutes,
+		Hibernate:          req.Hibernate,
+		SessionTimeout:     req.SessionTimeout,
+		RememberLogin:      req.RememberLogin,
+		DetachGraceMinutes: req.DetachGraceMinutes,
 	}
 
 	if err := h.tabSessionService.SaveTabSessionConfig(c.Request.Context(), config); err != nil {
@@ -1041,3 +1052,145 @@ func (h *SettingsHandler) SaveCookieConfig(c *gin.Context) {
 		"config":  config,
 	})
 }
+
+// === 终端录像配置相关 ===
+
+// GetRecordingConfigResponse 终端录像配置响应
+type GetRecordingConfigResponse struct {
+	Config *settings.RecordingConfig `json:"config"`
+}
+
+// SaveRecordingConfigRequest 保存终端录像配置请求
+type SaveRecordingConfigRequest struct {
+	Enabled       bool `json:"enabled"`
+	RecordInput   bool `json:"record_input"`
+	RetentionDays int  `json:"retention_days"`
+}
+
+// GetRecordingConfig 获取终端录像配置
+// @Summary 获取终端录像配置
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Success 200 {object} GetRecordingConfigResponse
+// @Router /api/v1/settings/advanced/recording [get]
+func (h *SettingsHandler) GetRecordingConfig(c *gin.Context) {
+	config, err := h.settingsService.GetRecordingConfig(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, GetRecordingConfigResponse{Config: config})
+}
+
+// SaveRecordingConfig 保存终端录像配置
+// @Summary 保存终端录像配置
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Param request body SaveRecordingConfigRequest true "终端录像配置"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/settings/advanced/recording [post]
+func (h *SettingsHandler) SaveRecordingConfig(c *gin.Context) {
+	var req SaveRecordingConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	config := &settings.RecordingConfig{
+		Enabled:       req.Enabled,
+		RecordInput:   req.RecordInput,
+		RetentionDays: req.RetentionDays,
+	}
+
+	if err := h.settingsService.SaveRecordingConfig(c.Request.Context(), config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "终端录像配置已保存",
+		"config":  config,
+	})
+}
+
+// === SSH 出站代理配置相关 ===
+
+// GetProxyConfigResponse SSH 出站代理配置响应
+type GetProxyConfigResponse struct {
+	Config *netproxy.Config `json:"config"`
+}
+
+// SaveProxyConfigRequest 保存 SSH 出站代理配置请求
+type SaveProxyConfigRequest struct {
+	Type     netproxy.Type `json:"type"`
+	Host     string        `json:"host"`
+	Port     int           `json:"port"`
+	Username string        `json:"username"`
+	Password string        `json:"password"`
+}
+
+// GetProxyConfig 获取 SSH 出站代理配置
+// @Summary 获取 SSH 出站代理配置
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Success 200 {object} GetProxyConfigResponse
+// @Router /api/v1/settings/advanced/proxy [get]
+func (h *SettingsHandler) GetProxyConfig(c *gin.Context) {
+	config, err := h.settingsService.GetProxyConfig(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 不返回密码
+	config.Password = ""
+
+	c.JSON(http.StatusOK, GetProxyConfigResponse{Config: config})
+}
+
+// SaveProxyConfig 保存 SSH 出站代理配置
+// @Summary 保存 SSH 出站代理配置
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Param request body SaveProxyConfigRequest true "SSH 出站代理配置"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/settings/advanced/proxy [post]
+func (h *SettingsHandler) SaveProxyConfig(c *gin.Context) {
+	var req SaveProxyConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	config := &netproxy.Config{
+		Type:     req.Type,
+		Host:     req.Host,
+		Port:     req.Port,
+		Username: req.Username,
+		Password: req.Password,
+	}
+
+	// 如果密码为空，保持原有密码
+	if req.Password == "" && req.Username != "" {
+		existingConfig, err := h.settingsService.GetProxyConfig(c.Request.Context())
+		if err == nil && existingConfig != nil {
+			config.Password = existingConfig.Password
+		}
+	}
+
+	if err := h.settingsService.SaveProxyConfig(c.Request.Context(), config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.Password = ""
+	c.JSON(http.StatusOK, gin.H{
+		"message": "代理配置已保存",
+		"config":  config,
+	})
+}
@@ -16,29 +16,25 @@ import (
 	"github.com/easyssh/server/internal/domain/server"
 	"github.com/easyssh/server/internal/domain/sftp"
 	sshDomain "github.com/easyssh/server/internal/domain/ssh"
-	"github.com/easyssh/server/internal/pkg/crypto"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/ssh"
 )
 
 // SFTPHandler SFTP 处理器
 type SFTPHandler struct {
 	serverService     server.Service
 	serverRepo        server.Repository
-	encryptor         *crypto.Encryptor
+	connManager       *sshDomain.ConnectionManager // SSH 连接管理器（与终端、监控共享连接）
 	uploadWSHandler   *ws.SFTPUploadHandler
-	hostKeyCallback   ssh.HostKeyCallback // SSH主机密钥验证回调
 }
 
 // NewSFTPHandler 创建 SFTP 处理器
-func NewSFTPHandler(serverService server.Service, serverRepo server.Repository, encryptor *crypto.Encryptor, uploadWSHandler *ws.SFTPUploadHandler, hostKeyCallback ssh.HostKeyCallback) *SFTPHandler {
+func NewSFTPHandler(serverService server.Service, serverRepo server.Repository, connManager *sshDomain.ConnectionManager, uploadWSHandler *ws.SFTPUploadHandler) *SFTPHandler {
 	return &SFTPHandler{
 		serverService:   serverService,
 		serverRepo:      serverRepo,
-		encryptor:       encryptor,
+		connManager:     connManager,
 		uploadWSHandler: uploadWSHandler,
-		hostKeyCallback: hostKeyCallback,
 	}
 }
 
@@ -56,15 +52,9 @@ func (h *SFTPHandler) createSFTPClient(c *gin.Context, serverID uuid.UUID) (*sft
 		return nil, nil, err
 	}
 
-	// 创建 SSH 客户端（使用主机密钥验证）
-	sshClient, err := sshDomain.NewClient(srv, h.encryptor, h.hostKeyCallback)
+	// 获取 SSH 连接（复用同一用户和服务器的已有连接，避免每次请求重新握手）
+	lease, err := h.connManager.Acquire(c.Request.Context(), userID.String(), srv, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create SSH client: %w", err)
-	}
-
-	// 连接到服务器
-	if err := sshClient.Connect(srv.Host, srv.Port); err != nil {
-		sshClient.Close() // 确保关闭连接
 		return nil, nil, fmt.Errorf("failed to connect: %w", err)
 	}
 
@@ -75,11 +65,10 @@ func (h *SFTPHandler) createSFTPClient(c *gin.Context, serverID uuid.UUID) (*sft
 		fmt.Printf("Failed to update server status: %v\n", err)
 	}
 
-	// 创建 SFTP 客户端
-	sftpClient, err := sftp.NewClient(sshClient, srv)
+	// 创建 SFTP 客户端（关闭时释放连接租用）
+	sftpClient, err := sftp.NewClient(lease, srv)
 	if err != nil {
-		sshClient.Close()
-		return nil, nil, fmt.Errorf("failed to create SFTP client: %w", err)
+		return nil, nil, err
 	}
 
 	return sftpClient, srv, nil
@@ -880,21 +869,15 @@ func (h *SFTPHandler) fastDownload(c *gin.Context, serverID uuid.UUID, req Batch
 		return
 	}
 
-	// 创建 SSH 客户端
-	sshClient, err := sshDomain.NewClient(srv, h.encryptor, h.hostKeyCallback)
+	// 获取 SSH 连接
+	lease, err := h.connManager.Acquire(c.Request.Context(), userID.String(), srv, nil)
 	if err != nil {
-		fmt.Printf("[SFTP FastDownload] Failed to create SSH client: %v\n", err)
-		RespondError(c, http.StatusInternalServerError, "ssh_error", err.Error())
-		return
-	}
-	defer sshClient.Close()
-
-	// 连接到服务器
-	if err := sshClient.Connect(srv.Host, srv.Port); err != nil {
 		fmt.Printf("[SFTP FastDownload] Failed to connect: %v\n", err)
 		RespondError(c, http.StatusInternalServerError, "connection_error", err.Error())
 		return
 	}
+	defer lease.Release()
+	sshClient := lease.Client()
 
 	// 构建 tar 命令
 	// 策略: 对每个路径,切换到其父目录(-C),然后打包目录名(去掉路径前缀)
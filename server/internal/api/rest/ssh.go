@@ -6,6 +6,7 @@ import (
 
 	"github.com/easyssh/server/internal/domain/ssh"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // SSHHandler SSH 会话处理器
@@ -108,8 +109,8 @@ func (h *SSHHandler) CloseSession(c *gin.Context) {
 		return
 	}
 
-	// 关闭会话
-	if err := h.sessionManager.Remove(sessionID); err != nil {
+	// 关闭会话（会话结束后由终端处理器从管理器中移除并更新会话记录）
+	if err := session.CloseWithReason(ssh.CloseReasonClosedByUser); err != nil && !errors.Is(err, ssh.ErrSessionClosed) {
 		RespondError(c, http.StatusInternalServerError, "close_session_failed", err.Error())
 		return
 	}
@@ -117,6 +118,145 @@ func (h *SSHHandler) CloseSession(c *gin.Context) {
 	RespondSuccessWithMessage(c, nil, "Session closed successfully")
 }
 
+// ListSharedSessions 获取共享给当前用户的会话列表
+// GET /api/v1/ssh/sessions/shared
+func (h *SSHHandler) ListSharedSessions(c *gin.Context) {
+	// 从上下文获取用户 ID
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		return
+	}
+
+	sessions := h.sessionManager.GetSharedWithUser(userID.String())
+
+	publicSessions := make([]interface{}, len(sessions))
+	for i, session := range sessions {
+		public := session.ToPublic()
+		if mode, ok := session.Access(userID.String()); ok {
+			public["mode"] = mode
+		}
+		publicSessions[i] = public
+	}
+
+	RespondSuccess(c, gin.H{
+		"sessions": publicSessions,
+		"total":    len(publicSessions),
+	})
+}
+
+// ShareSessionRequest 共享会话请求
+type ShareSessionRequest struct {
+	UserID string        `json:"user_id" binding:"required"`
+	Mode   ssh.ShareMode `json:"mode" binding:"required"`
+}
+
+// ListShares 获取会话的共享授权和当前连接的客户端（仅所有者）
+// GET /api/v1/ssh/sessions/:id/shares
+func (h *SSHHandler) ListShares(c *gin.Context) {
+	session, ok := h.getOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	attachments := session.Attachments()
+	clients := make([]gin.H, len(attachments))
+	for i, a := range attachments {
+		clients[i] = gin.H{
+			"user_id":   a.UserID,
+			"mode":      a.Mode,
+			"joined_at": a.JoinedAt,
+		}
+	}
+
+	RespondSuccess(c, gin.H{
+		"shares":  session.Shares(),
+		"clients": clients,
+	})
+}
+
+// ShareSession 授权其他用户加入会话（仅所有者，已授权时更新访问模式）
+// POST /api/v1/ssh/sessions/:id/shares
+func (h *SSHHandler) ShareSession(c *gin.Context) {
+	session, ok := h.getOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	var req ShareSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	targetID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", "Invalid user ID")
+		return
+	}
+	if targetID.String() == session.UserID {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", "Cannot share a session with its owner")
+		return
+	}
+
+	share, err := session.Grant(targetID.String(), req.Mode)
+	if err != nil {
+		switch {
+		case errors.Is(err, ssh.ErrInvalidShareMode):
+			RespondError(c, http.StatusBadRequest, "invalid_mode", err.Error())
+		case errors.Is(err, ssh.ErrSessionClosed):
+			RespondError(c, http.StatusConflict, "session_closed", "Session already closed")
+		default:
+			RespondError(c, http.StatusInternalServerError, "share_session_failed", err.Error())
+		}
+		return
+	}
+
+	RespondSuccess(c, share)
+}
+
+// RevokeShare 撤销用户的共享授权并断开其客户端（仅所有者）
+// DELETE /api/v1/ssh/sessions/:id/shares/:user_id
+func (h *SSHHandler) RevokeShare(c *gin.Context) {
+	session, ok := h.getOwnedSession(c)
+	if !ok {
+		return
+	}
+
+	if !session.Revoke(c.Param("user_id"), "share revoked") {
+		RespondError(c, http.StatusNotFound, "share_not_found", "Share not found")
+		return
+	}
+
+	RespondSuccessWithMessage(c, nil, "Share revoked successfully")
+}
+
+// getOwnedSession 获取当前用户拥有的会话，失败时已写入错误响应
+func (h *SSHHandler) getOwnedSession(c *gin.Context) (*ssh.Session, bool) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		return nil, false
+	}
+
+	session, err := h.sessionManager.Get(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ssh.ErrSessionNotFound) {
+			RespondError(c, http.StatusNotFound, "session_not_found", "Session not found")
+			return nil, false
+		}
+		RespondError(c, http.StatusInternalServerError, "get_session_failed", err.Error())
+		return nil, false
+	}
+
+	if session.UserID != userID.String() {
+		RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
+		return nil, false
+	}
+
+	return session, true
+}
+
 // GetStatistics 获取会话统计
 // GET /api/v1/ssh/statistics
 func (h *SSHHandler) GetStatistics(c *gin.Context) {
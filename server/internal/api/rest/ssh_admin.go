@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/easyssh/server/internal/domain/ssh"
+	"github.com/easyssh/server/internal/domain/user"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminSSHHandler 管理员实时会话监控处理器
+type AdminSSHHandler struct {
+	sessionManager *ssh.SessionManager
+	connManager    *ssh.ConnectionManager
+	serverRepo     server.Repository
+	userRepo       user.Repository
+}
+
+// NewAdminSSHHandler 创建管理员会话监控处理器
+func NewAdminSSHHandler(sessionManager *ssh.SessionManager, connManager *ssh.ConnectionManager, serverRepo server.Repository, userRepo user.Repository) *AdminSSHHandler {
+	return &AdminSSHHandler{
+		sessionManager: sessionManager,
+		connManager:    connManager,
+		serverRepo:     serverRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// TerminateSessionRequest 强制结束会话请求
+type TerminateSessionRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ListSessions 获取所有用户的活跃会话（包含服务器、客户端 IP 和空闲时间）
+// GET /api/v1/admin/ssh/sessions
+func (h *AdminSSHHandler) ListSessions(c *gin.Context) {
+	sessions := h.sessionManager.GetAll()
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+
+	// 同一用户/服务器通常有多个会话，查询结果按 ID 缓存
+	servers := make(map[string]*server.Server)
+	usernames := make(map[string]string)
+
+	publicSessions := make([]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.IsActive() {
+			continue
+		}
+		public := session.ToPublic()
+
+		srv, cached := servers[session.ServerID]
+		if !cached {
+			if id, err := uuid.Parse(session.ServerID); err == nil {
+				srv, _ = h.serverRepo.FindByID(c.Request.Context(), id)
+			}
+			servers[session.ServerID] = srv
+		}
+		if srv != nil {
+			public["server_name"] = srv.Name
+			public["server_host"] = srv.Host
+		}
+
+		username, cached := usernames[session.UserID]
+		if !cached {
+			if id, err := uuid.Parse(session.UserID); err == nil {
+				if u, err := h.userRepo.GetByID(c.Request.Context(), id); err == nil {
+					username = u.Username
+				}
+			}
+			usernames[session.UserID] = username
+		}
+		public["username"] = username
+
+		publicSessions = append(publicSessions, public)
+	}
+
+	RespondSuccess(c, gin.H{
+		"sessions": publicSessions,
+		"total":    len(publicSessions),
+	})
+}
+
+// ListConnections 获取共享 SSH 连接的统计信息（连接数、复用次数、每个连接的使用情况）
+// GET /api/v1/admin/ssh/connections
+func (h *AdminSSHHandler) ListConnections(c *gin.Context) {
+	stats := h.connManager.Stats()
+	sort.Slice(stats.Connections, func(i, j int) bool {
+		return stats.Connections[i].CreatedAt.After(stats.Connections[j].CreatedAt)
+	})
+
+	RespondSuccess(c, stats)
+}
+
+// TerminateSession 强制结束任意用户的会话，原因会显示给用户并写入会话记录
+// POST /api/v1/admin/ssh/sessions/:id/terminate
+func (h *AdminSSHHandler) TerminateSession(c *gin.Context) {
+	var req TerminateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	session, err := h.sessionManager.Get(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ssh.ErrSessionNotFound) {
+			RespondError(c, http.StatusNotFound, "session_not_found", "Session not found")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "get_session_failed", err.Error())
+		return
+	}
+
+	// 会话结束后由终端处理器从管理器中移除并更新会话记录
+	if err := session.Terminate(req.Reason); err != nil {
+		if errors.Is(err, ssh.ErrSessionClosed) {
+			RespondError(c, http.StatusConflict, "session_closed", "Session already closed")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "terminate_session_failed", err.Error())
+		return
+	}
+
+	RespondSuccessWithMessage(c, nil, "Session terminated successfully")
+}
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/easyssh/server/internal/domain/sshsession"
@@ -185,3 +186,86 @@ func (h *SSHSessionHandler) Close(c *gin.Context) {
 
 	RespondSuccess(c, gin.H{"message": "SSH session closed successfully"})
 }
+
+// DownloadRecording 下载会话录像（asciicast v2 格式）
+// GET /api/v1/ssh-sessions/:id/recording
+func (h *SSHSessionHandler) DownloadRecording(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid session ID format")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	// 管理员可以下载所有用户的录像用于审计
+	role, _ := c.Get("role")
+	session, path, err := h.sshSessionService.GetRecordingFile(uid, id, role == "admin")
+	if err != nil {
+		if err == sshsession.ErrSSHSessionNotFound {
+			RespondError(c, http.StatusNotFound, "not_found", "SSH session not found")
+			return
+		}
+		if err == sshsession.ErrRecordingNotFound {
+			RespondError(c, http.StatusNotFound, "recording_not_found", err.Error())
+			return
+		}
+		if err == sshsession.ErrUnauthorized {
+			RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "download_failed", err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "application/x-asciicast")
+	c.FileAttachment(path, fmt.Sprintf("session-%s.cast", session.ID))
+}
+
+// Search 全文检索会话的终端输出（管理员可以检索所有用户的会话）
+// GET /api/v1/ssh-sessions/search?q=&user_id=&server_id=&start_time=&end_time=
+func (h *SSHSessionHandler) Search(c *gin.Context) {
+	var req sshsession.SearchTranscriptsRequest
+
+	// 解析查询参数
+	if err := c.ShouldBindQuery(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", "user_id not found")
+		return
+	}
+
+	uid, err := uuid.Parse(userID.(string))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_user_id", err.Error())
+		return
+	}
+
+	role, _ := c.Get("role")
+	response, err := h.sshSessionService.SearchTranscripts(uid, role == "admin", &req)
+	if err != nil {
+		if err == sshsession.ErrInvalidSearchQuery {
+			RespondError(c, http.StatusBadRequest, "invalid_query", err.Error())
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "search_failed", err.Error())
+		return
+	}
+
+	RespondSuccess(c, response)
+}
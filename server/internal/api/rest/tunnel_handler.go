@@ -0,0 +1,188 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/easyssh/server/internal/domain/tunnel"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TunnelHandler 端口转发隧道处理器
+type TunnelHandler struct {
+	tunnelService tunnel.Service
+}
+
+// NewTunnelHandler 创建隧道处理器实例
+func NewTunnelHandler(tunnelService tunnel.Service) *TunnelHandler {
+	return &TunnelHandler{
+		tunnelService: tunnelService,
+	}
+}
+
+// respondTunnelError 将隧道服务错误转换为 HTTP 响应
+func respondTunnelError(c *gin.Context, err error, fallbackCode string) {
+	switch {
+	case errors.Is(err, tunnel.ErrTunnelNotFound):
+		RespondError(c, http.StatusNotFound, "not_found", "Tunnel not found")
+	case errors.Is(err, tunnel.ErrUnauthorized):
+		RespondError(c, http.StatusForbidden, "forbidden", "Access denied")
+	case errors.Is(err, tunnel.ErrInvalidServer), errors.Is(err, tunnel.ErrInvalidTunnelData):
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, tunnel.ErrBindAddressInUse):
+		RespondError(c, http.StatusConflict, "bind_address_in_use", err.Error())
+	case errors.Is(err, tunnel.ErrTunnelNotAllowed):
+		RespondError(c, http.StatusForbidden, "tunnel_not_allowed", err.Error())
+	default:
+		RespondError(c, http.StatusInternalServerError, fallbackCode, err.Error())
+	}
+}
+
+// parseTunnelRequest 解析当前用户和隧道 ID
+func parseTunnelRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_id", "Invalid tunnel ID format")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, id, true
+}
+
+// Create 创建隧道
+func (h *TunnelHandler) Create(c *gin.Context) {
+	var req tunnel.CreateTunnelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		return
+	}
+
+	detail, err := h.tunnelService.Create(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondTunnelError(c, err, "create_failed")
+		return
+	}
+
+	RespondSuccess(c, detail)
+}
+
+// List 获取隧道列表（含运行状态）
+func (h *TunnelHandler) List(c *gin.Context) {
+	var req tunnel.ListTunnelsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		return
+	}
+
+	details, err := h.tunnelService.List(userID, &req)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "list_failed", err.Error())
+		return
+	}
+
+	RespondSuccess(c, details)
+}
+
+// GetByID 获取隧道详情
+func (h *TunnelHandler) GetByID(c *gin.Context) {
+	userID, id, ok := parseTunnelRequest(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.tunnelService.Get(userID, id)
+	if err != nil {
+		respondTunnelError(c, err, "get_failed")
+		return
+	}
+
+	RespondSuccess(c, detail)
+}
+
+// Update 更新隧道
+func (h *TunnelHandler) Update(c *gin.Context) {
+	userID, id, ok := parseTunnelRequest(c)
+	if !ok {
+		return
+	}
+
+	var req tunnel.UpdateTunnelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	detail, err := h.tunnelService.Update(c.Request.Context(), userID, id, &req)
+	if err != nil {
+		respondTunnelError(c, err, "update_failed")
+		return
+	}
+
+	RespondSuccess(c, detail)
+}
+
+// Delete 删除隧道
+func (h *TunnelHandler) Delete(c *gin.Context) {
+	userID, id, ok := parseTunnelRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.tunnelService.Delete(userID, id); err != nil {
+		respondTunnelError(c, err, "delete_failed")
+		return
+	}
+
+	RespondSuccessWithMessage(c, nil, "Tunnel deleted successfully")
+}
+
+// Start 启动隧道
+func (h *TunnelHandler) Start(c *gin.Context) {
+	userID, id, ok := parseTunnelRequest(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.tunnelService.Start(userID, id)
+	if err != nil {
+		respondTunnelError(c, err, "start_failed")
+		return
+	}
+
+	RespondSuccess(c, detail)
+}
+
+// Stop 停止隧道
+func (h *TunnelHandler) Stop(c *gin.Context) {
+	userID, id, ok := parseTunnelRequest(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.tunnelService.Stop(userID, id)
+	if err != nil {
+		respondTunnelError(c, err, "stop_failed")
+		return
+	}
+
+	RespondSuccess(c, detail)
+}
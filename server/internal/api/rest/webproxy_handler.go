@@ -0,0 +1,274 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/auditlog"
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/easyssh/server/internal/domain/webproxy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// webProxyAuditWindow 同一用户访问同一服务器端口时，审计日志的最小记录间隔
+	webProxyAuditWindow = 30 * time.Minute
+	// easysshCookiePrefix EasySSH 自身 Cookie 的前缀，不转发给内部服务，也不允许内部服务设置
+	easysshCookiePrefix = "easyssh_"
+)
+
+// WebProxyHandler 通过 SSH 连接反向代理服务器本机的 HTTP/WebSocket 服务
+// 访问路径为 /proxy/:server_id/:port/...，只允许服务器配置中列出的端口
+// 内部服务与 EasySSH 同源，只应开放可信的服务
+type WebProxyHandler struct {
+	serverService   server.Service
+	dialer          *webproxy.Dialer
+	auditLogService auditlog.Service
+
+	auditMu sync.Mutex
+	audited map[string]time.Time // key: userID:serverID:port
+}
+
+// NewWebProxyHandler 创建反向代理处理器
+func NewWebProxyHandler(serverService server.Service, dialer *webproxy.Dialer, auditLogService auditlog.Service) *WebProxyHandler {
+	return &WebProxyHandler{
+		serverService:   serverService,
+		dialer:          dialer,
+		auditLogService: auditLogService,
+		audited:         make(map[string]time.Time),
+	}
+}
+
+// Proxy 反向代理请求
+func (h *WebProxyHandler) Proxy(c *gin.Context) {
+	userID, err := getUserIDFromContext(c)
+	if err != nil {
+		RespondError(c, http.StatusUnauthorized, "unauthorized", err.Error())
+		return
+	}
+
+	serverID, err := uuid.Parse(c.Param("server_id"))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid_server_id", "Invalid server ID")
+		return
+	}
+
+	port, err := strconv.Atoi(c.Param("port"))
+	if err != nil || port < 1 || port > 65535 {
+		RespondError(c, http.StatusBadRequest, "invalid_port", "Invalid port")
+		return
+	}
+
+	srv, err := h.serverService.GetByID(c.Request.Context(), userID, serverID)
+	if err != nil {
+		if errors.Is(err, server.ErrServerNotFound) {
+			RespondError(c, http.StatusNotFound, "server_not_found", "Server not found")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "get_failed", err.Error())
+		return
+	}
+
+	if !srv.AllowsWebProxyPort(port) {
+		h.audit(c, userID, srv, port, auditlog.StatusFailure, "port not allowed")
+		RespondError(c, http.StatusForbidden, "port_not_allowed", "Port is not allowed for web proxy on this server")
+		return
+	}
+	h.audit(c, userID, srv, port, auditlog.StatusSuccess, "")
+
+	// WebSocket 等升级连接会长时间保持，取消 HTTP 服务器的读写超时
+	if c.GetHeader("Upgrade") != "" {
+		rc := http.NewResponseController(c.Writer)
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+	}
+
+	prefix := "/proxy/" + serverID.String() + "/" + strconv.Itoa(port)
+	target := webproxy.TargetAddr(port)
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			rewriteProxyRequest(pr, prefix, target)
+		},
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return h.dialer.Dial(ctx, userID, srv, port)
+			},
+			// 每个请求独立使用一个 SSH 通道，响应结束后关闭，SSH 连接由拨号器复用
+			DisableKeepAlives:     true,
+			ResponseHeaderTimeout: 2 * time.Minute,
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			rewriteProxyResponse(resp, prefix, port)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("[WebProxy] 代理请求失败: server=%s, port=%d, error=%v", serverID, port, err)
+			RespondError(c, http.StatusBadGateway, "bad_gateway", err.Error())
+		},
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// rewriteProxyRequest 去掉路径前缀并移除 EasySSH 的认证信息
+func rewriteProxyRequest(pr *httputil.ProxyRequest, prefix, target string) {
+	in := pr.In.URL
+	out := pr.Out.URL
+
+	out.Scheme = "http"
+	out.Host = target
+	out.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(in.Path, prefix), "/")
+	out.RawPath = ""
+	if in.RawPath != "" {
+		out.RawPath = "/" + strings.TrimPrefix(strings.TrimPrefix(in.RawPath, prefix), "/")
+	}
+
+	// WebSocket 认证使用的 token 查询参数
+	if query := in.Query(); query.Has("token") {
+		query.Del("token")
+		out.RawQuery = query.Encode()
+	}
+
+	cookies := pr.Out.Cookies()
+	pr.Out.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if !strings.HasPrefix(cookie.Name, easysshCookiePrefix) {
+			pr.Out.AddCookie(cookie)
+		}
+	}
+
+	pr.Out.Host = target
+	pr.SetXForwarded()
+	pr.Out.Header.Set("X-Forwarded-Prefix", prefix)
+}
+
+// rewriteProxyResponse 为重定向地址和 Cookie 路径加上代理前缀
+func rewriteProxyResponse(resp *http.Response, prefix string, port int) {
+	if location := resp.Header.Get("Location"); location != "" {
+		resp.Header.Set("Location", rewriteLocation(location, prefix, port))
+	}
+
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+	resp.Header.Del("Set-Cookie")
+	for _, cookie := range cookies {
+		if strings.HasPrefix(cookie.Name, easysshCookiePrefix) {
+			continue
+		}
+		cookie.Domain = ""
+		if !strings.HasPrefix(cookie.Path, "/") {
+			cookie.Path = prefix + "/"
+		} else if !hasPathPrefix(cookie.Path, prefix) {
+			cookie.Path = prefix + cookie.Path
+		}
+		if v := cookie.String(); v != "" {
+			resp.Header.Add("Set-Cookie", v)
+		}
+	}
+}
+
+// rewriteLocation 将指向内部服务的绝对路径或地址改写到代理路径下，其他地址保持不变
+func rewriteLocation(location, prefix string, port int) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	if u.Host != "" {
+		if !isProxyTarget(u, port) {
+			return location
+		}
+		u.Scheme = ""
+		u.Host = ""
+		u.User = nil
+	} else if !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(location, "//") {
+		return location
+	}
+
+	if !hasPathPrefix(u.Path, prefix) {
+		u.Path = prefix + u.Path
+		u.RawPath = ""
+	}
+	return u.String()
+}
+
+// isProxyTarget 判断地址是否指向被代理的服务器本机端口
+func isProxyTarget(u *url.URL, port int) bool {
+	hostname := u.Hostname()
+	if hostname != "127.0.0.1" && hostname != "localhost" {
+		return false
+	}
+	if u.Port() == "" {
+		return (u.Scheme == "http" && port == 80) || (u.Scheme == "https" && port == 443)
+	}
+	return u.Port() == strconv.Itoa(port)
+}
+
+// hasPathPrefix 判断路径是否已经位于代理前缀下
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// audit 记录反向代理访问（同一用户、服务器和端口在时间窗口内只记录一次，拒绝访问时每次记录）
+func (h *WebProxyHandler) audit(c *gin.Context, userID uuid.UUID, srv *server.Server, port int, status auditlog.Status, errMsg string) {
+	if h.auditLogService == nil {
+		return
+	}
+
+	if status == auditlog.StatusSuccess {
+		key := userID.String() + ":" + srv.ID.String() + ":" + strconv.Itoa(port)
+		now := time.Now()
+
+		h.auditMu.Lock()
+		last, ok := h.audited[key]
+		if ok && now.Sub(last) < webProxyAuditWindow {
+			h.auditMu.Unlock()
+			return
+		}
+		h.audited[key] = now
+		for k, t := range h.audited {
+			if now.Sub(t) >= webProxyAuditWindow {
+				delete(h.audited, k)
+			}
+		}
+		h.auditMu.Unlock()
+	}
+
+	username, _ := c.Get("username")
+	usernameStr, _ := username.(string)
+	details, _ := json.Marshal(map[string]interface{}{
+		"server_name": srv.Name,
+		"port":        port,
+		"path":        c.Request.URL.Path,
+	})
+	serverID := srv.ID
+
+	req := &auditlog.CreateAuditLogRequest{
+		UserID:    userID,
+		Username:  usernameStr,
+		ServerID:  &serverID,
+		Action:    auditlog.ActionWebProxyAccess,
+		Resource:  webproxy.TargetAddr(port),
+		Status:    status,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   string(details),
+		ErrorMsg:  errMsg,
+	}
+	if err := h.auditLogService.Log(context.Background(), req); err != nil {
+		log.Printf("[WebProxy] 写入审计日志失败: %v", err)
+	}
+}
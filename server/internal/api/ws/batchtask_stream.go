@@ -0,0 +1,282 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/batchtask"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// batchTaskSendBuffer 每个连接待发送事件的队列长度，积压超过该长度的慢连接会被断开
+const batchTaskSendBuffer = 1024
+
+// batchTaskFrame 待发送的消息帧
+type batchTaskFrame struct {
+	data  []byte
+	final bool // 最后一帧，发送后关闭连接
+}
+
+// batchTaskConn 批量任务输出 WebSocket 连接
+// 事件先进入有界队列，由独立的写协程发送，避免慢连接拖慢远程执行
+type batchTaskConn struct {
+	conn      *websocket.Conn
+	send      chan batchTaskFrame
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newBatchTaskConn(conn *websocket.Conn) *batchTaskConn {
+	return &batchTaskConn{
+		conn: conn,
+		send: make(chan batchTaskFrame, batchTaskSendBuffer),
+		done: make(chan struct{}),
+	}
+}
+
+// enqueue 将消息放入发送队列，队列已满时返回 false
+func (c *batchTaskConn) enqueue(v interface{}, final bool) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return true
+	}
+	select {
+	case c.send <- batchTaskFrame{data: data, final: final}:
+		return true
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeJSON 带超时地写入 JSON 文本消息（仅在写协程启动前或写协程内调用）
+func (c *batchTaskConn) writeJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+// write 带超时地写入文本消息
+func (c *batchTaskConn) write(data []byte) error {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// close 通知写协程退出
+func (c *batchTaskConn) close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// writePump 发送队列中的消息和心跳，退出时关闭连接
+func (c *batchTaskConn) writePump(taskID string) {
+	ticker := time.NewTicker(wsPingEvery)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case frame := <-c.send:
+			if err := c.write(frame.data); err != nil {
+				log.Printf("[BatchTaskWS] 发送消息失败: taskID=%s, error=%v", taskID, err)
+				return
+			}
+			// 汇总帧是最后一帧，发送后关闭连接
+			if frame.final {
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(wsWriteWait)); err != nil {
+				log.Printf("[BatchTaskWS] 心跳失败: taskID=%s, error=%v", taskID, err)
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// BatchTaskStreamHandler 批量任务实时输出 WebSocket 处理器
+// 实现 batchtask.EventPublisher，将执行器产生的事件推送给订阅该任务的所有连接
+type BatchTaskStreamHandler struct {
+	batchTaskService batchtask.Service
+	// 存储活跃的 WebSocket 连接，key 是 taskID（同一任务可被多个页面同时订阅）
+	connections map[string]map[*batchTaskConn]struct{}
+	mu          sync.RWMutex
+}
+
+// NewBatchTaskStreamHandler 创建批量任务实时输出处理器
+func NewBatchTaskStreamHandler(batchTaskService batchtask.Service) *BatchTaskStreamHandler {
+	return &BatchTaskStreamHandler{
+		batchTaskService: batchTaskService,
+		connections:      make(map[string]map[*batchTaskConn]struct{}),
+	}
+}
+
+// HandleStream 处理批量任务实时输出 WebSocket 连接
+// WS /api/v1/batch-tasks/:id/ws
+func (h *BatchTaskStreamHandler) HandleStream(c *gin.Context) {
+	// 从上下文获取用户 ID（认证中间件已验证）
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user_id"})
+		return
+	}
+
+	// 解析任务 ID 并验证所有权
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id"})
+		return
+	}
+	if _, err := h.batchTaskService.GetBatchTask(userID, taskID); err != nil {
+		if err == batchtask.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+
+	// 升级到 WebSocket
+	wsConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("[BatchTaskWS] 升级失败: %v", err)
+		return
+	}
+	conn := newBatchTaskConn(wsConn)
+	key := taskID.String()
+
+	// 注册连接：注册后产生的事件进入发送队列，写协程启动后再发送
+	h.mu.Lock()
+	if h.connections[key] == nil {
+		h.connections[key] = make(map[*batchTaskConn]struct{})
+	}
+	h.connections[key][conn] = struct{}{}
+	h.mu.Unlock()
+
+	log.Printf("[BatchTaskWS] 连接已建立: taskID=%s", key)
+
+	// 注册之后再读取任务状态，任务在此之前结束时快照中已是最终状态，
+	// 在此之后结束时汇总帧一定会进入队列
+	task, err := h.batchTaskService.GetBatchTask(userID, taskID)
+	if err != nil {
+		h.removeConn(key, conn)
+		wsConn.Close()
+		return
+	}
+
+	// 发送当前任务状态快照
+	_ = conn.writeJSON(gin.H{
+		"type":      "snapshot",
+		"task_id":   key,
+		"task":      task,
+		"timestamp": time.Now().UnixMilli(),
+	})
+
+	// 任务未在运行（未启动或已结束）时直接发送汇总并关闭
+	if task.Status != "running" {
+		_ = conn.writeJSON(&batchtask.ExecutionEvent{
+			Type:         batchtask.EventSummary,
+			TaskID:       key,
+			Status:       task.Status,
+			SuccessCount: task.SuccessCount,
+			FailedCount:  task.FailedCount,
+			Total:        len(task.ServerIDs),
+			Timestamp:    time.Now().UnixMilli(),
+		})
+		h.removeConn(key, conn)
+		_ = wsConn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		wsConn.Close()
+		return
+	}
+
+	// 配置 WebSocket 超时和限制
+	_ = wsConn.SetReadDeadline(time.Now().Add(wsPongWait))
+	wsConn.SetReadLimit(1 << 10) // 1KB，只接收心跳
+	wsConn.SetPongHandler(func(appData string) error {
+		return wsConn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// 启动写协程（发送队列中的事件和心跳）
+	go conn.writePump(key)
+
+	// 读取客户端消息，直到连接关闭（仅用于心跳和断线检测）
+	for {
+		if _, _, err := wsConn.ReadMessage(); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("[BatchTaskWS] 客户端正常关闭: taskID=%s", key)
+			} else {
+				log.Printf("[BatchTaskWS] 读取错误: taskID=%s, error=%v", key, err)
+			}
+			break
+		}
+	}
+
+	// 清理
+	h.removeConn(key, conn)
+
+	log.Printf("[BatchTaskWS] 连接已关闭: taskID=%s", key)
+}
+
+// Publish 推送事件到订阅该任务的所有连接（实现 batchtask.EventPublisher）
+// 只放入各连接的发送队列，不阻塞执行器；队列已满的慢连接会被断开
+func (h *BatchTaskStreamHandler) Publish(taskID string, event *batchtask.ExecutionEvent) {
+	h.mu.RLock()
+	conns := make([]*batchTaskConn, 0, len(h.connections[taskID]))
+	for conn := range h.connections[taskID] {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	final := event.Type == batchtask.EventSummary
+	for _, conn := range conns {
+		if !conn.enqueue(event, final) {
+			log.Printf("[BatchTaskWS] 客户端接收过慢，断开连接: taskID=%s", taskID)
+			h.removeConn(taskID, conn)
+			continue
+		}
+
+		// 汇总帧之后不再有事件，取消订阅（写协程发送完汇总帧后关闭连接）
+		if final {
+			h.unregister(taskID, conn)
+		}
+	}
+}
+
+// unregister 取消连接对任务的订阅
+func (h *BatchTaskStreamHandler) unregister(taskID string, conn *batchTaskConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if conns, ok := h.connections[taskID]; ok {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(h.connections, taskID)
+		}
+	}
+}
+
+// removeConn 取消订阅并通知写协程关闭连接
+func (h *BatchTaskStreamHandler) removeConn(taskID string, conn *batchTaskConn) {
+	h.unregister(taskID, conn)
+	conn.close()
+}
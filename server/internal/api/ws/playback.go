@@ -0,0 +1,438 @@
+package ws
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/sshsession"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// playbackPositionInterval 播放中上报进度的间隔
+	playbackPositionInterval = time.Second
+	// playbackMaxFrameSize 快进（seek）时合并输出的单帧上限
+	playbackMaxFrameSize = 64 * 1024
+	// playbackMinSpeed / playbackMaxSpeed 播放速度范围
+	playbackMinSpeed = 0.1
+	playbackMaxSpeed = 16
+)
+
+// 回放状态
+const (
+	playbackStatePlaying = "playing"
+	playbackStatePaused  = "paused"
+	playbackStateEnded   = "ended"
+)
+
+// PlaybackInfo 回放信息（连接建立后发送）
+type PlaybackInfo struct {
+	SessionID string  `json:"session_id"`
+	Width     int     `json:"width"`
+	Height    int     `json:"height"`
+	Duration  float64 `json:"duration"` // 录像时长（秒）
+	Title     string  `json:"title,omitempty"`
+	StartedAt int64   `json:"started_at"` // 会话开始时间（Unix 秒）
+}
+
+// PlaybackState 回放状态消息
+type PlaybackState struct {
+	State    string  `json:"state"`    // playing/paused/ended
+	Position float64 `json:"position"` // 当前位置（秒）
+	Speed    float64 `json:"speed"`
+}
+
+// PlaybackControl 客户端控制消息数据
+type PlaybackControl struct {
+	Position *float64 `json:"position,omitempty"` // seek 目标位置（秒）
+	Speed    *float64 `json:"speed,omitempty"`    // 播放速度
+}
+
+// PlaybackHandler 终端录像回放 WebSocket 处理器
+// 输出沿用终端的二进制帧协议，前端可以直接复用 xterm 渲染
+type PlaybackHandler struct {
+	sshSessionService sshsession.Service
+}
+
+// NewPlaybackHandler 创建录像回放处理器
+func NewPlaybackHandler(sshSessionService sshsession.Service) *PlaybackHandler {
+	return &PlaybackHandler{sshSessionService: sshSessionService}
+}
+
+// HandlePlayback 处理录像回放 WebSocket 连接
+// WS /api/v1/ssh-sessions/:id/playback?t=120.5&speed=2&autoplay=true
+//
+// 服务端 -> 客户端：
+//   - 二进制帧：终端输出
+//   - {"type":"playback_info"}：录像信息
+//   - {"type":"resize","data":{"cols","rows"}}：终端尺寸变化
+//   - {"type":"reset"}：跳转前清屏
+//   - {"type":"state","data":{"state","position","speed"}}：播放状态与进度
+//
+// 客户端 -> 服务端：play、pause、seek {"position"}、speed {"speed"}、ping
+func (h *PlaybackHandler) HandlePlayback(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user_id"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id"})
+		return
+	}
+
+	// 管理员可以回放所有用户的录像用于审计
+	role, _ := c.Get("role")
+	session, recording, err := h.sshSessionService.OpenRecording(userID, id, role == "admin")
+	if err != nil {
+		switch err {
+		case sshsession.ErrUnauthorized:
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		case sshsession.ErrSSHSessionNotFound, sshsession.ErrRecordingNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	defer recording.Close()
+
+	// 解析初始播放参数
+	start := parseFloatQuery(c, "t", 0)
+	speed := clampSpeed(parseFloatQuery(c, "speed", 1))
+	autoplay := c.DefaultQuery("autoplay", "true") != "false"
+
+	wsConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("[Playback] 升级失败: %v", err)
+		return
+	}
+	defer wsConn.Close()
+
+	log.Printf("[Playback] 开始回放: session=%s, user=%s", session.ID, userID)
+
+	player := &playbackPlayer{
+		conn:      wsConn,
+		recording: recording,
+		speed:     speed,
+	}
+
+	player.sendJSON("playback_info", PlaybackInfo{
+		SessionID: session.ID.String(),
+		Width:     recording.Header.Width,
+		Height:    recording.Header.Height,
+		Duration:  recording.Duration(),
+		Title:     recording.Header.Title,
+		StartedAt: recording.Header.Timestamp,
+	})
+
+	// 读取客户端控制消息
+	controls := make(chan Message, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wsConn.SetReadLimit(4 * 1024)
+		for {
+			messageType, data, err := wsConn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType != websocket.TextMessage {
+				continue
+			}
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			select {
+			case controls <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	player.seek(start)
+	if autoplay {
+		player.play()
+	} else {
+		player.sendState()
+	}
+	player.run(controls, done)
+
+	log.Printf("[Playback] 回放结束: session=%s, user=%s", session.ID, userID)
+}
+
+// playbackPlayer 单个回放连接的播放状态
+type playbackPlayer struct {
+	conn      *websocket.Conn
+	writeMu   sync.Mutex
+	recording *sshsession.Recording
+
+	next     *sshsession.RecordingEvent // 下一个待发送的事件，nil 表示已读完
+	position float64                    // anchor 时刻对应的录像位置（秒）
+	anchor   time.Time                  // 开始播放（或调整速度）的时刻
+	speed    float64
+	playing  bool
+}
+
+// run 播放主循环，直到连接关闭
+func (p *playbackPlayer) run(controls <-chan Message, done <-chan struct{}) {
+	ticker := time.NewTicker(playbackPositionInterval)
+	defer ticker.Stop()
+
+	for {
+		// 计算到下一个事件的等待时间
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if p.playing && p.next != nil {
+			wait := (p.next.Time - p.currentPosition()) / p.speed
+			timer = time.NewTimer(time.Duration(math.Max(wait, 0) * float64(time.Second)))
+			timerC = timer.C
+		}
+
+		select {
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-timerC:
+			p.emitDue()
+		case msg := <-controls:
+			p.handleControl(msg)
+		case <-ticker.C:
+			if p.playing {
+				p.sendState()
+			}
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// handleControl 处理客户端控制消息
+func (p *playbackPlayer) handleControl(msg Message) {
+	var ctl PlaybackControl
+	if len(msg.Data) > 0 {
+		_ = json.Unmarshal(msg.Data, &ctl)
+	}
+
+	switch msg.Type {
+	case "play":
+		p.play()
+	case "pause":
+		p.pause()
+	case "seek":
+		if ctl.Position == nil {
+			return
+		}
+		wasPlaying := p.playing
+		p.seek(*ctl.Position)
+		if wasPlaying {
+			p.play()
+		} else {
+			p.sendState()
+		}
+	case "speed":
+		if ctl.Speed == nil {
+			return
+		}
+		p.position = p.currentPosition()
+		p.anchor = time.Now()
+		p.speed = clampSpeed(*ctl.Speed)
+		p.sendState()
+	case "ping":
+		p.sendJSON("pong", nil)
+	}
+}
+
+// currentPosition 当前播放位置（秒）
+func (p *playbackPlayer) currentPosition() float64 {
+	if !p.playing {
+		return p.position
+	}
+	return p.position + time.Since(p.anchor).Seconds()*p.speed
+}
+
+// play 开始或继续播放（已播放完毕时从头开始）
+func (p *playbackPlayer) play() {
+	if p.playing || p.recording.Len() == 0 {
+		p.sendState()
+		return
+	}
+	if p.next == nil {
+		p.seek(0)
+	}
+	p.playing = true
+	p.anchor = time.Now()
+	p.sendState()
+}
+
+// pause 暂停播放
+func (p *playbackPlayer) pause() {
+	if !p.playing {
+		return
+	}
+	p.position = p.currentPosition()
+	p.playing = false
+	p.sendState()
+}
+
+// emitDue 发送所有已到时间的事件
+func (p *playbackPlayer) emitDue() {
+	now := p.currentPosition()
+	for p.next != nil && p.next.Time <= now {
+		p.emit(*p.next)
+		p.next = p.readEvent()
+	}
+
+	if p.next == nil {
+		p.position = p.recording.Duration()
+		p.playing = false
+		p.sendState()
+	}
+}
+
+// seek 跳转到指定位置，快速重放到该位置为止的输出
+// 向后跳转（或已播放完毕）时清屏并从最近的重放起点开始，向前跳转时从当前位置继续
+func (p *playbackPlayer) seek(position float64) {
+	position = math.Max(0, math.Min(position, p.recording.Duration()))
+	current := p.currentPosition()
+	p.playing = false
+
+	if position < current || p.next == nil {
+		width, height, err := p.recording.Rewind(position)
+		if err != nil {
+			log.Printf("[Playback] 定位录像失败: %v", err)
+			p.next = nil
+			p.position = position
+			return
+		}
+		p.sendJSON("reset", nil)
+		p.sendJSON("resize", ResizeMessage{Cols: width, Rows: height})
+		p.next = p.readEvent()
+	}
+
+	var frame []byte
+	for p.next != nil && p.next.Time <= position {
+		switch p.next.Type {
+		case sshsession.RecordingEventOutput:
+			frame = append(frame, p.next.Data...)
+			if len(frame) >= playbackMaxFrameSize {
+				p.sendBinary(frame)
+				frame = frame[:0]
+			}
+		case sshsession.RecordingEventResize:
+			if len(frame) > 0 {
+				p.sendBinary(frame)
+				frame = frame[:0]
+			}
+			p.emit(*p.next)
+		}
+		p.next = p.readEvent()
+	}
+	if len(frame) > 0 {
+		p.sendBinary(frame)
+	}
+
+	p.position = position
+}
+
+// readEvent 从录像中读取下一个事件，读完或读取失败时返回 nil
+func (p *playbackPlayer) readEvent() *sshsession.RecordingEvent {
+	event, err := p.recording.Next()
+	if err != nil {
+		if err != io.EOF {
+			log.Printf("[Playback] 读取录像失败: %v", err)
+		}
+		return nil
+	}
+	return &event
+}
+
+// emit 发送单个事件（输入事件不回放，终端回显已包含在输出中）
+func (p *playbackPlayer) emit(event sshsession.RecordingEvent) {
+	switch event.Type {
+	case sshsession.RecordingEventOutput:
+		p.sendBinary([]byte(event.Data))
+	case sshsession.RecordingEventResize:
+		if cols, rows, ok := sshsession.ParseResize(event.Data); ok {
+			p.sendJSON("resize", ResizeMessage{Cols: cols, Rows: rows})
+		}
+	}
+}
+
+// sendState 发送当前播放状态
+func (p *playbackPlayer) sendState() {
+	state := playbackStatePaused
+	if p.playing {
+		state = playbackStatePlaying
+	} else if p.next == nil {
+		state = playbackStateEnded
+	}
+	p.sendJSON("state", PlaybackState{
+		State:    state,
+		Position: math.Round(p.currentPosition()*1000) / 1000,
+		Speed:    p.speed,
+	})
+}
+
+// sendJSON 发送 JSON 控制消息
+func (p *playbackPlayer) sendJSON(msgType string, data interface{}) {
+	msg := Message{Type: msgType}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		msg.Data = encoded
+	}
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	_ = p.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	_ = p.conn.WriteJSON(msg)
+}
+
+// sendBinary 发送终端输出（二进制帧）
+func (p *playbackPlayer) sendBinary(data []byte) {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	_ = p.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	_ = p.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// parseFloatQuery 读取浮点数查询参数
+func parseFloatQuery(c *gin.Context, key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(c.Query(key), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return defaultValue
+	}
+	return value
+}
+
+// clampSpeed 将播放速度限制在允许范围内
+func clampSpeed(speed float64) float64 {
+	if math.IsNaN(speed) || speed <= 0 {
+		return 1
+	}
+	return math.Max(playbackMinSpeed, math.Min(speed, playbackMaxSpeed))
+}
@@ -10,19 +10,28 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/easyssh/server/internal/domain/auditlog"
+	"github.com/easyssh/server/internal/domain/cmdguard"
 	"github.com/easyssh/server/internal/domain/server"
 	"github.com/easyssh/server/internal/domain/settings"
 	sshDomain "github.com/easyssh/server/internal/domain/ssh"
 	"github.com/easyssh/server/internal/domain/sshsession"
-	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/easyssh/server/internal/domain/tabsession"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"golang.org/x/crypto/ssh"
 )
 
+// sshInitTimeout 建立 SSH 连接和初始化终端的超时时间
+const sshInitTimeout = 10 * time.Second
+
+// sessionMetricsFlushInterval 会话流量统计写入数据库的间隔
+const sessionMetricsFlushInterval = 30 * time.Second
+
 // getUpgrader 创建 WebSocket upgrader，集成 CORS 配置
 func (h *TerminalHandler) getUpgrader() websocket.Upgrader {
 	return websocket.Upgrader{
@@ -87,25 +96,70 @@ type TerminalHandler struct {
 	serverService     server.Service
 	serverRepo        server.Repository
 	sessionManager    *sshDomain.SessionManager
-	encryptor         *crypto.Encryptor
+	connManager       *sshDomain.ConnectionManager // SSH 连接管理器（与 SFTP、监控共享连接）
 	sshSessionService sshsession.Service
-	hostKeyCallback   ssh.HostKeyCallback // SSH主机密钥验证回调
-	configManager     settings.ConfigManager // CORS 配置管理器
+	configManager     *settings.ConfigManager // CORS 与录像配置管理器
+	recordingStore    *sshsession.RecordingStore // 终端录像存储（为空时不录制）
+	tabSessionService tabsession.Service         // 标签/会话配置（断线保持时间）
+	auditLogService   auditlog.Service           // 审计日志（记录加入/离开共享会话）
+	commandGuardService cmdguard.Service // 危险命令规则（为空时不检查输入）
+	alertNotifier       AlertNotifier    // 危险命令告警通知
 }
 
 // NewTerminalHandler 创建终端处理器
-func NewTerminalHandler(serverService server.Service, serverRepo server.Repository, sessionManager *sshDomain.SessionManager, encryptor *crypto.Encryptor, sshSessionService sshsession.Service, hostKeyCallback ssh.HostKeyCallback, configManager settings.ConfigManager) *TerminalHandler {
+func NewTerminalHandler(serverService server.Service, serverRepo server.Repository, sessionManager *sshDomain.SessionManager, connManager *sshDomain.ConnectionManager, sshSessionService sshsession.Service, configManager *settings.ConfigManager) *TerminalHandler {
 	return &TerminalHandler{
 		serverService:     serverService,
 		serverRepo:        serverRepo,
 		sessionManager:    sessionManager,
-		encryptor:         encryptor,
+		connManager:       connManager,
 		sshSessionService: sshSessionService,
-		hostKeyCallback:   hostKeyCallback,
 		configManager:     configManager,
 	}
 }
 
+// SetRecordingStore 设置终端录像存储
+func (h *TerminalHandler) SetRecordingStore(store *sshsession.RecordingStore) {
+	h.recordingStore = store
+}
+
+// SetTabSessionService 设置标签/会话配置服务（未设置时断线即关闭会话）
+func (h *TerminalHandler) SetTabSessionService(service tabsession.Service) {
+	h.tabSessionService = service
+}
+
+// SetAuditLogService 设置审计日志服务
+func (h *TerminalHandler) SetAuditLogService(service auditlog.Service) {
+	h.auditLogService = service
+}
+
+// startRecording 按全局设置和服务器设置决定是否录制会话，未开启或失败时返回 nil
+func (h *TerminalHandler) startRecording(srv *server.Server, sessionID string, cols, rows int) *sshsession.Recorder {
+	if h.recordingStore == nil {
+		return nil
+	}
+
+	config, err := h.configManager.GetRecordingConfig(context.Background())
+	if err != nil {
+		log.Printf("[Recording] 读取录像配置失败: error=%v", err)
+		config = &settings.RecordingConfig{}
+	}
+	if !srv.ShouldRecord(config.Enabled) {
+		return nil
+	}
+
+	title := srv.Name
+	if title == "" {
+		title = srv.Host
+	}
+	recorder, err := h.recordingStore.Create(sessionID, cols, rows, title, config.RecordInput)
+	if err != nil {
+		log.Printf("[Recording] 创建录像失败: session=%s, error=%v", sessionID, err)
+		return nil
+	}
+	return recorder
+}
+
 // Message WebSocket 消息
 type Message struct {
 	Type string          `json:"type"`
@@ -191,10 +245,16 @@ func (h *TerminalHandler) HandleSSH(c *gin.Context) {
 		stdin     io.WriteCloser
 		stdout    io.Reader
 		stderr    io.Reader
+		recorder  *sshsession.Recorder
+		srv       *server.Server
 		err       error
 	}
 	resultChan := make(chan initResult, 1)
 
+	// 键盘交互认证的问题通过 WebSocket 转发给用户回答
+	var initWriteMu sync.Mutex
+	relay := &authRelay{conn: wsConn, writeMu: &initWriteMu, prompting: make(chan struct{}, 1)}
+
 	// 异步建立SSH连接和初始化
 	go func() {
 		// 获取服务器信息
@@ -204,15 +264,9 @@ func (h *TerminalHandler) HandleSSH(c *gin.Context) {
 			return
 		}
 
-		// 创建 SSH 客户端（使用主机密钥验证）
-		client, err := sshDomain.NewClient(srv, h.encryptor, h.hostKeyCallback)
+		// 获取 SSH 连接（同一用户和服务器的连接由终端、SFTP、监控共享，需要新建连接时认证问题转发给用户）
+		lease, err := h.connManager.Acquire(context.Background(), userID, srv, relay.challenge)
 		if err != nil {
-			resultChan <- initResult{err: fmt.Errorf("client_creation_failed: %w", err)}
-			return
-		}
-
-		// 连接到服务器
-		if err := client.Connect(srv.Host, srv.Port); err != nil {
 			// 异步更新服务器状态为离线
 			go func() {
 				srv.UpdateStatus(server.StatusOffline)
@@ -233,20 +287,21 @@ func (h *TerminalHandler) HandleSSH(c *gin.Context) {
 		}()
 
 		// 创建 SSH 会话
-		sshSession, err := client.NewSession()
+		sshSession, err := lease.Client().NewSession()
 		if err != nil {
-			client.Close()
+			lease.Discard()
 			resultChan <- initResult{err: fmt.Errorf("session_creation_failed: %w", err)}
 			return
 		}
 
-		// 创建会话记录
-		session := sshDomain.NewSession(userID, serverID, client, cols, rows)
+		// 创建会话记录（会话关闭时释放连接租用）
+		session := sshDomain.NewLeasedSession(userID, srv, lease, cols, rows)
 		session.SSHSession = sshSession
 
 		// 获取客户端IP
 		clientIP := c.ClientIP()
 		clientPort := 0 // WebSocket无法获取客户端端口，使用0
+		session.ClientIP = clientIP
 
 		// 异步创建数据库会话记录
 		var dbSession *sshsession.SSHSession
@@ -278,6 +333,7 @@ func (h *TerminalHandler) HandleSSH(c *gin.Context) {
 
 		// 请求伪终端
 		if err := sshSession.RequestPty("xterm-256color", rows, cols, modes); err != nil {
+			session.Close()
 			resultChan <- initResult{err: fmt.Errorf("pty_request_failed: %w", err)}
 			return
 		}
@@ -285,24 +341,28 @@ func (h *TerminalHandler) HandleSSH(c *gin.Context) {
 		// 获取输入输出管道
 		stdin, err := sshSession.StdinPipe()
 		if err != nil {
+			session.Close()
 			resultChan <- initResult{err: fmt.Errorf("stdin_pipe_failed: %w", err)}
 			return
 		}
 
 		stdout, err := sshSession.StdoutPipe()
 		if err != nil {
+			session.Close()
 			resultChan <- initResult{err: fmt.Errorf("stdout_pipe_failed: %w", err)}
 			return
 		}
 
 		stderr, err := sshSession.StderrPipe()
 		if err != nil {
+			session.Close()
 			resultChan <- initResult{err: fmt.Errorf("stderr_pipe_failed: %w", err)}
 			return
 		}
 
 		// 启动 shell
 		if err := sshSession.Shell(); err != nil {
+			session.Close()
 			resultChan <- initResult{err: fmt.Errorf("shell_start_failed: %w", err)}
 			return
 		}
@@ -321,170 +381,311 @@ func (h *TerminalHandler) HandleSSH(c *gin.Context) {
 			stdin:     stdin,
 			stdout:    stdout,
 			stderr:    stderr,
+			recorder:  h.startRecording(srv, session.ID, cols, rows),
+			srv:       srv,
 			err:       nil,
 		}
 	}()
 
-	// 等待初始化完成或超时
+	// 等待初始化完成或超时（转发认证问题时延长超时，给用户留出回答时间）
 	var result initResult
-	select {
-	case result = <-resultChan:
-		if result.err != nil {
-			h.sendError(wsConn, "initialization_failed", result.err.Error())
+	timeout := time.NewTimer(sshInitTimeout)
+	defer timeout.Stop()
+wait:
+	for {
+		select {
+		case result = <-resultChan:
+			if result.err != nil {
+				h.sendError(wsConn, "initialization_failed", result.err.Error())
+				return
+			}
+			break wait
+		case <-relay.prompting:
+			timeout.Reset(authPromptTimeout + sshInitTimeout)
+		case <-timeout.C:
+			initWriteMu.Lock()
+			h.sendError(wsConn, "initialization_timeout", "SSH connection timeout")
+			initWriteMu.Unlock()
+			// 初始化稍后完成时关闭会话，释放连接租用
+			go func() {
+				if late := <-resultChan; late.session != nil {
+					late.session.Close()
+				}
+			}()
 			return
 		}
-	case <-time.After(10 * time.Second):
-		h.sendError(wsConn, "initialization_timeout", "SSH connection timeout")
-		return
 	}
 
-	// 初始化成功，注册会话
+	// 初始化成功，注册会话并启动输出转发（会话生命周期与 WebSocket 连接解耦）
 	session := result.session
-	dbSession := result.dbSession
-	stdin := result.stdin
-	stdout := result.stdout
-	stderr := result.stderr
-
 	h.sessionManager.Add(session)
-	defer h.sessionManager.Remove(session.ID)
+	h.startSession(session, result.dbSession, result.recorder, result.stdin, result.stdout, result.stderr)
 
-	// 发送连接成功消息
-	h.sendMessage(wsConn, Message{
-		Type: "connected",
-		Data: json.RawMessage(fmt.Sprintf(`{"session_id":"%s"}`, session.ID)),
-	})
+	client := newTerminalClient(wsConn, session, userID, sshDomain.ShareModeInteractive)
+	client.guard = h.newCommandGuard(c, session, result.srv, userID)
+	if err := client.attach(false); err != nil {
+		h.sendError(wsConn, "session_closed", "SSH session has ended")
+		return
+	}
+	client.run()
+
+	// 连接断开：会话已结束时为空操作，否则进入断线保持
+	session.Detach(client.attachment.ID, h.detachGracePeriod())
+}
+
+// HandleAttach 连接到已有会话，连接后先回放最近的输出
+// 所有者用于断线后重新连接，被授权的其他用户按授权模式（只读/交互）加入，加入和离开均记录审计日志
+// WS /api/v1/ssh/sessions/:id/attach?cols=&rows=
+func (h *TerminalHandler) HandleAttach(c *gin.Context) {
+	// 从上下文获取用户 ID
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDStr.(string)
 
-	// 创建停止通道和关闭保护
-	done := make(chan struct{})
-	var closeOnce sync.Once
-	closeChannel := func() {
-		closeOnce.Do(func() {
-			close(done)
-		})
+	session, err := h.sessionManager.Get(c.Param("id"))
+	if err != nil || !session.IsActive() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session_not_found"})
+		return
+	}
+	mode, allowed := session.Access(userID)
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
 	}
 
-	// 从 SSH 读取并发送到 WebSocket（stdout）- 使用二进制传输
-	go func() {
-		buf := make([]byte, 32768) // 增大缓冲区以提高性能
-		for {
-			n, err := stdout.Read(buf)
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading from stdout: %v", err)
-				}
-				closeChannel()
-				return
-			}
+	// 获取终端尺寸参数（未提供时保持会话当前尺寸）
+	var cols, rows int
+	if colsStr := c.Query("cols"); colsStr != "" {
+		fmt.Sscanf(colsStr, "%d", &cols)
+	}
+	if rowsStr := c.Query("rows"); rowsStr != "" {
+		fmt.Sscanf(rowsStr, "%d", &rows)
+	}
 
-			if n > 0 {
-				// 直接发送二进制数据，不使用 JSON 包装
-				if err := wsConn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
-					log.Printf("Error sending output: %v", err)
-					closeChannel()
-					return
-				}
-			}
+	// 升级到 WebSocket
+	upgrader := h.getUpgrader()
+	wsConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade to WebSocket: %v", err)
+		return
+	}
+	defer wsConn.Close()
+
+	// 危险命令防护已启用但无法创建时（缺少服务器信息）降级为只读，输入不能绕过检查
+	var guard *commandGuard
+	if mode == sshDomain.ShareModeInteractive && h.commandGuardService != nil {
+		if guard = h.newCommandGuard(c, session, session.Server, userID); guard == nil {
+			log.Printf("[CommandGuard] 会话缺少服务器信息，以只读方式连接: session=%s, user=%s", session.ID, userID)
+			mode = sshDomain.ShareModeView
 		}
-	}()
+	}
+
+	// 只读客户端不能改变终端尺寸
+	if cols > 0 && rows > 0 && mode == sshDomain.ShareModeInteractive {
+		if err := session.ResizeTerminal(cols, rows); err != nil {
+			log.Printf("Error resizing terminal: %v", err)
+		}
+	}
+
+	client := newTerminalClient(wsConn, session, userID, mode)
+	client.guard = guard
+	if err := client.attach(true); err != nil {
+		h.sendError(wsConn, "session_closed", "SSH session has ended")
+		return
+	}
+	h.auditSessionAccess(c, session, client.attachment, auditlog.ActionSSHSessionJoin)
+
+	client.run()
+
+	// 连接断开：会话已结束时为空操作，否则进入断线保持
+	session.Detach(client.attachment.ID, h.detachGracePeriod())
+	h.auditSessionAccess(c, session, client.attachment, auditlog.ActionSSHSessionLeave)
+}
 
-	// 从 SSH 读取并发送到 WebSocket（stderr）- 也使用二进制传输
+// HandleShadow 管理员以只读方式实时观看任意用户的会话，加入和离开均记录审计日志
+// WS /api/v1/admin/ssh/sessions/:id/shadow
+func (h *TerminalHandler) HandleShadow(c *gin.Context) {
+	// 从上下文获取用户 ID
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	userID := userIDStr.(string)
+
+	session, err := h.sessionManager.Get(c.Param("id"))
+	if err != nil || !session.IsActive() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session_not_found"})
+		return
+	}
+
+	// 升级到 WebSocket
+	upgrader := h.getUpgrader()
+	wsConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade to WebSocket: %v", err)
+		return
+	}
+	defer wsConn.Close()
+
+	client := newTerminalClient(wsConn, session, userID, sshDomain.ShareModeView)
+	if err := client.attach(true); err != nil {
+		h.sendError(wsConn, "session_closed", "SSH session has ended")
+		return
+	}
+	h.auditSessionAccess(c, session, client.attachment, auditlog.ActionSSHSessionShadow)
+
+	client.run()
+
+	session.Detach(client.attachment.ID, h.detachGracePeriod())
+	h.auditSessionAccess(c, session, client.attachment, auditlog.ActionSSHSessionLeave)
+}
+
+// auditSessionAccess 记录加入/离开会话的审计日志
+func (h *TerminalHandler) auditSessionAccess(c *gin.Context, session *sshDomain.Session, attachment *sshDomain.Attachment, action auditlog.ActionType) {
+	if h.auditLogService == nil {
+		return
+	}
+
+	userID, err := uuid.Parse(attachment.UserID)
+	if err != nil {
+		return
+	}
+	username, _ := c.Get("username")
+	usernameStr, _ := username.(string)
+
+	var serverID *uuid.UUID
+	if sid, err := uuid.Parse(session.ServerID); err == nil {
+		serverID = &sid
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"session_id": session.ID,
+		"owner_id":   session.UserID,
+		"mode":       attachment.Mode,
+	})
+
+	req := &auditlog.CreateAuditLogRequest{
+		UserID:    userID,
+		Username:  usernameStr,
+		ServerID:  serverID,
+		Action:    action,
+		Resource:  session.ID,
+		Status:    auditlog.StatusSuccess,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   string(details),
+	}
+	if action == auditlog.ActionSSHSessionLeave {
+		req.Duration = time.Since(attachment.JoinedAt).Milliseconds()
+	}
+
+	if err := h.auditLogService.Log(context.Background(), req); err != nil {
+		log.Printf("Failed to write session audit log: %v", err)
+	}
+}
+
+// startSession 启动会话的输出转发，并在会话结束时完成录像、文本索引、流量统计和数据库记录
+func (h *TerminalHandler) startSession(session *sshDomain.Session, dbSession *sshsession.SSHSession, recorder *sshsession.Recorder, stdin io.Writer, stdout, stderr io.Reader) {
+	// 记录去除控制序列后的输出文本用于全文检索，时间轴与录像保持一致
+	transcript := h.sshSessionService.StartTranscript(session.ID, recorder.StartedAt())
+
+	// 流量统计：sent 为发送到服务器的输入字节数，received 为从服务器接收的输出字节数
+	var bytesSent, bytesReceived atomic.Int64
 	go func() {
-		buf := make([]byte, 32768)
+		ticker := time.NewTicker(sessionMetricsFlushInterval)
+		defer ticker.Stop()
 		for {
-			n, err := stderr.Read(buf)
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading from stderr: %v", err)
-				}
+			select {
+			case <-session.Done():
 				return
-			}
-
-			if n > 0 {
-				// stderr 也直接发送二进制数据
-				if err := wsConn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
-					log.Printf("Error sending stderr: %v", err)
-					return
+			case <-ticker.C:
+				if err := h.sshSessionService.UpdateSessionMetrics(session.ID, bytesSent.Load(), bytesReceived.Load()); err != nil {
+					log.Printf("Failed to update SSH session metrics: %v", err)
 				}
 			}
 		}
 	}()
 
-	// 从 WebSocket 读取并发送到 SSH
-	go func() {
-		for {
-			messageType, message, err := wsConn.ReadMessage()
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("WebSocket error: %v", err)
+	session.Start(stdin, stdout, stderr, sshDomain.SessionHooks{
+		OnOutput: func(data []byte) {
+			bytesReceived.Add(int64(len(data)))
+			recorder.Output(data)
+			transcript.Write(data)
+		},
+		OnInput: func(data []byte) {
+			bytesSent.Add(int64(len(data)))
+			recorder.Input(data)
+		},
+		OnResize: recorder.Resize,
+		OnEnd: func(reason string) {
+			h.sessionManager.Remove(session.ID)
+			transcript.Close()
+
+			// 结束录像并关联到会话记录
+			if recorder != nil {
+				size, err := recorder.Close()
+				if err != nil {
+					log.Printf("[Recording] 关闭录像失败: session=%s, error=%v", session.ID, err)
 				}
-				closeChannel()
-				return
-			}
-
-			switch messageType {
-			case websocket.TextMessage:
-				// JSON 格式的控制消息
-				var msg Message
-				if err := json.Unmarshal(message, &msg); err != nil {
-					log.Printf("Error parsing message: %v", err)
-					continue
+				if err := h.sshSessionService.AttachRecording(session.ID, recorder.Path(), size); err != nil {
+					log.Printf("[Recording] 关联录像失败: session=%s, error=%v", session.ID, err)
 				}
+			}
 
-				switch msg.Type {
-				case "input":
-					var input InputMessage
-					if err := json.Unmarshal(msg.Data, &input); err != nil {
-						log.Printf("Error parsing input: %v", err)
-						continue
-					}
-					if _, err := stdin.Write([]byte(input.Data)); err != nil {
-						log.Printf("Error writing to stdin: %v", err)
-						closeChannel()
-						return
-					}
-
-				case "resize":
-					var resize ResizeMessage
-					if err := json.Unmarshal(msg.Data, &resize); err != nil {
-						log.Printf("Error parsing resize: %v", err)
-						continue
-					}
-					if err := session.ResizeTerminal(resize.Cols, resize.Rows); err != nil {
-						log.Printf("Error resizing terminal: %v", err)
-					}
-
-				case "ping":
-					h.sendMessage(wsConn, Message{Type: "pong"})
-				}
+			h.finishSessionRecord(session.ID, dbSession, reason, bytesSent.Load(), bytesReceived.Load())
+		},
+	})
+}
 
-			case websocket.BinaryMessage:
-				// 二进制数据直接作为输入发送到 SSH
-				if _, err := stdin.Write(message); err != nil {
-					log.Printf("Error writing binary to stdin: %v", err)
-					closeChannel()
-					return
-				}
-			}
+// finishSessionRecord 更新数据库会话记录：状态、流量统计与断开原因（时长由服务层计算）
+func (h *TerminalHandler) finishSessionRecord(sessionID string, dbSession *sshsession.SSHSession, reason string, sent, received int64) {
+	// 初始化时数据库记录可能尚未创建完成，此时按 SessionID 重新查询
+	if dbSession == nil {
+		if dbSess, err := h.sshSessionService.GetSSHSessionBySessionID(sessionID); err == nil {
+			dbSession = dbSess
 		}
-	}()
+	}
+	if dbSession == nil {
+		log.Printf("SSH session record not found, metrics dropped: session=%s", sessionID)
+		return
+	}
 
-	// 等待会话结束
-	<-done
+	status := "closed"
+	switch {
+	case reason == sshDomain.CloseReasonTimeout, reason == sshDomain.CloseReasonIdleTimeout:
+		status = "timeout"
+	case strings.HasPrefix(reason, sshDomain.CloseReasonTerminatedPrefix):
+		status = "terminated"
+	}
 
-	// 更新数据库会话记录状态为关闭
-	if dbSession != nil {
-		updateReq := &sshsession.UpdateSSHSessionRequest{
-			Status: "closed",
-		}
+	updateReq := &sshsession.UpdateSSHSessionRequest{
+		Status:        status,
+		BytesSent:     &sent,
+		BytesReceived: &received,
+		ErrorMessage:  reason,
+	}
 
-		if _, err := h.sshSessionService.UpdateSSHSession(dbSession.UserID, dbSession.ID, updateReq); err != nil {
-			log.Printf("Failed to update SSH session status: %v", err)
-		}
+	if _, err := h.sshSessionService.UpdateSSHSession(dbSession.UserID, dbSession.ID, updateReq); err != nil {
+		log.Printf("Failed to update SSH session status: %v", err)
+	}
+}
+
+// detachGracePeriod 获取终端断线后保持会话的时间（未启用休眠时为 0）
+func (h *TerminalHandler) detachGracePeriod() time.Duration {
+	if h.tabSessionService == nil {
+		return 0
 	}
 
-	// 尝试发送关闭消息（如果连接已关闭则静默忽略）
-	wsConn.SetWriteDeadline(time.Now().Add(time.Second))
-	_ = wsConn.WriteJSON(Message{Type: "closed"})
+	config, err := h.tabSessionService.GetTabSessionConfig(context.Background())
+	if err != nil {
+		log.Printf("Failed to load tab session config: %v", err)
+		return 0
+	}
+	return config.DetachGracePeriod()
 }
 
 // sendMessage 发送消息
@@ -0,0 +1,103 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/gorilla/websocket"
+)
+
+// authPromptTimeout 等待用户回答键盘交互认证问题的时间
+const authPromptTimeout = 2 * time.Minute
+
+var errAuthCancelled = errors.New("authentication cancelled by user")
+
+// AuthPrompt 键盘交互认证问题
+type AuthPrompt struct {
+	Prompt string `json:"prompt"`
+	Echo   bool   `json:"echo"` // false 表示密码类输入，前端不应回显
+}
+
+// AuthPromptMessage 键盘交互认证问题（服务端 -> 客户端，type=auth_prompt）
+type AuthPromptMessage struct {
+	Kind        string       `json:"kind"` // keyboard_interactive 或 passphrase
+	Host        string       `json:"host"`
+	User        string       `json:"user"`
+	Name        string       `json:"name"`
+	Instruction string       `json:"instruction"`
+	Prompts     []AuthPrompt `json:"prompts"`
+}
+
+// AuthResponseMessage 键盘交互认证回答（客户端 -> 服务端，type=auth_response）
+type AuthResponseMessage struct {
+	Answers []string `json:"answers"`
+}
+
+// authRelay 在 SSH 连接建立期间将键盘交互认证问题转发给浏览器
+// 连接建立期间只有认证回调读取 WebSocket，建立完成后再交给终端客户端读取
+type authRelay struct {
+	conn      *websocket.Conn
+	writeMu   *sync.Mutex   // 与等待初始化的协程共用，串行化写入
+	prompting chan struct{} // 每次转发问题时通知等待方延长初始化超时
+}
+
+// challenge 实现 sshDomain.KeyboardInteractiveHandler
+func (r *authRelay) challenge(ch sshDomain.KeyboardInteractiveChallenge) ([]string, error) {
+	select {
+	case r.prompting <- struct{}{}:
+	default:
+	}
+
+	prompts := make([]AuthPrompt, len(ch.Questions))
+	for i, q := range ch.Questions {
+		prompts[i] = AuthPrompt{Prompt: q, Echo: ch.Echos[i]}
+	}
+	data, _ := json.Marshal(AuthPromptMessage{
+		Kind:        ch.Kind,
+		Host:        ch.Host,
+		User:        ch.User,
+		Name:        ch.Name,
+		Instruction: ch.Instruction,
+		Prompts:     prompts,
+	})
+	msg, _ := json.Marshal(Message{Type: "auth_prompt", Data: data})
+
+	r.writeMu.Lock()
+	r.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	err := r.conn.WriteMessage(websocket.TextMessage, msg)
+	r.writeMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	r.conn.SetReadDeadline(time.Now().Add(authPromptTimeout))
+	defer r.conn.SetReadDeadline(time.Time{})
+
+	for {
+		messageType, message, err := r.conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(message, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "auth_response":
+			var resp AuthResponseMessage
+			if err := json.Unmarshal(msg.Data, &resp); err != nil {
+				return nil, err
+			}
+			return resp.Answers, nil
+		case "close":
+			return nil, errAuthCancelled
+		}
+	}
+}
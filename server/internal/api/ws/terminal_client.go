@@ -0,0 +1,185 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// terminalClient 附加到终端会话的一个 WebSocket 连接
+// 多个客户端可以同时附加到同一会话：输出推送给所有客户端，只读客户端的输入会被忽略
+type terminalClient struct {
+	conn       *websocket.Conn
+	session    *sshDomain.Session
+	attachment *sshDomain.Attachment
+	writeMu    sync.Mutex    // 输出推送、控制消息可能来自不同协程，写入需要串行化
+	guard      *commandGuard // 危险命令防护（为空时不检查输入）
+}
+
+// newTerminalClient 创建终端客户端
+func newTerminalClient(conn *websocket.Conn, session *sshDomain.Session, userID string, mode sshDomain.ShareMode) *terminalClient {
+	c := &terminalClient{conn: conn, session: session}
+	c.attachment = &sshDomain.Attachment{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		Mode:     mode,
+		JoinedAt: time.Now(),
+		Output: func(data []byte) {
+			// 直接发送二进制数据，不使用 JSON 包装
+			if err := c.write(websocket.BinaryMessage, data); err != nil {
+				log.Printf("Error sending output: %v", err)
+				conn.Close() // 读取循环随之结束并断开
+			}
+		},
+		Ended: func(reason string) {
+			c.sendReason("closed", reason)
+			conn.Close()
+		},
+		Kick: func(reason string) {
+			c.sendReason("kicked", reason)
+			conn.Close()
+		},
+		IdleWarning: func(remaining time.Duration) {
+			// 客户端有任何输入或会话有任何输出即可重新计时
+			data, _ := json.Marshal(map[string]int{"remaining": int(remaining.Seconds())})
+			c.sendJSON(Message{Type: "idle_warning", Data: data})
+		},
+	}
+	return c
+}
+
+// readOnly 是否为只读客户端
+func (c *terminalClient) readOnly() bool {
+	return c.attachment.Mode != sshDomain.ShareModeInteractive
+}
+
+// attach 附加到会话并发送连接消息和回滚输出
+// 连接消息和回滚输出必须先于实时输出发送：持有写锁完成附加，之后的输出推送会等待写锁
+func (c *terminalClient) attach(resumed bool) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	scrollback, err := c.session.Attach(c.attachment)
+	if err != nil {
+		return err
+	}
+
+	connected, _ := json.Marshal(map[string]interface{}{
+		"session_id": c.session.ID,
+		"resumed":    resumed,
+		"mode":       c.attachment.Mode,
+	})
+	data, _ := json.Marshal(Message{Type: "connected", Data: connected})
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Printf("Error sending message: %v", err)
+		c.conn.Close()
+		return nil
+	}
+	if len(scrollback) > 0 {
+		if err := c.conn.WriteMessage(websocket.BinaryMessage, scrollback); err != nil {
+			log.Printf("Error sending scrollback: %v", err)
+			c.conn.Close()
+		}
+	}
+	return nil
+}
+
+// run 从 WebSocket 读取并发送到 SSH，直到连接断开
+func (c *terminalClient) run() {
+	defer c.abandonCommand()
+
+	for {
+		messageType, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("WebSocket error: %v", err)
+			}
+			return
+		}
+
+		switch messageType {
+		case websocket.TextMessage:
+			// JSON 格式的控制消息
+			var msg Message
+			if err := json.Unmarshal(message, &msg); err != nil {
+				log.Printf("Error parsing message: %v", err)
+				continue
+			}
+
+			if msg.Type == "ping" {
+				c.sendJSON(Message{Type: "pong"})
+				continue
+			}
+			if c.readOnly() {
+				continue
+			}
+
+			switch msg.Type {
+			case "input":
+				var input InputMessage
+				if err := json.Unmarshal(msg.Data, &input); err != nil {
+					log.Printf("Error parsing input: %v", err)
+					continue
+				}
+				c.input([]byte(input.Data))
+
+			case "command_confirm_response":
+				var resp CommandConfirmResponse
+				if err := json.Unmarshal(msg.Data, &resp); err != nil {
+					log.Printf("Error parsing command confirm response: %v", err)
+					continue
+				}
+				c.resolveCommand(resp.ID, resp.Approved)
+
+			case "resize":
+				var resize ResizeMessage
+				if err := json.Unmarshal(msg.Data, &resize); err != nil {
+					log.Printf("Error parsing resize: %v", err)
+					continue
+				}
+				if err := c.session.ResizeTerminal(resize.Cols, resize.Rows); err != nil {
+					log.Printf("Error resizing terminal: %v", err)
+				}
+
+			case "close":
+				// 用户主动关闭标签页：立即结束会话，不进入断线保持
+				c.session.CloseWithReason(sshDomain.CloseReasonClosedByUser)
+			}
+
+		case websocket.BinaryMessage:
+			// 二进制数据直接作为输入发送到 SSH
+			if c.readOnly() {
+				continue
+			}
+			c.input(message)
+		}
+	}
+}
+
+// write 串行化写入 WebSocket
+func (c *terminalClient) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// sendJSON 发送 JSON 控制消息
+func (c *terminalClient) sendJSON(msg Message) {
+	data, _ := json.Marshal(msg)
+	if err := c.write(websocket.TextMessage, data); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+}
+
+// sendReason 发送带原因的控制消息（closed/kicked）
+func (c *terminalClient) sendReason(msgType, reason string) {
+	data, _ := json.Marshal(map[string]string{"reason": reason})
+	c.sendJSON(Message{Type: msgType, Data: data})
+}
@@ -0,0 +1,298 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/auditlog"
+	"github.com/easyssh/server/internal/domain/cmdguard"
+	"github.com/easyssh/server/internal/domain/server"
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// commandConfirmTimeout 等待用户确认危险命令的时间，超时视为取消
+const commandConfirmTimeout = time.Minute
+
+// killLine 阻止或取消命令时发送 Ctrl-U，清空远端 shell 中已输入的命令
+var killLine = []byte{0x15}
+
+// CommandBlockedMessage 命令被阻止（服务端 -> 客户端，type=command_blocked）
+type CommandBlockedMessage struct {
+	Command    string `json:"command"`
+	RuleName   string `json:"rule_name"`
+	FullScreen bool   `json:"full_screen,omitempty"` // 终端处于全屏程序中，可能不是 shell 命令
+}
+
+// CommandConfirmMessage 命令需要确认（服务端 -> 客户端，type=command_confirm）
+type CommandConfirmMessage struct {
+	ID         string `json:"id"`
+	Command    string `json:"command"`
+	RuleName   string `json:"rule_name"`
+	Timeout    int    `json:"timeout"`               // 秒，超时视为取消
+	FullScreen bool   `json:"full_screen,omitempty"` // 终端处于全屏程序中，可能不是 shell 命令
+}
+
+// CommandConfirmResponse 确认结果（客户端 -> 服务端，type=command_confirm_response）
+type CommandConfirmResponse struct {
+	ID       string `json:"id"`
+	Approved bool   `json:"approved"`
+}
+
+// commandReporter 记录命令匹配事件（审计日志、告警通知）
+type commandReporter func(match *cmdguard.Match, command string, action auditlog.ActionType)
+
+// commandGuard 终端客户端的危险命令防护
+// 命令行的重建状态保存在会话上（所有客户端共享），这里只保存规则匹配和上报所需的客户端信息
+// 全屏程序（vim 等）中的回车同样检查：是否处于全屏程序只能从远端输出得知，可以被伪造
+type commandGuard struct {
+	service cmdguard.Service
+	server  *server.Server
+	report  commandReporter
+}
+
+// input 将用户输入发送到 SSH，配置了危险命令防护时在回车前检查命令
+func (c *terminalClient) input(data []byte) {
+	if c.guard == nil {
+		c.writeStdin(data)
+		return
+	}
+
+	line := c.session.CommandLine()
+	line.Lock()
+	defer line.Unlock()
+	c.inputLocked(line, data)
+}
+
+// inputLocked 逐行检查并发送输入，调用方需持有 line 的锁
+func (c *terminalClient) inputLocked(line *sshDomain.CommandLine, data []byte) {
+	g := c.guard
+
+	// 等待确认期间丢弃输入，避免绕过确认
+	if line.Pending != nil {
+		return
+	}
+
+	for len(data) > 0 {
+		n, command, enter := line.Buffer.Scan(data)
+		if !enter {
+			c.writeStdin(data)
+			return
+		}
+
+		match := g.service.Check(g.server, command)
+		if match == nil || match.Action == cmdguard.ActionAlert {
+			c.writeStdin(data[:n+1])
+			if match != nil {
+				g.report(match, command, auditlog.ActionCommandAlert)
+			}
+			data = data[n+1:]
+			continue
+		}
+
+		// 发送回车之前的输入，拦截回车
+		c.writeStdin(data[:n])
+		fullScreen := line.AltScreen()
+
+		if match.Action == cmdguard.ActionBlock {
+			c.writeStdin(killLine)
+			g.report(match, command, auditlog.ActionCommandBlocked)
+			c.sendData("command_blocked", CommandBlockedMessage{Command: command, RuleName: match.RuleName, FullScreen: fullScreen})
+			return
+		}
+
+		id := uuid.New().String()
+		line.Pending = &sshDomain.PendingCommand{
+			ID:       id,
+			ClientID: c.attachment.ID,
+			Command:  command,
+			Match:    match,
+			Rest:     append([]byte(nil), data[n:]...),
+			Timer:    time.AfterFunc(commandConfirmTimeout, func() { c.resolveCommand(id, false) }),
+		}
+		c.sendData("command_confirm", CommandConfirmMessage{
+			ID:         id,
+			Command:    command,
+			RuleName:   match.RuleName,
+			Timeout:    int(commandConfirmTimeout.Seconds()),
+			FullScreen: fullScreen,
+		})
+		return
+	}
+}
+
+// resolveCommand 处理用户对危险命令的确认结果：确认后发送回车和后续输入，取消或超时则清空命令行
+// 只处理由当前客户端触发的确认
+func (c *terminalClient) resolveCommand(id string, approved bool) {
+	if c.guard == nil {
+		return
+	}
+
+	g := c.guard
+	line := c.session.CommandLine()
+	line.Lock()
+	defer line.Unlock()
+
+	p := line.Pending
+	if p == nil || p.ID != id || p.ClientID != c.attachment.ID {
+		return
+	}
+	p.Timer.Stop()
+	line.Pending = nil
+
+	if !approved {
+		c.writeStdin(killLine)
+		g.report(p.Match, p.Command, auditlog.ActionCommandRejected)
+		return
+	}
+
+	g.report(p.Match, p.Command, auditlog.ActionCommandConfirmed)
+	c.writeStdin(p.Rest[:1])
+	c.inputLocked(line, p.Rest[1:])
+}
+
+// abandonCommand 客户端断开时取消它触发的待确认命令，避免其他客户端的输入一直被丢弃
+func (c *terminalClient) abandonCommand() {
+	if c.guard == nil {
+		return
+	}
+
+	line := c.session.CommandLine()
+	line.Lock()
+	p := line.Pending
+	line.Unlock()
+
+	if p != nil && p.ClientID == c.attachment.ID {
+		c.resolveCommand(p.ID, false)
+	}
+}
+
+// writeStdin 写入 SSH 标准输入
+func (c *terminalClient) writeStdin(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	if _, err := c.session.Write(data); err != nil {
+		log.Printf("Error writing to stdin: %v", err)
+	}
+}
+
+// sendData 发送带数据的控制消息
+func (c *terminalClient) sendData(msgType string, v interface{}) {
+	data, _ := json.Marshal(v)
+	c.sendJSON(Message{Type: msgType, Data: data})
+}
+
+// AlertNotifier 发送告警通知（settings.Service.SendAlert）
+type AlertNotifier func(ctx context.Context, event, title, text string, data map[string]interface{}) error
+
+// SetCommandGuard 设置危险命令防护（未设置时不检查终端输入）
+func (h *TerminalHandler) SetCommandGuard(service cmdguard.Service, notifier AlertNotifier) {
+	h.commandGuardService = service
+	h.alertNotifier = notifier
+}
+
+// newCommandGuard 为交互客户端创建危险命令防护，未启用或缺少服务器信息时返回 nil
+func (h *TerminalHandler) newCommandGuard(c *gin.Context, session *sshDomain.Session, srv *server.Server, userID string) *commandGuard {
+	if h.commandGuardService == nil || srv == nil {
+		return nil
+	}
+
+	username, _ := c.Get("username")
+	usernameStr, _ := username.(string)
+	ip := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	report := func(match *cmdguard.Match, command string, action auditlog.ActionType) {
+		log.Printf("[CommandGuard] 命令匹配规则: session=%s, user=%s, rule=%s, action=%s, command=%q",
+			session.ID, usernameStr, match.RuleName, action, command)
+
+		// 审计和通知涉及数据库与外部请求，不阻塞终端输入
+		go func() {
+			h.auditCommand(session, srv, userID, usernameStr, ip, userAgent, match, command, action)
+			if action == auditlog.ActionCommandAlert || action == auditlog.ActionCommandBlocked {
+				h.notifyCommand(srv, usernameStr, ip, match, command, action)
+			}
+		}()
+	}
+
+	return &commandGuard{service: h.commandGuardService, server: srv, report: report}
+}
+
+// auditCommand 记录命令匹配规则的审计日志
+func (h *TerminalHandler) auditCommand(session *sshDomain.Session, srv *server.Server, userID, username, ip, userAgent string, match *cmdguard.Match, command string, action auditlog.ActionType) {
+	if h.auditLogService == nil {
+		return
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return
+	}
+
+	status := auditlog.StatusWarning
+	if action == auditlog.ActionCommandBlocked || action == auditlog.ActionCommandRejected {
+		status = auditlog.StatusFailure
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"session_id":  session.ID,
+		"command":     command,
+		"rule_id":     match.RuleID,
+		"rule_name":   match.RuleName,
+		"rule_action": match.Action,
+	})
+
+	req := &auditlog.CreateAuditLogRequest{
+		UserID:    uid,
+		Username:  username,
+		ServerID:  &srv.ID,
+		Action:    action,
+		Resource:  session.ID,
+		Status:    status,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   string(details),
+	}
+	if err := h.auditLogService.Log(context.Background(), req); err != nil {
+		log.Printf("[CommandGuard] 写入审计日志失败: %v", err)
+	}
+}
+
+// notifyCommand 发送危险命令告警通知
+func (h *TerminalHandler) notifyCommand(srv *server.Server, username, ip string, match *cmdguard.Match, command string, action auditlog.ActionType) {
+	if h.alertNotifier == nil {
+		return
+	}
+
+	title := "危险命令告警"
+	outcome := "已执行"
+	if action == auditlog.ActionCommandBlocked {
+		title = "危险命令已阻止"
+		outcome = "已阻止"
+	}
+
+	text := fmt.Sprintf("- **用户**: %s\n- **服务器**: %s (%s)\n- **命令**: `%s`\n- **规则**: %s\n- **结果**: %s\n- **来源IP**: %s\n- **时间**: %s",
+		username, srv.Name, srv.Host, command, match.RuleName, outcome, ip, time.Now().Format("2006-01-02 15:04:05"))
+	data := map[string]interface{}{
+		"user":      username,
+		"server_id": srv.ID,
+		"server":    srv.Name,
+		"host":      srv.Host,
+		"command":   command,
+		"rule_id":   match.RuleID,
+		"rule_name": match.RuleName,
+		"action":    action,
+		"ip":        ip,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := h.alertNotifier(ctx, string(action), title, text, data); err != nil {
+		log.Printf("[CommandGuard] 发送告警通知失败: %v", err)
+	}
+}
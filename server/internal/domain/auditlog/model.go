@@ -25,6 +25,36 @@ const (
 	ActionSSHConnect    ActionType = "ssh_connect"
 	ActionSSHDisconnect ActionType = "ssh_disconnect"
 
+	// 共享会话
+	ActionSSHShareGrant   ActionType = "ssh_share_grant"
+	ActionSSHShareRevoke  ActionType = "ssh_share_revoke"
+	ActionSSHSessionJoin  ActionType = "ssh_session_join"
+	ActionSSHSessionLeave ActionType = "ssh_session_leave"
+
+	// 管理员会话监控
+	ActionSSHSessionShadow    ActionType = "ssh_session_shadow"
+	ActionSSHSessionTerminate ActionType = "ssh_session_terminate"
+
+	// 端口转发隧道
+	ActionTunnelCreate     ActionType = "tunnel_create"
+	ActionTunnelUpdate     ActionType = "tunnel_update"
+	ActionTunnelDelete     ActionType = "tunnel_delete"
+	ActionTunnelStart      ActionType = "tunnel_start"
+	ActionTunnelStop       ActionType = "tunnel_stop"
+	ActionTunnelDisconnect ActionType = "tunnel_disconnect"
+
+	// Web 反向代理
+	ActionWebProxyAccess ActionType = "web_proxy_access"
+
+	// 危险命令防护
+	ActionCommandBlocked    ActionType = "command_blocked"
+	ActionCommandConfirmed  ActionType = "command_confirmed"
+	ActionCommandRejected   ActionType = "command_rejected"
+	ActionCommandAlert      ActionType = "command_alert"
+	ActionCommandRuleCreate ActionType = "command_rule_create"
+	ActionCommandRuleUpdate ActionType = "command_rule_update"
+	ActionCommandRuleDelete ActionType = "command_rule_delete"
+
 	// SFTP 操作
 	ActionSFTPUpload   ActionType = "sftp_upload"
 	ActionSFTPDownload ActionType = "sftp_download"
@@ -0,0 +1,100 @@
+package batchtask
+
+import (
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 实时事件类型
+const (
+	EventServerStart = "server_start" // 开始在某台服务器上执行
+	EventOutput      = "output"       // stdout/stderr 输出片段
+	EventServerDone  = "server_done"  // 某台服务器执行结束
+	EventSummary     = "summary"      // 任务整体执行结束
+)
+
+// ExecutionEvent 批量任务执行过程中的实时事件
+type ExecutionEvent struct {
+	Type         string `json:"type"`
+	TaskID       string `json:"task_id"`
+	ServerID     string `json:"server_id,omitempty"`
+	ServerName   string `json:"server_name,omitempty"`
+	Stream       string `json:"stream,omitempty"` // stdout/stderr
+	Data         string `json:"data,omitempty"`
+	ExitCode     *int   `json:"exit_code,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Error        string `json:"error,omitempty"`
+	SuccessCount int    `json:"success_count,omitempty"`
+	FailedCount  int    `json:"failed_count,omitempty"`
+	Total        int    `json:"total,omitempty"`
+	Timestamp    int64  `json:"timestamp"` // 毫秒时间戳
+}
+
+// EventPublisher 实时事件发布接口（由 WebSocket 层实现）
+type EventPublisher interface {
+	Publish(taskID string, event *ExecutionEvent)
+}
+
+// streamWriter 将输出片段转换为实时事件的 io.Writer
+// 末尾不完整的多字节字符留到下一次写入，避免同一个字符被拆到两个事件中
+type streamWriter struct {
+	publisher EventPublisher
+	taskID    string
+	serverID  string
+	stream    string
+
+	mu      sync.Mutex
+	pending []byte
+}
+
+// Write 实现 io.Writer
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	data := append(w.pending, p...)
+	complete, rest := splitIncompleteUTF8(data)
+	w.pending = append([]byte(nil), rest...)
+	w.mu.Unlock()
+
+	w.publish(complete)
+	return len(p), nil
+}
+
+// Flush 发送剩余的不完整字符（命令结束时调用）
+func (w *streamWriter) Flush() {
+	w.mu.Lock()
+	data := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	w.publish(data)
+}
+
+// publish 发布输出事件
+func (w *streamWriter) publish(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	w.publisher.Publish(w.taskID, &ExecutionEvent{
+		Type:      EventOutput,
+		TaskID:    w.taskID,
+		ServerID:  w.serverID,
+		Stream:    w.stream,
+		Data:      string(data),
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// splitIncompleteUTF8 将数据拆分为完整部分和末尾不完整的多字节字符
+func splitIncompleteUTF8(p []byte) ([]byte, []byte) {
+	// UTF-8 字符最长 4 字节，只需检查末尾 3 个字节
+	for i := len(p) - 1; i >= 0 && i >= len(p)-3; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return p[:i], p[i:]
+			}
+			break
+		}
+	}
+	return p, nil
+}
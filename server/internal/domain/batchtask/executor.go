@@ -0,0 +1,317 @@
+package batchtask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/script"
+	"github.com/easyssh/server/internal/domain/server"
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultServerTimeout 单台服务器的最长执行时间
+const defaultServerTimeout = 30 * time.Minute
+
+// Executor 批量任务执行器，负责通过 SSH 在目标服务器上真正执行任务
+type Executor struct {
+	service         Service
+	serverService   server.Service
+	scriptService   script.Service
+	encryptor       *crypto.Encryptor
+	hostKeyCallback ssh.HostKeyCallback
+	publisher       EventPublisher // 实时事件发布器（可选）
+	maxParallel     int
+	serverTimeout   time.Duration
+
+	// 执行器生命周期：Stop 时取消正在执行的任务并等待其写回结果
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+	mu      sync.Mutex
+	stopped bool
+}
+
+// NewExecutor 创建批量任务执行器
+func NewExecutor(service Service, serverService server.Service, scriptService script.Service, encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback) *Executor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Executor{
+		service:         service,
+		serverService:   serverService,
+		scriptService:   scriptService,
+		encryptor:       encryptor,
+		hostKeyCallback: hostKeyCallback,
+		maxParallel:     sshDomain.DefaultMaxParallel,
+		serverTimeout:   defaultServerTimeout,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// SetPublisher 设置实时事件发布器
+func (e *Executor) SetPublisher(publisher EventPublisher) {
+	e.publisher = publisher
+}
+
+// publish 发布实时事件（未设置发布器时忽略）
+func (e *Executor) publish(event *ExecutionEvent) {
+	if e.publisher == nil {
+		return
+	}
+	event.Timestamp = time.Now().UnixMilli()
+	e.publisher.Publish(event.TaskID, event)
+}
+
+// Submit 在后台执行任务，执行器已停止时返回 false
+func (e *Executor) Submit(task *BatchTask) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return false
+	}
+
+	e.running.Add(1)
+	go func() {
+		defer e.running.Done()
+		e.Execute(e.ctx, task)
+	}()
+	return true
+}
+
+// Stop 停止执行器，取消正在执行的任务并等待其退出（未执行的服务器记为失败）
+func (e *Executor) Stop() {
+	e.mu.Lock()
+	e.stopped = true
+	e.mu.Unlock()
+
+	e.cancel()
+	e.running.Wait()
+	log.Printf("[BatchTask] 执行器已停止")
+}
+
+// Execute 执行批量任务（阻塞直到所有服务器执行完毕）
+// 调用前任务应已被置为 running 状态
+func (e *Executor) Execute(ctx context.Context, task *BatchTask) {
+	log.Printf("[BatchTask] 开始执行: taskID=%s, servers=%d, mode=%s", task.ID, len(task.ServerIDs), task.ExecutionMode)
+
+	// 为每台目标服务器预先创建 pending 结果记录，便于查看尚未执行的主机
+	results := make([]*BatchTaskResult, 0, len(task.ServerIDs))
+	for _, serverID := range task.ServerIDs {
+		result := &BatchTaskResult{
+			TaskID:   task.ID,
+			ServerID: serverID,
+			Status:   ResultStatusPending,
+		}
+		e.saveResult(result)
+		results = append(results, result)
+	}
+
+	command, err := e.resolveCommand(task)
+	if err != nil {
+		log.Printf("[BatchTask] 解析任务内容失败: taskID=%s, error=%v", task.ID, err)
+		now := time.Now()
+		for _, result := range results {
+			result.Status = ResultStatusFailed
+			result.ErrorMessage = err.Error()
+			result.CompletedAt = &now
+			e.saveResult(result)
+		}
+		if err := e.service.UpdateTaskProgress(task.ID, 0, len(results)); err != nil {
+			log.Printf("[BatchTask] 更新进度失败: taskID=%s, error=%v", task.ID, err)
+		}
+		if err := e.service.CompleteBatchTask(task.ID, "failed"); err != nil {
+			log.Printf("[BatchTask] 更新完成状态失败: taskID=%s, error=%v", task.ID, err)
+		}
+		e.publish(&ExecutionEvent{
+			Type:        EventSummary,
+			TaskID:      task.ID.String(),
+			Status:      "failed",
+			Error:       err.Error(),
+			FailedCount: len(results),
+			Total:       len(results),
+		})
+		return
+	}
+
+	var (
+		mu           sync.Mutex
+		successCount int
+		failedCount  int
+	)
+
+	// 每台服务器执行结束后保存结果、累计计数并刷新进度
+	record := func(result *BatchTaskResult) {
+		e.saveResult(result)
+
+		mu.Lock()
+		if result.Status == ResultStatusSuccess {
+			successCount++
+		} else {
+			failedCount++
+		}
+		success, failed := successCount, failedCount
+		mu.Unlock()
+
+		if result.ErrorMessage != "" {
+			log.Printf("[BatchTask] 服务器执行失败: taskID=%s, serverID=%s, error=%s", task.ID, result.ServerID, result.ErrorMessage)
+		}
+
+		e.publish(&ExecutionEvent{
+			Type:         EventServerDone,
+			TaskID:       task.ID.String(),
+			ServerID:     result.ServerID,
+			ServerName:   result.ServerName,
+			ExitCode:     result.ExitCode,
+			Status:       result.Status,
+			Error:        result.ErrorMessage,
+			SuccessCount: success,
+			FailedCount:  failed,
+			Total:        len(results),
+		})
+
+		if err := e.service.UpdateTaskProgress(task.ID, success, failed); err != nil {
+			log.Printf("[BatchTask] 更新进度失败: taskID=%s, error=%v", task.ID, err)
+		}
+	}
+
+	parallel := e.maxParallel
+	if task.ExecutionMode == "sequential" {
+		parallel = 1
+	}
+	sshDomain.RunEach(len(results), parallel, func(i int) {
+		e.runOnServer(ctx, task.UserID, results[i], command)
+		record(results[i])
+	})
+
+	status := "completed"
+	if failedCount > 0 {
+		status = "failed"
+	}
+	if err := e.service.CompleteBatchTask(task.ID, status); err != nil {
+		log.Printf("[BatchTask] 更新完成状态失败: taskID=%s, error=%v", task.ID, err)
+	}
+
+	e.publish(&ExecutionEvent{
+		Type:         EventSummary,
+		TaskID:       task.ID.String(),
+		Status:       status,
+		SuccessCount: successCount,
+		FailedCount:  failedCount,
+		Total:        len(results),
+	})
+
+	log.Printf("[BatchTask] 执行完成: taskID=%s, status=%s, success=%d, failed=%d", task.ID, status, successCount, failedCount)
+}
+
+// saveResult 保存执行结果，失败时仅记录日志，不影响任务执行
+func (e *Executor) saveResult(result *BatchTaskResult) {
+	if err := e.service.SaveTaskResult(result); err != nil {
+		log.Printf("[BatchTask] 保存执行结果失败: taskID=%s, serverID=%s, error=%v", result.TaskID, result.ServerID, err)
+	}
+}
+
+// resolveCommand 根据任务类型得到要在远程执行的内容
+// 脚本任务按脚本语言选择解释器，参数使用声明的默认值
+func (e *Executor) resolveCommand(task *BatchTask) (*script.PreparedScript, error) {
+	switch task.TaskType {
+	case "command":
+		if task.Content == "" {
+			return nil, errors.New("command content is empty")
+		}
+		return &script.PreparedScript{Command: task.Content}, nil
+	case "script":
+		if task.ScriptID == nil {
+			return nil, errors.New("script_id is required for script task")
+		}
+		return e.scriptService.PrepareScript(task.UserID, *task.ScriptID, task.ScriptVersion)
+	default:
+		return nil, fmt.Errorf("task type %q is not supported by executor", task.TaskType)
+	}
+}
+
+// runOnServer 在单台服务器上执行命令，并将结果写回 result
+func (e *Executor) runOnServer(ctx context.Context, userID uuid.UUID, result *BatchTaskResult, command *script.PreparedScript) {
+	// stdout/stderr 分别保留
+	stdout := sshDomain.NewOutputBuffer(sshDomain.DefaultOutputLimit)
+	stderr := sshDomain.NewOutputBuffer(sshDomain.DefaultOutputLimit)
+
+	startedAt := time.Now()
+	result.Status = ResultStatusRunning
+	result.StartedAt = &startedAt
+
+	// 结束时统一填充输出、耗时和最终状态
+	defer func() {
+		completedAt := time.Now()
+		result.CompletedAt = &completedAt
+		result.Duration = completedAt.Sub(startedAt).Milliseconds()
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+		result.OutputTruncated = stdout.Truncated() || stderr.Truncated()
+		if result.ErrorMessage == "" && result.ExitCode != nil && *result.ExitCode == 0 {
+			result.Status = ResultStatusSuccess
+		} else {
+			result.Status = ResultStatusFailed
+		}
+	}()
+
+	// 执行器已停止时不再连接剩余的服务器
+	if ctx.Err() != nil {
+		result.ErrorMessage = sshDomain.ErrExecCanceled.Error()
+		return
+	}
+
+	sid, err := uuid.Parse(result.ServerID)
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("invalid server id: %v", err)
+		return
+	}
+
+	srv, err := e.serverService.GetByID(ctx, userID, sid)
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("server not found: %v", err)
+		return
+	}
+	result.ServerName = srv.Name
+	result.ServerHost = srv.Host
+	e.saveResult(result)
+
+	e.publish(&ExecutionEvent{
+		Type:       EventServerStart,
+		TaskID:     result.TaskID.String(),
+		ServerID:   result.ServerID,
+		ServerName: result.ServerName,
+	})
+
+	// 有发布器时同时将输出实时推送
+	var stdoutWriter, stderrWriter io.Writer = stdout, stderr
+	if e.publisher != nil {
+		taskID := result.TaskID.String()
+		stdoutStream := &streamWriter{publisher: e.publisher, taskID: taskID, serverID: result.ServerID, stream: "stdout"}
+		stderrStream := &streamWriter{publisher: e.publisher, taskID: taskID, serverID: result.ServerID, stream: "stderr"}
+		defer stdoutStream.Flush()
+		defer stderrStream.Flush()
+		stdoutWriter = io.MultiWriter(stdout, stdoutStream)
+		stderrWriter = io.MultiWriter(stderr, stderrStream)
+	}
+
+	exitCode, err := sshDomain.RunRemote(ctx, srv, e.encryptor, e.hostKeyCallback, &sshDomain.RemoteExec{
+		Command: command.Command,
+		Stdin:   command.Stdin(),
+		Stdout:  stdoutWriter,
+		Stderr:  stderrWriter,
+		Timeout: e.serverTimeout,
+	})
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return
+	}
+
+	result.ExitCode = &exitCode
+}
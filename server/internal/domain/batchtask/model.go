@@ -15,6 +15,7 @@ type BatchTask struct {
 	TaskType      string         `gorm:"type:varchar(20);not null" json:"task_type"` // command/script/file
 	Content       string         `gorm:"type:text" json:"content"`                   // 命令内容或文件路径
 	ScriptID      *uuid.UUID     `gorm:"type:uuid" json:"script_id,omitempty"`       // 关联的脚本ID
+	ScriptVersion *int           `json:"script_version,omitempty"`                   // 固定的脚本版本（为空时使用最新版本）
 	ServerIDs     []string       `gorm:"type:jsonb;serializer:json;not null" json:"server_ids"`
 	ExecutionMode string         `gorm:"type:varchar(20);default:'parallel'" json:"execution_mode"` // parallel/sequential
 	Status        string         `gorm:"type:varchar(20);default:'pending'" json:"status"`          // pending/running/completed/failed
@@ -41,12 +42,54 @@ func (b *BatchTask) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// 单台服务器执行结果状态
+const (
+	ResultStatusPending = "pending"
+	ResultStatusRunning = "running"
+	ResultStatusSuccess = "success"
+	ResultStatusFailed  = "failed"
+)
+
+// BatchTaskResult 批量任务在单台服务器上的执行结果
+type BatchTaskResult struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	TaskID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"task_id"`
+	ServerID        string     `gorm:"type:varchar(100);not null;index" json:"server_id"`
+	ServerName      string     `gorm:"type:varchar(100)" json:"server_name"`
+	ServerHost      string     `gorm:"type:varchar(255)" json:"server_host"`
+	Status          string     `gorm:"type:varchar(20);default:'pending';index" json:"status"` // pending/running/success/failed
+	ExitCode        *int       `json:"exit_code,omitempty"`
+	Stdout          string     `gorm:"type:text" json:"stdout"`           // 标准输出摘录（超出上限部分被截断）
+	Stderr          string     `gorm:"type:text" json:"stderr"`           // 标准错误摘录（超出上限部分被截断）
+	OutputTruncated bool       `gorm:"default:false" json:"output_truncated"`
+	ErrorMessage    string     `gorm:"type:text" json:"error_message,omitempty"` // 连接失败、超时等非退出码错误
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	Duration        int64      `json:"duration"` // 执行耗时（毫秒）
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (BatchTaskResult) TableName() string {
+	return "batch_task_results"
+}
+
+// BeforeCreate GORM 钩子：创建前自动生成 UUID
+func (r *BatchTaskResult) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
 // CreateBatchTaskRequest 创建批量任务请求
 type CreateBatchTaskRequest struct {
 	TaskName      string    `json:"task_name" binding:"required"`
 	TaskType      string    `json:"task_type" binding:"required,oneof=command script file"`
 	Content       string    `json:"content"`
 	ScriptID      *string   `json:"script_id,omitempty"`
+	ScriptVersion *int      `json:"script_version,omitempty"` // 固定脚本版本，省略时始终使用最新版本
 	ServerIDs     []string  `json:"server_ids" binding:"required,min=1"`
 	ExecutionMode string    `json:"execution_mode"`
 }
@@ -55,6 +98,7 @@ type CreateBatchTaskRequest struct {
 type UpdateBatchTaskRequest struct {
 	TaskName      string   `json:"task_name,omitempty"`
 	Content       string   `json:"content,omitempty"`
+	ScriptVersion *int     `json:"script_version,omitempty"` // 传 0 取消固定，改为使用最新版本
 	ServerIDs     []string `json:"server_ids,omitempty"`
 	ExecutionMode string   `json:"execution_mode,omitempty"`
 }
@@ -76,6 +120,22 @@ type ListBatchTasksResponse struct {
 	TotalPages int         `json:"total_pages"`
 }
 
+// ListBatchTaskResultsRequest 执行结果列表查询请求
+type ListBatchTaskResultsRequest struct {
+	Page   int    `form:"page" json:"page"`
+	Limit  int    `form:"limit" json:"limit"`
+	Status string `form:"status" json:"status"` // 状态筛选：pending/running/success/failed
+}
+
+// ListBatchTaskResultsResponse 执行结果列表响应
+type ListBatchTaskResultsResponse struct {
+	Data       []BatchTaskResult `json:"data"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+}
+
 // BatchTaskStatistics 批量任务统计
 type BatchTaskStatistics struct {
 	TotalTasks     int64          `json:"total_tasks"`
@@ -1,6 +1,8 @@
 package batchtask
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -14,7 +16,13 @@ type Repository interface {
 	List(userID uuid.UUID, req *ListBatchTasksRequest) ([]BatchTask, int64, error)
 	GetStatistics(userID uuid.UUID) (*BatchTaskStatistics, error)
 	UpdateStatus(id uuid.UUID, status string) error
+	MarkRunning(id uuid.UUID, startedAt time.Time) (bool, error)
 	UpdateProgress(id uuid.UUID, successCount, failedCount int) error
+	FailRunning(message string, completedAt time.Time) (int64, error)
+
+	// 执行结果
+	SaveResult(result *BatchTaskResult) error
+	ListResults(taskID uuid.UUID, req *ListBatchTaskResultsRequest) ([]BatchTaskResult, int64, error)
 }
 
 type repository struct {
@@ -136,6 +144,47 @@ func (r *repository) UpdateStatus(id uuid.UUID, status string) error {
 		Error
 }
 
+// MarkRunning 将待执行的任务标记为运行中，任务已不是 pending 状态时返回 false
+func (r *repository) MarkRunning(id uuid.UUID, startedAt time.Time) (bool, error) {
+	result := r.db.Model(&BatchTask{}).
+		Where("id = ? AND status = ?", id, "pending").
+		Updates(map[string]interface{}{
+			"status":     "running",
+			"started_at": startedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FailRunning 将所有运行中的任务及其未完成的执行结果标记为失败，返回受影响的任务数
+func (r *repository) FailRunning(message string, completedAt time.Time) (int64, error) {
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		running := tx.Model(&BatchTask{}).Select("id").Where("status = ?", "running")
+		if err := tx.Model(&BatchTaskResult{}).
+			Where("task_id IN (?) AND status IN ?", running, []string{ResultStatusPending, ResultStatusRunning}).
+			Updates(map[string]interface{}{
+				"status":        ResultStatusFailed,
+				"error_message": message,
+				"completed_at":  completedAt,
+			}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&BatchTask{}).
+			Where("status = ?", "running").
+			Updates(map[string]interface{}{
+				"status":       "failed",
+				"completed_at": completedAt,
+			})
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
+
 // UpdateProgress 更新任务进度
 func (r *repository) UpdateProgress(id uuid.UUID, successCount, failedCount int) error {
 	return r.db.Model(&BatchTask{}).
@@ -145,3 +194,47 @@ func (r *repository) UpdateProgress(id uuid.UUID, successCount, failedCount int)
 			"failed_count":  failedCount,
 		}).Error
 }
+
+// SaveResult 保存单台服务器执行结果（不存在则创建）
+func (r *repository) SaveResult(result *BatchTaskResult) error {
+	return r.db.Save(result).Error
+}
+
+// ListResults 获取任务的执行结果列表
+func (r *repository) ListResults(taskID uuid.UUID, req *ListBatchTaskResultsRequest) ([]BatchTaskResult, int64, error) {
+	var results []BatchTaskResult
+	var total int64
+
+	query := r.db.Model(&BatchTaskResult{}).Where("task_id = ?", taskID)
+
+	// 状态筛选
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	// 统计总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+	offset := (req.Page - 1) * req.Limit
+
+	// 查询数据
+	err := query.Order("created_at ASC").
+		Offset(offset).
+		Limit(req.Limit).
+		Find(&results).Error
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
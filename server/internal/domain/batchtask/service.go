@@ -2,8 +2,11 @@ package batchtask
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
+	"github.com/easyssh/server/internal/domain/script"
 	"github.com/google/uuid"
 )
 
@@ -11,6 +14,7 @@ var (
 	ErrBatchTaskNotFound    = errors.New("batch task not found")
 	ErrInvalidBatchTaskData = errors.New("invalid batch task data")
 	ErrUnauthorized         = errors.New("unauthorized access to batch task")
+	ErrExecutorUnavailable  = errors.New("batch task executor is not available")
 )
 
 // Service 批量任务业务逻辑接口
@@ -24,10 +28,15 @@ type Service interface {
 	StartBatchTask(userID uuid.UUID, id uuid.UUID) error
 	UpdateTaskProgress(id uuid.UUID, successCount, failedCount int) error
 	CompleteBatchTask(id uuid.UUID, status string) error
+	ListBatchTaskResults(userID uuid.UUID, id uuid.UUID, req *ListBatchTaskResultsRequest) (*ListBatchTaskResultsResponse, error)
+	SaveTaskResult(result *BatchTaskResult) error
+	RecoverInterruptedTasks() error
 }
 
 type service struct {
-	repo Repository
+	repo          Repository
+	executor      *Executor      // 任务执行器（可选，未设置时仅更新状态）
+	scriptService script.Service // 用于校验固定的脚本版本（可选）
 }
 
 // NewService 创建批量任务服务实例
@@ -35,6 +44,16 @@ func NewService(repo Repository) Service {
 	return &service{repo: repo}
 }
 
+// SetScriptService 设置脚本服务（用于校验固定的脚本版本是否存在）
+func (s *service) SetScriptService(scriptService script.Service) {
+	s.scriptService = scriptService
+}
+
+// SetExecutor 设置任务执行器（执行器依赖 Service，需在创建后注入）
+func (s *service) SetExecutor(executor *Executor) {
+	s.executor = executor
+}
+
 // CreateBatchTask 创建批量任务
 func (s *service) CreateBatchTask(userID uuid.UUID, req *CreateBatchTaskRequest) (*BatchTask, error) {
 	// 验证必填字段
@@ -71,6 +90,19 @@ func (s *service) CreateBatchTask(userID uuid.UUID, req *CreateBatchTaskRequest)
 		scriptID = &sid
 	}
 
+	// 验证固定的脚本版本
+	if req.ScriptVersion != nil {
+		if scriptID == nil {
+			return nil, errors.New("script_version requires script_id")
+		}
+		if *req.ScriptVersion < 1 {
+			return nil, errors.New("invalid script_version")
+		}
+		if err := s.checkScriptVersion(userID, *scriptID, *req.ScriptVersion); err != nil {
+			return nil, err
+		}
+	}
+
 	// 构建批量任务
 	task := &BatchTask{
 		UserID:        userID,
@@ -78,6 +110,7 @@ func (s *service) CreateBatchTask(userID uuid.UUID, req *CreateBatchTaskRequest)
 		TaskType:      req.TaskType,
 		Content:       req.Content,
 		ScriptID:      scriptID,
+		ScriptVersion: req.ScriptVersion,
 		ServerIDs:     req.ServerIDs,
 		ExecutionMode: req.ExecutionMode,
 		Status:        "pending",
@@ -121,6 +154,22 @@ func (s *service) UpdateBatchTask(userID uuid.UUID, id uuid.UUID, req *UpdateBat
 		updates["content"] = req.Content
 	}
 
+	if req.ScriptVersion != nil {
+		switch {
+		case *req.ScriptVersion == 0:
+			updates["script_version"] = nil
+		case *req.ScriptVersion < 0:
+			return nil, errors.New("invalid script_version")
+		case existingTask.ScriptID == nil:
+			return nil, errors.New("script_version requires script_id")
+		default:
+			if err := s.checkScriptVersion(userID, *existingTask.ScriptID, *req.ScriptVersion); err != nil {
+				return nil, err
+			}
+			updates["script_version"] = *req.ScriptVersion
+		}
+	}
+
 	if req.ExecutionMode != "" {
 		validExecutionModes := map[string]bool{"parallel": true, "sequential": true}
 		if !validExecutionModes[req.ExecutionMode] {
@@ -235,14 +284,47 @@ func (s *service) StartBatchTask(userID uuid.UUID, id uuid.UUID) error {
 		return errors.New("task is not in pending status")
 	}
 
-	// 更新状态为运行中
+	// 没有执行器时不修改状态，否则任务会一直停留在 running
+	if s.executor == nil {
+		return ErrExecutorUnavailable
+	}
+
+	// 更新状态为运行中（条件更新，避免并发启动时重复执行）
 	now := time.Now()
-	updates := map[string]interface{}{
-		"status":     "running",
-		"started_at": now,
+	started, err := s.repo.MarkRunning(id, now)
+	if err != nil {
+		return err
+	}
+	if !started {
+		return errors.New("task is not in pending status")
 	}
 
-	return s.repo.Update(id, updates)
+	// 异步执行任务，进度和最终状态由执行器回写
+	task.Status = "running"
+	task.StartedAt = &now
+	if !s.executor.Submit(task) {
+		// 服务正在关闭，将任务恢复为待执行
+		if err := s.repo.UpdateStatus(id, "pending"); err != nil {
+			log.Printf("[BatchTask] 恢复任务状态失败: taskID=%s, error=%v", id, err)
+		}
+		return ErrExecutorUnavailable
+	}
+
+	return nil
+}
+
+// RecoverInterruptedTasks 将上次运行时未执行完的任务标记为失败（服务启动时调用）
+// 执行器随进程退出，残留的 running 任务不会再有进度，也无法再次启动或删除
+// 多副本部署时其他实例仍在执行的任务会被一并标记，其执行器结束时会写回真实的结果和状态
+func (s *service) RecoverInterruptedTasks() error {
+	count, err := s.repo.FailRunning("interrupted by server restart", time.Now())
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("[BatchTask] 已将 %d 个中断的任务标记为失败", count)
+	}
+	return nil
 }
 
 // UpdateTaskProgress 更新任务进度
@@ -278,3 +360,75 @@ func (s *service) CompleteBatchTask(id uuid.UUID, status string) error {
 
 	return s.repo.Update(id, updates)
 }
+
+// ListBatchTaskResults 获取批量任务的单机执行结果
+func (s *service) ListBatchTaskResults(userID uuid.UUID, id uuid.UUID, req *ListBatchTaskResultsRequest) (*ListBatchTaskResultsResponse, error) {
+	task, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrBatchTaskNotFound
+	}
+
+	// 验证所有权
+	if task.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	// 验证状态筛选
+	if req.Status != "" {
+		validStatuses := map[string]bool{
+			ResultStatusPending: true,
+			ResultStatusRunning: true,
+			ResultStatusSuccess: true,
+			ResultStatusFailed:  true,
+		}
+		if !validStatuses[req.Status] {
+			return nil, errors.New("invalid status, must be one of: pending, running, success, failed")
+		}
+	}
+
+	// 设置默认值
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	results, total, err := s.repo.ListResults(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算总页数
+	totalPages := int(total) / req.Limit
+	if int(total)%req.Limit > 0 {
+		totalPages++
+	}
+
+	return &ListBatchTaskResultsResponse{
+		Data:       results,
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// SaveTaskResult 保存单台服务器执行结果（由执行器调用）
+func (s *service) SaveTaskResult(result *BatchTaskResult) error {
+	return s.repo.SaveResult(result)
+}
+
+// checkScriptVersion 检查固定的脚本版本是否存在（未设置脚本服务时跳过）
+func (s *service) checkScriptVersion(userID, scriptID uuid.UUID, version int) error {
+	if s.scriptService == nil {
+		return nil
+	}
+	if _, err := s.scriptService.GetVersion(userID, scriptID, version); err != nil {
+		if errors.Is(err, script.ErrVersionNotFound) {
+			return fmt.Errorf("script_version %d does not exist", version)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package cmdguard
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// 输入解析状态
+const (
+	stateNormal = iota
+	stateEscape // 收到 ESC
+	stateCSI    // ESC [ 控制序列
+	stateOSC    // ESC ] 操作系统命令，以 BEL 或 ESC \ 结束
+	stateSS3    // ESC O 单字符功能键
+)
+
+// LineBuffer 根据终端输入重建当前命令行
+// 处理可打印字符、退格、Ctrl-U/Ctrl-W/Ctrl-C 和括号粘贴，忽略光标移动等控制序列
+// 历史命令、Tab 补全等由远端 shell 完成的编辑无法从输入中得知，重建结果只是尽力而为
+type LineBuffer struct {
+	line   []byte
+	state  int
+	params []byte // 当前 CSI 序列的参数
+	paste  bool   // 括号粘贴模式中，换行属于粘贴内容而不是回车
+}
+
+// Scan 处理输入直到遇到回车
+// 遇到回车时返回回车在 data 中的位置、重建的命令行和 true，并清空缓冲区；否则处理全部输入并返回 false
+func (b *LineBuffer) Scan(data []byte) (int, string, bool) {
+	for i, ch := range data {
+		switch b.state {
+		case stateEscape:
+			switch ch {
+			case '[':
+				b.state = stateCSI
+				b.params = b.params[:0]
+			case ']':
+				b.state = stateOSC
+			case 'O':
+				b.state = stateSS3
+			default:
+				// Alt+按键等
+				b.state = stateNormal
+			}
+			continue
+
+		case stateCSI:
+			if ch < 0x40 || ch > 0x7e {
+				b.params = append(b.params, ch)
+				continue
+			}
+			b.state = stateNormal
+			if ch == '~' {
+				switch string(b.params) {
+				case "200":
+					b.paste = true
+				case "201":
+					b.paste = false
+				}
+			}
+			continue
+
+		case stateOSC:
+			switch ch {
+			case 0x07:
+				b.state = stateNormal
+			case 0x1b:
+				b.state = stateEscape
+			}
+			continue
+
+		case stateSS3:
+			b.state = stateNormal
+			continue
+		}
+
+		switch ch {
+		case '\r', '\n':
+			if b.paste {
+				b.line = append(b.line, '\n')
+				continue
+			}
+			line := string(b.line)
+			b.Reset()
+			return i, line, true
+		case 0x1b:
+			b.state = stateEscape
+		case 0x7f, 0x08: // 退格
+			if len(b.line) > 0 {
+				_, size := utf8.DecodeLastRune(b.line)
+				b.line = b.line[:len(b.line)-size]
+			}
+		case 0x15, 0x03: // Ctrl-U 清空整行，Ctrl-C 放弃当前行
+			b.line = b.line[:0]
+		case 0x17: // Ctrl-W 删除前一个单词
+			trimmed := bytes.TrimRight(b.line, " ")
+			if idx := bytes.LastIndexByte(trimmed, ' '); idx >= 0 {
+				b.line = trimmed[:idx+1]
+			} else {
+				b.line = b.line[:0]
+			}
+		case '\t':
+			b.line = append(b.line, ' ')
+		default:
+			if ch >= 0x20 {
+				b.line = append(b.line, ch)
+			}
+		}
+	}
+	return len(data), "", false
+}
+
+// Reset 清空缓冲区和解析状态
+func (b *LineBuffer) Reset() {
+	b.line = b.line[:0]
+	b.state = stateNormal
+	b.paste = false
+}
+
+// 进入/退出备用屏幕的控制序列（vim、less、top 等全屏程序）
+var (
+	altScreenEnter = [][]byte{[]byte("\x1b[?1049h"), []byte("\x1b[?1047h"), []byte("\x1b[?47h")}
+	altScreenExit  = [][]byte{[]byte("\x1b[?1049l"), []byte("\x1b[?1047l"), []byte("\x1b[?47l")}
+)
+
+// TrackAltScreen 根据终端输出判断是否处于备用屏幕（全屏程序），输出可以被伪造，结果只能作为提示
+func TrackAltScreen(output []byte, current bool) bool {
+	enter, exit := -1, -1
+	for _, seq := range altScreenEnter {
+		if idx := bytes.LastIndex(output, seq); idx > enter {
+			enter = idx
+		}
+	}
+	for _, seq := range altScreenExit {
+		if idx := bytes.LastIndex(output, seq); idx > exit {
+			exit = idx
+		}
+	}
+
+	if enter < 0 && exit < 0 {
+		return current
+	}
+	return enter > exit
+}
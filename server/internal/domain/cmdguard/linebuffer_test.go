@@ -0,0 +1,175 @@
+package cmdguard
+
+import "testing"
+
+func TestLineBufferScan(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string // 依次输入，最后一段中包含回车
+		want   string
+		pos    int // 回车在最后一段输入中的位置
+	}{
+		{
+			name:   "plain command",
+			inputs: []string{"ls -la\r"},
+			want:   "ls -la",
+			pos:    6,
+		},
+		{
+			name:   "split across inputs",
+			inputs: []string{"rm -", "rf /tmp", "/x\r"},
+			want:   "rm -rf /tmp/x",
+			pos:    2,
+		},
+		{
+			name:   "backspace",
+			inputs: []string{"lsx\x7f -l\r"},
+			want:   "ls -l",
+			pos:    7,
+		},
+		{
+			name:   "ctrl-h backspace removes multibyte character",
+			inputs: []string{"echo 中\x08文\r"},
+			want:   "echo 文",
+			pos:    12,
+		},
+		{
+			name:   "backspace on empty line",
+			inputs: []string{"\x7f\x7fls\r"},
+			want:   "ls",
+			pos:    4,
+		},
+		{
+			name:   "ctrl-w removes previous word",
+			inputs: []string{"rm -rf /tmp\x17/\r"},
+			want:   "rm -rf /",
+			pos:    13,
+		},
+		{
+			name:   "ctrl-w ignores trailing spaces",
+			inputs: []string{"echo hello  \x17world\r"},
+			want:   "echo world",
+			pos:    18,
+		},
+		{
+			name:   "ctrl-w on single word clears line",
+			inputs: []string{"reboot\x17ls\r"},
+			want:   "ls",
+			pos:    9,
+		},
+		{
+			name:   "ctrl-u clears line",
+			inputs: []string{"rm -rf /\x15ls\r"},
+			want:   "ls",
+			pos:    11,
+		},
+		{
+			name:   "ctrl-c abandons line",
+			inputs: []string{"rm -rf /\x03", "pwd\r"},
+			want:   "pwd",
+			pos:    3,
+		},
+		{
+			name:   "bracketed paste keeps newlines",
+			inputs: []string{"\x1b[200~echo a\necho b\x1b[201~\r"},
+			want:   "echo a\necho b",
+			pos:    25,
+		},
+		{
+			name:   "bracketed paste split across inputs",
+			inputs: []string{"\x1b[20", "0~rm -rf\n", "/ \x1b[201~\r"},
+			want:   "rm -rf\n/ ",
+			pos:    8,
+		},
+		{
+			name:   "cursor keys are ignored",
+			inputs: []string{"ls\x1b[D\x1b[C\x1bOA -a\r"},
+			want:   "ls -a",
+			pos:    14,
+		},
+		{
+			name:   "alt key is ignored",
+			inputs: []string{"l\x1bbs\r"},
+			want:   "ls",
+			pos:    4,
+		},
+		{
+			name:   "osc sequence is ignored",
+			inputs: []string{"\x1b]11;?\x07ls\r"},
+			want:   "ls",
+			pos:    9,
+		},
+		{
+			name:   "tab becomes space",
+			inputs: []string{"ls\t-l\n"},
+			want:   "ls -l",
+			pos:    5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b LineBuffer
+			last := len(tt.inputs) - 1
+			for i, input := range tt.inputs[:last] {
+				if n, _, enter := b.Scan([]byte(input)); enter || n != len(input) {
+					t.Fatalf("Scan(inputs[%d]) = %d, %v, want %d, false", i, n, enter, len(input))
+				}
+			}
+
+			n, line, enter := b.Scan([]byte(tt.inputs[last]))
+			if !enter {
+				t.Fatalf("Scan() found no Enter")
+			}
+			if line != tt.want {
+				t.Errorf("Scan() line = %q, want %q", line, tt.want)
+			}
+			if n != tt.pos {
+				t.Errorf("Scan() position = %d, want %d", n, tt.pos)
+			}
+		})
+	}
+}
+
+func TestLineBufferScanResetsAfterEnter(t *testing.T) {
+	var b LineBuffer
+	data := []byte("ls\rpwd\r")
+
+	n, line, enter := b.Scan(data)
+	if !enter || line != "ls" || n != 2 {
+		t.Fatalf("first Scan() = %d, %q, %v, want 2, \"ls\", true", n, line, enter)
+	}
+
+	n, line, enter = b.Scan(data[n+1:])
+	if !enter || line != "pwd" || n != 3 {
+		t.Fatalf("second Scan() = %d, %q, %v, want 3, \"pwd\", true", n, line, enter)
+	}
+}
+
+func TestTrackAltScreen(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		current bool
+		want    bool
+	}{
+		{name: "plain output keeps state off", output: "hello", current: false, want: false},
+		{name: "plain output keeps state on", output: "hello", current: true, want: true},
+		{name: "enter 1049", output: "\x1b[?1049h\x1b[H", current: false, want: true},
+		{name: "enter 1047", output: "\x1b[?1047h", current: false, want: true},
+		{name: "enter 47", output: "\x1b[?47h", current: false, want: true},
+		{name: "exit 1049", output: "\x1b[?1049l$ ", current: true, want: false},
+		{name: "exit 47", output: "\x1b[?47l", current: true, want: false},
+		{name: "enter then exit in one chunk", output: "\x1b[?1049hvim\x1b[?1049l", current: false, want: false},
+		{name: "exit then enter in one chunk", output: "\x1b[?1049l\x1b[?1049h", current: true, want: true},
+		{name: "unrelated private mode", output: "\x1b[?25l\x1b[?2004h", current: false, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrackAltScreen([]byte(tt.output), tt.current); got != tt.want {
+				t.Errorf("TrackAltScreen(%q, %v) = %v, want %v", tt.output, tt.current, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package cmdguard
+
+import (
+	"time"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Action 命令匹配规则后的处理方式
+type Action string
+
+const (
+	ActionBlock   Action = "block"   // 阻止执行
+	ActionConfirm Action = "confirm" // 执行前需要用户在终端中确认
+	ActionAlert   Action = "alert"   // 允许执行，发送告警通知并记录审计日志
+)
+
+// severity 处理方式的严重程度，多条规则同时匹配时取最严重的
+func (a Action) severity() int {
+	switch a {
+	case ActionBlock:
+		return 3
+	case ActionConfirm:
+		return 2
+	case ActionAlert:
+		return 1
+	}
+	return 0
+}
+
+// Rule 危险命令规则（由管理员维护，对所有用户生效）
+type Rule struct {
+	ID           uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	Name         string         `gorm:"type:varchar(100);not null" json:"name"`
+	Pattern      string         `gorm:"type:text;not null" json:"pattern"` // 正则表达式（RE2 语法），匹配重建后的命令行
+	Action       Action         `gorm:"type:varchar(20);not null" json:"action"`
+	ServerGroups pq.StringArray `gorm:"type:text[]" json:"server_groups"` // 生效的服务器分组
+	ServerTags   pq.StringArray `gorm:"type:text[]" json:"server_tags"`   // 生效的服务器标签（分组和标签都为空时对所有服务器生效）
+	Enabled      bool           `gorm:"default:true" json:"enabled"`
+	Description  string         `gorm:"type:text" json:"description"`
+	CreatedBy    uuid.UUID      `gorm:"type:uuid" json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate GORM钩子：创建前生成UUID
+func (r *Rule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName 指定表名
+func (Rule) TableName() string {
+	return "command_rules"
+}
+
+// AppliesTo 判断规则是否对服务器生效：服务器属于任一分组或带有任一标签
+func (r *Rule) AppliesTo(srv *server.Server) bool {
+	if len(r.ServerGroups) == 0 && len(r.ServerTags) == 0 {
+		return true
+	}
+	for _, group := range r.ServerGroups {
+		if srv.Group == group {
+			return true
+		}
+	}
+	for _, tag := range r.ServerTags {
+		for _, serverTag := range srv.Tags {
+			if serverTag == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Match 命令匹配到的规则
+type Match struct {
+	RuleID   uuid.UUID `json:"rule_id"`
+	RuleName string    `json:"rule_name"`
+	Action   Action    `json:"action"`
+}
+
+// CreateRuleRequest 创建规则请求
+type CreateRuleRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	Pattern      string   `json:"pattern" binding:"required,max=1000"`
+	Action       Action   `json:"action" binding:"required,oneof=block confirm alert"`
+	ServerGroups []string `json:"server_groups"`
+	ServerTags   []string `json:"server_tags"`
+	Enabled      *bool    `json:"enabled"` // 默认启用
+	Description  string   `json:"description"`
+}
+
+// UpdateRuleRequest 更新规则请求
+type UpdateRuleRequest struct {
+	Name         *string   `json:"name" binding:"omitempty,max=100"`
+	Pattern      *string   `json:"pattern" binding:"omitempty,max=1000"`
+	Action       *Action   `json:"action" binding:"omitempty,oneof=block confirm alert"`
+	ServerGroups *[]string `json:"server_groups"`
+	ServerTags   *[]string `json:"server_tags"`
+	Enabled      *bool     `json:"enabled"`
+	Description  *string   `json:"description"`
+}
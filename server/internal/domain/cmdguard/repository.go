@@ -0,0 +1,63 @@
+package cmdguard
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Repository 危险命令规则数据访问接口
+type Repository interface {
+	Create(rule *Rule) error
+	Save(rule *Rule) error
+	Delete(id uuid.UUID) error
+	GetByID(id uuid.UUID) (*Rule, error)
+	List() ([]Rule, error)
+	ListEnabled() ([]Rule, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建规则仓储实例
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Create 创建规则
+func (r *repository) Create(rule *Rule) error {
+	return r.db.Create(rule).Error
+}
+
+// Save 保存规则
+func (r *repository) Save(rule *Rule) error {
+	return r.db.Save(rule).Error
+}
+
+// Delete 删除规则（软删除）
+func (r *repository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&Rule{}).Error
+}
+
+// GetByID 根据ID获取规则
+func (r *repository) GetByID(id uuid.UUID) (*Rule, error) {
+	var rule Rule
+	if err := r.db.Where("id = ?", id).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// List 获取所有规则
+func (r *repository) List() ([]Rule, error) {
+	var rules []Rule
+	err := r.db.Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+// ListEnabled 获取所有启用的规则
+func (r *repository) ListEnabled() ([]Rule, error) {
+	var rules []Rule
+	err := r.db.Where("enabled = ?", true).Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
@@ -0,0 +1,218 @@
+package cmdguard
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRuleNotFound   = errors.New("command rule not found")
+	ErrInvalidPattern = errors.New("invalid command pattern")
+)
+
+// rulesCacheTTL 已编译规则的缓存时间（多副本部署时感知其他实例上的规则变更）
+const rulesCacheTTL = time.Minute
+
+// Service 危险命令规则业务逻辑接口
+type Service interface {
+	Create(userID uuid.UUID, req *CreateRuleRequest) (*Rule, error)
+	Update(id uuid.UUID, req *UpdateRuleRequest) (*Rule, error)
+	Delete(id uuid.UUID) error
+	Get(id uuid.UUID) (*Rule, error)
+	List() ([]Rule, error)
+
+	// Check 检查命令是否匹配对服务器生效的规则，返回最严重的匹配（未匹配时返回 nil）
+	Check(srv *server.Server, command string) *Match
+}
+
+// compiledRule 编译后的规则
+type compiledRule struct {
+	rule    Rule
+	pattern *regexp.Regexp
+}
+
+type service struct {
+	repo Repository
+
+	mu       sync.RWMutex
+	rules    []compiledRule
+	loadedAt time.Time
+}
+
+// NewService 创建危险命令规则服务实例
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// compilePattern 编译规则正则表达式
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+	}
+	return re, nil
+}
+
+// invalidate 规则变更后清除缓存
+func (s *service) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// enabledRules 获取编译后的启用规则（带缓存）
+func (s *service) enabledRules() []compiledRule {
+	s.mu.RLock()
+	if time.Since(s.loadedAt) < rulesCacheTTL {
+		rules := s.rules
+		s.mu.RUnlock()
+		return rules
+	}
+	s.mu.RUnlock()
+
+	rules, err := s.repo.ListEnabled()
+	if err != nil {
+		// 读取失败时沿用上一次加载的规则
+		log.Printf("[CommandGuard] 加载命令规则失败: %v", err)
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.rules
+	}
+
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		re, err := compilePattern(rule.Pattern)
+		if err != nil {
+			log.Printf("[CommandGuard] 跳过无效规则: rule=%s, error=%v", rule.ID, err)
+			continue
+		}
+		compiled = append(compiled, compiledRule{rule: rule, pattern: re})
+	}
+
+	s.mu.Lock()
+	s.rules = compiled
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return compiled
+}
+
+// Check 检查命令是否匹配规则
+func (s *service) Check(srv *server.Server, command string) *Match {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil
+	}
+
+	var match *Match
+	for _, cr := range s.enabledRules() {
+		if match != nil && cr.rule.Action.severity() <= match.Action.severity() {
+			continue
+		}
+		if !cr.rule.AppliesTo(srv) || !cr.pattern.MatchString(command) {
+			continue
+		}
+		match = &Match{RuleID: cr.rule.ID, RuleName: cr.rule.Name, Action: cr.rule.Action}
+	}
+	return match
+}
+
+// Create 创建规则
+func (s *service) Create(userID uuid.UUID, req *CreateRuleRequest) (*Rule, error) {
+	if _, err := compilePattern(req.Pattern); err != nil {
+		return nil, err
+	}
+
+	rule := &Rule{
+		Name:         strings.TrimSpace(req.Name),
+		Pattern:      req.Pattern,
+		Action:       req.Action,
+		ServerGroups: req.ServerGroups,
+		ServerTags:   req.ServerTags,
+		Enabled:      true,
+		Description:  req.Description,
+		CreatedBy:    userID,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	if err := s.repo.Create(rule); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+// Update 更新规则
+func (s *service) Update(id uuid.UUID, req *UpdateRuleRequest) (*Rule, error) {
+	rule, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrRuleNotFound
+	}
+
+	if req.Name != nil {
+		rule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Pattern != nil {
+		if _, err := compilePattern(*req.Pattern); err != nil {
+			return nil, err
+		}
+		rule.Pattern = *req.Pattern
+	}
+	if req.Action != nil {
+		rule.Action = *req.Action
+	}
+	if req.ServerGroups != nil {
+		rule.ServerGroups = *req.ServerGroups
+	}
+	if req.ServerTags != nil {
+		rule.ServerTags = *req.ServerTags
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Description != nil {
+		rule.Description = *req.Description
+	}
+
+	if err := s.repo.Save(rule); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rule, nil
+}
+
+// Delete 删除规则
+func (s *service) Delete(id uuid.UUID) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return ErrRuleNotFound
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// Get 获取规则详情
+func (s *service) Get(id uuid.UUID) (*Rule, error) {
+	rule, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrRuleNotFound
+	}
+	return rule, nil
+}
+
+// List 获取所有规则
+func (s *service) List() ([]Rule, error) {
+	return s.repo.List()
+}
@@ -9,13 +9,13 @@ import (
 
 	"github.com/easyssh/server/internal/domain/server"
 	sshDomain "github.com/easyssh/server/internal/domain/ssh"
-	"github.com/easyssh/server/internal/pkg/crypto"
 	"github.com/google/uuid"
 )
 
 // PooledConnection 池化的监控连接
 type PooledConnection struct {
-	Client       *sshDomain.Client // SSH 客户端
+	Client       *sshDomain.Client // SSH 客户端（由连接管理器共享）
+	lease        *sshDomain.Lease  // 连接管理器的租用，引用计数归零时释放
 	RefCount     int               // 引用计数（多少个监控 WebSocket 在使用）
 	ServerID     string            // 服务器 ID
 	UserID       string            // 用户 ID
@@ -44,6 +44,13 @@ func (pc *PooledConnection) DecRef() int {
 	return pc.RefCount
 }
 
+// release 释放连接管理器的租用（共享的 SSH 连接由连接管理器关闭）
+func (pc *PooledConnection) release() {
+	if pc.lease != nil {
+		pc.lease.Release()
+	}
+}
+
 // GetRefCount 获取当前引用计数
 func (pc *PooledConnection) GetRefCount() int {
 	pc.mu.RLock()
@@ -69,16 +76,16 @@ type ConnectionPool struct {
 	connections        map[string]*PooledConnection // key: userID:serverID
 	mu                 sync.RWMutex
 	serverService      server.Service // 服务器服务，用于获取服务器配置
-	encryptor          *crypto.Encryptor
+	connManager        *sshDomain.ConnectionManager // SSH 连接管理器（与终端、SFTP 共享连接）
 	connectionTimeout  time.Duration  // 连接超时时间
 }
 
 // NewConnectionPool 创建监控连接池
-func NewConnectionPool(serverService server.Service, encryptor *crypto.Encryptor) *ConnectionPool {
+func NewConnectionPool(serverService server.Service, connManager *sshDomain.ConnectionManager) *ConnectionPool {
 	return &ConnectionPool{
 		connections:       make(map[string]*PooledConnection),
 		serverService:     serverService,
-		encryptor:         encryptor,
+		connManager:       connManager,
 		connectionTimeout: 30 * time.Second, // 默认30秒超时
 	}
 }
@@ -112,7 +119,10 @@ func (p *ConnectionPool) GetOrCreate(userID, serverID string) (*PooledConnection
 		// 连接不健康，需要移除并重新创建
 		log.Printf("[ConnectionPool] 连接不健康，移除: key=%s", key)
 		p.mu.Lock()
-		delete(p.connections, key)
+		if p.connections[key] == conn {
+			delete(p.connections, key)
+			conn.release()
+		}
 		p.mu.Unlock()
 	} else {
 		p.mu.RUnlock()
@@ -128,6 +138,7 @@ func (p *ConnectionPool) GetOrCreate(userID, serverID string) (*PooledConnection
 			return conn, nil
 		}
 		delete(p.connections, key)
+		conn.release()
 	}
 	p.mu.Unlock()
 
@@ -160,22 +171,17 @@ func (p *ConnectionPool) GetOrCreate(userID, serverID string) (*PooledConnection
 	resultChan := make(chan result, 1)
 
 	go func() {
-		// 创建 SSH 客户端（连接池使用不安全模式以避免阻塞）
-		client, err := sshDomain.NewClient(srv, p.encryptor, nil)
+		// 从连接管理器获取 SSH 连接（用户已打开终端或 SFTP 时直接复用）
+		lease, err := p.connManager.Acquire(ctx, userID, srv, nil)
 		if err != nil {
-			resultChan <- result{nil, fmt.Errorf("failed to create ssh client: %w", err)}
-			return
-		}
-
-		// 连接到服务器
-		if err := client.Connect(srv.Host, srv.Port); err != nil {
 			resultChan <- result{nil, fmt.Errorf("failed to connect to server: %w", err)}
 			return
 		}
 
 		// 创建池化连接
 		pooledConn := &PooledConnection{
-			Client:     client,
+			Client:     lease.Client(),
+			lease:      lease,
 			RefCount:   1, // 初始引用计数为 1
 			ServerID:   serverID,
 			UserID:     userID,
@@ -191,6 +197,12 @@ func (p *ConnectionPool) GetOrCreate(userID, serverID string) (*PooledConnection
 	select {
 	case <-ctx.Done():
 		log.Printf("[ConnectionPool] 连接超时: key=%s, timeout=10s", key)
+		// 连接稍后建立成功时释放租用
+		go func() {
+			if res := <-resultChan; res.conn != nil {
+				res.conn.release()
+			}
+		}()
 		return nil, fmt.Errorf("connection timeout after 10s")
 	case res := <-resultChan:
 		if res.err != nil {
@@ -204,10 +216,8 @@ func (p *ConnectionPool) GetOrCreate(userID, serverID string) (*PooledConnection
 	// 再次检查是否已被其他goroutine创建
 	if conn, exists := p.connections[key]; exists && conn.IsHealthy() {
 		p.mu.Unlock()
-		// 关闭刚创建的连接
-		if newConn.Client != nil {
-			newConn.Client.Close()
-		}
+		// 释放刚获取的连接
+		newConn.release()
 		conn.IncRef()
 		log.Printf("[ConnectionPool] 复用其他goroutine创建的连接: key=%s, refCount=%d", key, conn.GetRefCount())
 		return conn, nil
@@ -238,9 +248,7 @@ func (p *ConnectionPool) Release(userID, serverID string) {
 	// 引用计数归零，立即关闭连接
 	if newRefCount == 0 {
 		log.Printf("[ConnectionPool] 引用计数归零，立即关闭连接: key=%s", key)
-		if conn.Client != nil {
-			conn.Client.Close()
-		}
+		conn.release()
 		delete(p.connections, key)
 	}
 }
@@ -257,10 +265,8 @@ func (p *ConnectionPool) ForceClose(userID, serverID string) error {
 		return fmt.Errorf("connection not found: %s", key)
 	}
 
-	// 关闭 SSH 连接
-	if conn.Client != nil {
-		conn.Client.Close()
-	}
+	// 释放 SSH 连接
+	conn.release()
 
 	delete(p.connections, key)
 	log.Printf("[ConnectionPool] 强制关闭连接: key=%s", key)
@@ -323,9 +329,7 @@ func (p *ConnectionPool) Close() {
 	// 关闭所有连接
 	for key, conn := range p.connections {
 		log.Printf("[ConnectionPool] 关闭连接: key=%s", key)
-		if conn.Client != nil {
-			conn.Client.Close()
-		}
+		conn.release()
 	}
 
 	// 清空连接池
@@ -9,7 +9,6 @@ import (
 
 	"github.com/easyssh/server/internal/domain/server"
 	sshDomain "github.com/easyssh/server/internal/domain/ssh"
-	"github.com/easyssh/server/internal/pkg/crypto"
 	"github.com/google/uuid"
 )
 
@@ -37,14 +36,14 @@ type Service interface {
 // service 监控服务实现
 type service struct {
 	serverService server.Service
-	encryptor     *crypto.Encryptor
+	connManager   *sshDomain.ConnectionManager
 }
 
-// NewService 创建监控服务
-func NewService(serverService server.Service, encryptor *crypto.Encryptor) Service {
+// NewService 创建监控服务（SSH 连接由连接管理器共享，并按服务器配置校验主机密钥）
+func NewService(serverService server.Service, connManager *sshDomain.ConnectionManager) Service {
 	return &service{
 		serverService: serverService,
-		encryptor:     encryptor,
+		connManager:   connManager,
 	}
 }
 
@@ -74,20 +73,22 @@ func (s *service) executeSSHCommand(ctx context.Context, serverID, command strin
 		return "", fmt.Errorf("failed to get server: %w", err)
 	}
 
-	// 创建 SSH 客户端（监控命令使用不安全模式以避免阻塞）
-	sshClient, err := sshDomain.NewClient(srv, s.encryptor, nil)
+	// 获取 SSH 连接（复用同一用户和服务器的已有连接）
+	lease, err := s.connManager.Acquire(ctx, userID.String(), srv, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create SSH client: %w", err)
-	}
-
-	// 连接
-	if err := sshClient.Connect(srv.Host, srv.Port); err != nil {
 		return "", fmt.Errorf("failed to connect: %w", err)
 	}
-	defer sshClient.Close()
 
-	// 执行命令
-	output, err := sshClient.ExecuteCommand(command)
+	// 执行命令，共享连接已失效时重新建立一次
+	output, err := lease.Client().ExecuteCommand(command)
+	if err != nil && !lease.Client().IsConnected() {
+		lease.Discard()
+		if lease, err = s.connManager.Acquire(ctx, userID.String(), srv, nil); err != nil {
+			return "", fmt.Errorf("failed to connect: %w", err)
+		}
+		output, err = lease.Client().ExecuteCommand(command)
+	}
+	lease.Release()
 	if err != nil {
 		return "", fmt.Errorf("failed to execute command: %w", err)
 	}
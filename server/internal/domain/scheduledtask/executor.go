@@ -0,0 +1,192 @@
+package scheduledtask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/batchtask"
+	"github.com/easyssh/server/internal/domain/script"
+	"github.com/easyssh/server/internal/domain/server"
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultServerTimeout 单台服务器的最长执行时间
+const defaultServerTimeout = 30 * time.Minute
+
+// ServerResult 定时任务在单台服务器上的执行结果
+type ServerResult struct {
+	ServerID    string    `json:"server_id"`
+	ServerName  string    `json:"server_name,omitempty"`
+	ExitCode    *int      `json:"exit_code,omitempty"`
+	Output      string    `json:"output,omitempty"`
+	Truncated   bool      `json:"truncated"`
+	Error       string    `json:"error,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Success 是否执行成功（无错误且退出码为 0）
+func (r *ServerResult) Success() bool {
+	return r.Error == "" && r.ExitCode != nil && *r.ExitCode == 0
+}
+
+// Executor 定时任务执行器，负责解析任务目标并通过 SSH 执行
+type Executor struct {
+	serverService    server.Service
+	scriptService    script.Service
+	batchTaskService batchtask.Service
+	encryptor        *crypto.Encryptor
+	hostKeyCallback  ssh.HostKeyCallback
+	maxParallel      int
+	serverTimeout    time.Duration
+}
+
+// NewExecutor 创建定时任务执行器
+func NewExecutor(serverService server.Service, scriptService script.Service, batchTaskService batchtask.Service, encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback) *Executor {
+	return &Executor{
+		serverService:    serverService,
+		scriptService:    scriptService,
+		batchTaskService: batchTaskService,
+		encryptor:        encryptor,
+		hostKeyCallback:  hostKeyCallback,
+		maxParallel:      sshDomain.DefaultMaxParallel,
+		serverTimeout:    defaultServerTimeout,
+	}
+}
+
+// executionPlan 解析后的执行计划
+type executionPlan struct {
+	command    *script.PreparedScript
+	serverIDs  []string
+	sequential bool
+}
+
+// Execute 执行定时任务（阻塞直到所有服务器执行完毕）
+// 返回每台服务器的执行结果；任务本身无法执行（如脚本不存在）时返回 error
+func (e *Executor) Execute(ctx context.Context, task *ScheduledTask) ([]*ServerResult, error) {
+	plan, err := e.resolvePlan(task)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ServerResult, len(plan.serverIDs))
+	for i, serverID := range plan.serverIDs {
+		results[i] = &ServerResult{ServerID: serverID}
+	}
+
+	parallel := e.maxParallel
+	if plan.sequential {
+		parallel = 1
+	}
+	sshDomain.RunEach(len(results), parallel, func(i int) {
+		e.runOnServer(ctx, task.UserID, results[i], plan.command)
+	})
+
+	return results, nil
+}
+
+// resolvePlan 根据任务类型得到要执行的内容和目标服务器
+func (e *Executor) resolvePlan(task *ScheduledTask) (*executionPlan, error) {
+	plan := &executionPlan{serverIDs: task.ServerIDs}
+
+	switch task.TaskType {
+	case "command":
+		if task.Command == "" {
+			return nil, errors.New("command is empty")
+		}
+		plan.command = &script.PreparedScript{Command: task.Command}
+	case "script":
+		if task.ScriptID == nil {
+			return nil, errors.New("script_id is required for script task")
+		}
+		prepared, err := e.scriptService.PrepareScript(task.UserID, *task.ScriptID, task.ScriptVersion)
+		if err != nil {
+			return nil, err
+		}
+		plan.command = prepared
+	case "batch":
+		// 以批量任务为模板：使用其命令/脚本和执行模式，未指定服务器时沿用其服务器列表
+		if task.BatchTaskID == nil {
+			return nil, errors.New("batch_task_id is required for batch task")
+		}
+		bt, err := e.batchTaskService.GetBatchTask(task.UserID, *task.BatchTaskID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load batch task: %w", err)
+		}
+		switch bt.TaskType {
+		case "command":
+			plan.command = &script.PreparedScript{Command: bt.Content}
+		case "script":
+			if bt.ScriptID == nil {
+				return nil, errors.New("batch task has no script_id")
+			}
+			prepared, err := e.scriptService.PrepareScript(task.UserID, *bt.ScriptID, bt.ScriptVersion)
+			if err != nil {
+				return nil, err
+			}
+			plan.command = prepared
+		default:
+			return nil, fmt.Errorf("batch task type %q is not supported", bt.TaskType)
+		}
+		if len(plan.serverIDs) == 0 {
+			plan.serverIDs = bt.ServerIDs
+		}
+		plan.sequential = bt.ExecutionMode == "sequential"
+	default:
+		return nil, fmt.Errorf("task type %q is not supported", task.TaskType)
+	}
+
+	if strings.TrimSpace(plan.command.Command) == "" {
+		return nil, errors.New("nothing to execute")
+	}
+	if len(plan.serverIDs) == 0 {
+		return nil, errors.New("no target servers")
+	}
+	return plan, nil
+}
+
+// runOnServer 在单台服务器上执行命令，并将结果写回 result
+func (e *Executor) runOnServer(ctx context.Context, userID uuid.UUID, result *ServerResult, command *script.PreparedScript) {
+	// stdout/stderr 合并保留
+	output := sshDomain.NewOutputBuffer(sshDomain.DefaultOutputLimit)
+	result.StartedAt = time.Now()
+
+	defer func() {
+		result.CompletedAt = time.Now()
+		result.Output = output.String()
+		result.Truncated = output.Truncated()
+	}()
+
+	sid, err := uuid.Parse(result.ServerID)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid server id: %v", err)
+		return
+	}
+
+	srv, err := e.serverService.GetByID(ctx, userID, sid)
+	if err != nil {
+		result.Error = fmt.Sprintf("server not found: %v", err)
+		return
+	}
+	result.ServerName = srv.Name
+
+	exitCode, err := sshDomain.RunRemote(ctx, srv, e.encryptor, e.hostKeyCallback, &sshDomain.RemoteExec{
+		Command: command.Command,
+		Stdin:   command.Stdin(),
+		Stdout:  output,
+		Stderr:  output,
+		Timeout: e.serverTimeout,
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+
+	result.ExitCode = &exitCode
+}
@@ -0,0 +1,115 @@
+package scheduledtask
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// lockKeyPrefix 单次触发锁的 Redis 键前缀
+	lockKeyPrefix = "scheduledtask:lock:"
+	// lockTTL 锁保留时间，需大于各副本间的时钟偏差；到期前同一次触发不会被重复执行
+	lockTTL = 10 * time.Minute
+
+	// runningKeyPrefix 任务运行中标记的 Redis 键前缀（用于重叠策略）
+	runningKeyPrefix = "scheduledtask:running:"
+	// runningLockTTL 运行中标记的有效期，执行期间定期续期；实例崩溃后自动失效
+	runningLockTTL = 5 * time.Minute
+	// runningLockRefresh 运行中标记的续期间隔
+	runningLockRefresh = time.Minute
+)
+
+// Locker 分布式锁存储（多副本部署时保证每次触发在集群内只执行一次）
+// cache.RedisClient 满足该接口；未设置时使用仅在本进程内生效的 memoryLocker
+// 续期和释放只作用于自己持有的锁（值等于持有者标识），避免锁过期被其他副本抢占后误操作
+type Locker interface {
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error)
+	ExpireIfValue(ctx context.Context, key, value string, expiration time.Duration) (bool, error)
+	DeleteIfValue(ctx context.Context, key, value string) (bool, error)
+}
+
+// memoryLocker 进程内锁存储（单实例部署时使用）
+type memoryLocker struct {
+	mu   sync.Mutex
+	keys map[string]memoryLock
+}
+
+// memoryLock 进程内锁
+type memoryLock struct {
+	value     string
+	expiresAt time.Time
+}
+
+func newMemoryLocker() *memoryLocker {
+	return &memoryLocker{keys: make(map[string]memoryLock)}
+}
+
+// SetNX 仅当键不存在（或已过期）时设置
+func (l *memoryLocker) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for k, lock := range l.keys {
+		if now.After(lock.expiresAt) {
+			delete(l.keys, k)
+		}
+	}
+
+	if _, exists := l.keys[key]; exists {
+		return false, nil
+	}
+	l.keys[key] = memoryLock{value: fmt.Sprint(value), expiresAt: now.Add(expiration)}
+	return true, nil
+}
+
+// ExpireIfValue 仅当键的值等于 value 时重新设置过期时间
+func (l *memoryLocker) ExpireIfValue(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, exists := l.keys[key]
+	if !exists || lock.value != value || time.Now().After(lock.expiresAt) {
+		return false, nil
+	}
+	lock.expiresAt = time.Now().Add(expiration)
+	l.keys[key] = lock
+	return true, nil
+}
+
+// DeleteIfValue 仅当键的值等于 value 时删除
+func (l *memoryLocker) DeleteIfValue(ctx context.Context, key, value string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, exists := l.keys[key]
+	if !exists || lock.value != value {
+		return false, nil
+	}
+	delete(l.keys, key)
+	return true, nil
+}
+
+// defaultInstanceID 生成当前实例标识（主机名:进程号），用于记录锁持有者
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// occurrenceLockKey 生成某任务某次计划触发的锁键
+func occurrenceLockKey(taskID uuid.UUID, scheduledAt time.Time) string {
+	return fmt.Sprintf("%s%s:%d", lockKeyPrefix, taskID, scheduledAt.Unix())
+}
+
+// runningLockKey 生成任务运行中标记的锁键
+func runningLockKey(taskID uuid.UUID) string {
+	return runningKeyPrefix + taskID.String()
+}
@@ -7,28 +7,46 @@ import (
 	"gorm.io/gorm"
 )
 
+// 错过触发的处理策略（服务停机期间错过的 Cron 触发）
+const (
+	MissedRunSkip = "skip"     // 跳过，等待下一次触发
+	MissedRunOnce = "run_once" // 启动后补执行一次
+	MissedRunAll  = "run_all"  // 启动后按计划时间逐次补执行
+)
+
+// 重叠执行策略（上一次执行尚未结束时又到达新的触发）
+const (
+	OverlapAllow = "allow" // 允许并发执行
+	OverlapSkip  = "skip"  // 跳过本次触发
+	OverlapQueue = "queue" // 排队，等上一次结束后再执行
+)
+
 // ScheduledTask 定时任务模型
 type ScheduledTask struct {
-	ID             uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	UserID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	TaskName       string         `gorm:"type:varchar(100);not null" json:"task_name"`
-	TaskType       string         `gorm:"type:varchar(20);not null" json:"task_type"` // command/script/batch
-	ScriptID       *uuid.UUID     `gorm:"type:uuid" json:"script_id,omitempty"`
-	BatchTaskID    *uuid.UUID     `gorm:"type:uuid" json:"batch_task_id,omitempty"`
-	Command        string         `gorm:"type:text" json:"command,omitempty"`
-	ServerIDs      []string       `gorm:"type:jsonb;serializer:json" json:"server_ids"`
-	CronExpression string         `gorm:"type:varchar(100);not null" json:"cron_expression"`
-	Timezone       string         `gorm:"type:varchar(50);default:'UTC'" json:"timezone"`
-	Enabled        bool           `gorm:"default:true" json:"enabled"`
-	LastRunAt      *time.Time     `json:"last_run_at,omitempty"`
-	NextRunAt      *time.Time     `json:"next_run_at,omitempty"`
-	RunCount       int            `gorm:"default:0" json:"run_count"`
-	FailureCount   int            `gorm:"default:0" json:"failure_count"`
-	LastStatus     string         `gorm:"type:varchar(20)" json:"last_status,omitempty"` // success/failed
-	Description    string         `gorm:"type:text" json:"description"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	UserID           uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	TaskName         string         `gorm:"type:varchar(100);not null" json:"task_name"`
+	TaskType         string         `gorm:"type:varchar(20);not null" json:"task_type"` // command/script/batch
+	ScriptID         *uuid.UUID     `gorm:"type:uuid" json:"script_id,omitempty"`
+	ScriptVersion    *int           `json:"script_version,omitempty"` // 固定的脚本版本（为空时使用最新版本）
+	BatchTaskID      *uuid.UUID     `gorm:"type:uuid" json:"batch_task_id,omitempty"`
+	Command          string         `gorm:"type:text" json:"command,omitempty"`
+	ServerIDs        []string       `gorm:"type:jsonb;serializer:json" json:"server_ids"`
+	CronExpression   string         `gorm:"type:varchar(100);not null" json:"cron_expression"`
+	Timezone         string         `gorm:"type:varchar(50);default:'UTC'" json:"timezone"`
+	Enabled          bool           `gorm:"default:true" json:"enabled"`
+	LastRunAt        *time.Time     `json:"last_run_at,omitempty"`
+	NextRunAt        *time.Time     `json:"next_run_at,omitempty"`
+	RunCount         int            `gorm:"default:0" json:"run_count"`
+	FailureCount     int            `gorm:"default:0" json:"failure_count"`
+	LastStatus       string         `gorm:"type:varchar(20)" json:"last_status,omitempty"`            // success/failed
+	HistoryRetention int            `gorm:"default:50" json:"history_retention"`                      // 保留的运行记录条数
+	MissedRunPolicy  string         `gorm:"type:varchar(20);default:'skip'" json:"missed_run_policy"` // 停机期间错过的触发：skip/run_once/run_all
+	OverlapPolicy    string         `gorm:"type:varchar(20);default:'allow'" json:"overlap_policy"`   // 上次未结束时的新触发：allow/skip/queue
+	Description      string         `gorm:"type:text" json:"description"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // BeforeCreate GORM钩子：创建前生成UUID
@@ -46,27 +64,35 @@ func (ScheduledTask) TableName() string {
 
 // CreateScheduledTaskRequest 创建定时任务请求
 type CreateScheduledTaskRequest struct {
-	TaskName       string    `json:"task_name" binding:"required"`
-	TaskType       string    `json:"task_type" binding:"required,oneof=command script batch"`
-	ScriptID       *string   `json:"script_id,omitempty"`
-	BatchTaskID    *string   `json:"batch_task_id,omitempty"`
-	Command        string    `json:"command,omitempty"`
-	ServerIDs      []string  `json:"server_ids,omitempty"`
-	CronExpression string    `json:"cron_expression" binding:"required"`
-	Timezone       string    `json:"timezone,omitempty"`
-	Enabled        *bool     `json:"enabled,omitempty"`
-	Description    string    `json:"description,omitempty"`
+	TaskName         string   `json:"task_name" binding:"required"`
+	TaskType         string   `json:"task_type" binding:"required,oneof=command script batch"`
+	ScriptID         *string  `json:"script_id,omitempty"`
+	ScriptVersion    *int     `json:"script_version,omitempty"` // 固定脚本版本，省略时始终使用最新版本
+	BatchTaskID      *string  `json:"batch_task_id,omitempty"`
+	Command          string   `json:"command,omitempty"`
+	ServerIDs        []string `json:"server_ids,omitempty"`
+	CronExpression   string   `json:"cron_expression" binding:"required"`
+	Timezone         string   `json:"timezone,omitempty"`
+	Enabled          *bool    `json:"enabled,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	HistoryRetention *int     `json:"history_retention,omitempty"`
+	MissedRunPolicy  string   `json:"missed_run_policy,omitempty"` // skip/run_once/run_all
+	OverlapPolicy    string   `json:"overlap_policy,omitempty"`    // allow/skip/queue
 }
 
 // UpdateScheduledTaskRequest 更新定时任务请求
 type UpdateScheduledTaskRequest struct {
-	TaskName       string   `json:"task_name,omitempty"`
-	Command        string   `json:"command,omitempty"`
-	ServerIDs      []string `json:"server_ids,omitempty"`
-	CronExpression string   `json:"cron_expression,omitempty"`
-	Timezone       string   `json:"timezone,omitempty"`
-	Enabled        *bool    `json:"enabled,omitempty"`
-	Description    string   `json:"description,omitempty"`
+	TaskName         string   `json:"task_name,omitempty"`
+	ScriptVersion    *int     `json:"script_version,omitempty"` // 传 0 取消固定，改为使用最新版本
+	Command          string   `json:"command,omitempty"`
+	ServerIDs        []string `json:"server_ids,omitempty"`
+	CronExpression   string   `json:"cron_expression,omitempty"`
+	Timezone         string   `json:"timezone,omitempty"`
+	Enabled          *bool    `json:"enabled,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	HistoryRetention *int     `json:"history_retention,omitempty"`
+	MissedRunPolicy  string   `json:"missed_run_policy,omitempty"` // skip/run_once/run_all
+	OverlapPolicy    string   `json:"overlap_policy,omitempty"`    // allow/skip/queue
 }
 
 // ListScheduledTasksRequest 定时任务列表查询请求
@@ -94,3 +120,59 @@ type ScheduledTaskStatistics struct {
 	TotalRuns     int64          `json:"total_runs"`
 	ByType        map[string]int `json:"by_type"`
 }
+
+// 运行记录状态
+const (
+	RunStatusRunning = "running"
+	RunStatusSuccess = "success"
+	RunStatusFailed  = "failed"
+	RunStatusSkipped = "skipped" // 因重叠策略被跳过
+)
+
+// ScheduledTaskRun 定时任务运行记录（每次触发一条）
+type ScheduledTaskRun struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	TaskID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"task_id"`
+	TriggerSource string         `gorm:"type:varchar(20);not null" json:"trigger_source"` // cron/manual
+	Status        string         `gorm:"type:varchar(20);not null" json:"status"`         // running/success/failed/skipped
+	LockOwner     string         `gorm:"type:varchar(255)" json:"lock_owner,omitempty"`   // 执行该次运行的实例（主机名:进程号）
+	ScheduledAt   time.Time      `json:"scheduled_at"`                                    // 计划触发时间
+	SuccessCount  int            `gorm:"default:0" json:"success_count"`
+	FailedCount   int            `gorm:"default:0" json:"failed_count"`
+	ErrorMessage  string         `gorm:"type:text" json:"error_message,omitempty"`
+	Results       []ServerResult `gorm:"type:jsonb;serializer:json" json:"results,omitempty"` // 每台服务器的退出码和输出（已截断）
+	StartedAt     time.Time      `gorm:"index" json:"started_at"`
+	CompletedAt   *time.Time     `json:"completed_at,omitempty"`
+	Duration      int64          `json:"duration"` // 毫秒
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ScheduledTaskRun) TableName() string {
+	return "scheduled_task_runs"
+}
+
+// BeforeCreate GORM钩子：创建前生成UUID
+func (r *ScheduledTaskRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ListScheduledTaskRunsRequest 运行记录列表查询请求
+type ListScheduledTaskRunsRequest struct {
+	Page          int    `form:"page" json:"page"`
+	Limit         int    `form:"limit" json:"limit"`
+	Status        string `form:"status" json:"status"`
+	TriggerSource string `form:"trigger_source" json:"trigger_source"`
+}
+
+// ListScheduledTaskRunsResponse 运行记录列表响应
+type ListScheduledTaskRunsResponse struct {
+	Data       []ScheduledTaskRun `json:"data"`
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+	TotalPages int                `json:"total_pages"`
+}
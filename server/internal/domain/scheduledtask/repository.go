@@ -15,6 +15,11 @@ type Repository interface {
 	GetStatistics(userID uuid.UUID) (*ScheduledTaskStatistics, error)
 	UpdateRunStatus(id uuid.UUID, status string, runCount, failureCount int) error
 	GetEnabledTasks() ([]ScheduledTask, error)
+	CreateRun(run *ScheduledTaskRun) error
+	SaveRun(run *ScheduledTaskRun) error
+	GetRun(taskID, runID uuid.UUID) (*ScheduledTaskRun, error)
+	ListRuns(taskID uuid.UUID, req *ListScheduledTaskRunsRequest) ([]ScheduledTaskRun, int64, error)
+	PruneRuns(taskID uuid.UUID, keep int) error
 }
 
 type repository struct {
@@ -139,3 +144,68 @@ func (r *repository) GetEnabledTasks() ([]ScheduledTask, error) {
 	err := r.db.Where("enabled = ?", true).Find(&tasks).Error
 	return tasks, err
 }
+
+// CreateRun 创建运行记录
+func (r *repository) CreateRun(run *ScheduledTaskRun) error {
+	return r.db.Create(run).Error
+}
+
+// SaveRun 保存运行记录
+func (r *repository) SaveRun(run *ScheduledTaskRun) error {
+	return r.db.Save(run).Error
+}
+
+// GetRun 获取任务的单条运行记录
+func (r *repository) GetRun(taskID, runID uuid.UUID) (*ScheduledTaskRun, error) {
+	var run ScheduledTaskRun
+	err := r.db.Where("id = ? AND task_id = ?", runID, taskID).First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns 获取任务的运行记录列表（不含输出，按开始时间倒序）
+func (r *repository) ListRuns(taskID uuid.UUID, req *ListScheduledTaskRunsRequest) ([]ScheduledTaskRun, int64, error) {
+	var runs []ScheduledTaskRun
+	var total int64
+
+	query := r.db.Model(&ScheduledTaskRun{}).Where("task_id = ?", taskID)
+
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	if req.TriggerSource != "" {
+		query = query.Where("trigger_source = ?", req.TriggerSource)
+	}
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页（列表不返回各服务器输出，详情接口再查看）
+	offset := (req.Page - 1) * req.Limit
+	if err := query.Omit("results").
+		Order("started_at DESC").
+		Offset(offset).
+		Limit(req.Limit).
+		Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return runs, total, nil
+}
+
+// PruneRuns 只保留最近 keep 条运行记录
+func (r *repository) PruneRuns(taskID uuid.UUID, keep int) error {
+	keepIDs := r.db.Model(&ScheduledTaskRun{}).
+		Select("id").
+		Where("task_id = ?", taskID).
+		Order("started_at DESC").
+		Limit(keep)
+
+	return r.db.Where("task_id = ? AND id NOT IN (?)", taskID, keepIDs).
+		Delete(&ScheduledTaskRun{}).Error
+}
@@ -0,0 +1,588 @@
+package scheduledtask
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// 触发来源
+const (
+	TriggerCron    = "cron"    // 按 Cron 表达式自动触发
+	TriggerManual  = "manual"  // 用户手动触发
+	TriggerCatchUp = "catchup" // 启动后补执行停机期间错过的触发
+)
+
+const (
+	// syncInterval 从数据库同步任务调度的间隔（多副本时感知其他实例上的任务变更）
+	syncInterval = time.Minute
+	// maxCatchUpRuns run_all 策略下最多补执行的次数（只保留最近的）
+	maxCatchUpRuns = 100
+	// maxQueuedRuns queue 策略下本实例每个任务最多排队等待的触发数，超出后跳过
+	maxQueuedRuns = 5
+	// queuePollInterval queue 策略下等待上一次执行结束的轮询间隔
+	queuePollInterval = 5 * time.Second
+)
+
+// cronParser 与服务层校验一致的 5 段 Cron 表达式解析器（支持 CRON_TZ= 前缀）
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// scheduledEntry 已注册的调度项
+type scheduledEntry struct {
+	entryID   cron.EntryID
+	signature string // 调度相关字段的签名，变化时需要重新注册
+}
+
+// Scheduler 进程内定时任务调度器
+// 启动时加载所有启用的任务，任务变更时由服务层调用 Reload 重新注册，并定期与数据库同步
+// 设置 Locker 后，Cron 触发会先抢占单次触发锁，保证多副本部署时只有一个实例执行
+type Scheduler struct {
+	repo       Repository
+	executor   *Executor
+	cron       *cron.Cron
+	entries    map[uuid.UUID]scheduledEntry
+	queued     map[uuid.UUID]int // queue 策略下各任务在本实例排队的触发数
+	mu         sync.Mutex
+	locker     Locker
+	instanceID string
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+	stopped bool // 由 mu 保护，停止后不再接受手动触发
+}
+
+// NewScheduler 创建定时任务调度器
+func NewScheduler(repo Repository, executor *Executor) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		repo:       repo,
+		executor:   executor,
+		cron:       cron.New(cron.WithParser(cronParser)),
+		entries:    make(map[uuid.UUID]scheduledEntry),
+		queued:     make(map[uuid.UUID]int),
+		locker:     newMemoryLocker(),
+		instanceID: defaultInstanceID(),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// SetLocker 设置分布式锁（多副本部署时必须设置）
+func (s *Scheduler) SetLocker(locker Locker) {
+	s.locker = locker
+}
+
+// Start 加载所有启用的任务并启动调度
+func (s *Scheduler) Start() error {
+	if err := s.sync(); err != nil {
+		return err
+	}
+
+	s.cron.Start()
+	go s.syncLoop()
+
+	// 处理停机期间错过的触发
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.catchUpMissedRuns()
+	}()
+
+	log.Printf("[Scheduler] 调度器已启动: instance=%s, tasks=%d", s.instanceID, len(s.entries))
+	return nil
+}
+
+// sync 与数据库中的启用任务对齐：注册新增/变更的任务，移除已禁用或删除的任务
+func (s *Scheduler) sync() error {
+	tasks, err := s.repo.GetEnabledTasks()
+	if err != nil {
+		return fmt.Errorf("failed to load scheduled tasks: %w", err)
+	}
+
+	enabled := make(map[uuid.UUID]bool, len(tasks))
+	for i := range tasks {
+		task := &tasks[i]
+		enabled[task.ID] = true
+
+		s.mu.Lock()
+		entry, ok := s.entries[task.ID]
+		s.mu.Unlock()
+		if ok && entry.signature == scheduleSignature(task) {
+			continue
+		}
+
+		s.unregister(task.ID)
+		if err := s.register(task); err != nil {
+			log.Printf("[Scheduler] 注册任务失败: taskID=%s, error=%v", task.ID, err)
+		}
+	}
+
+	s.mu.Lock()
+	var stale []uuid.UUID
+	for id := range s.entries {
+		if !enabled[id] {
+			stale = append(stale, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, id := range stale {
+		s.unregister(id)
+	}
+
+	return nil
+}
+
+// syncLoop 定期同步任务调度，直到调度器停止
+func (s *Scheduler) syncLoop() {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.sync(); err != nil {
+				log.Printf("[Scheduler] 同步任务失败: %v", err)
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop 停止调度，取消正在执行的任务并等待其退出
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	stopCtx := s.cron.Stop()
+	s.cancel()
+	<-stopCtx.Done()
+	s.running.Wait()
+	log.Printf("[Scheduler] 调度器已停止")
+}
+
+// Reload 重新加载单个任务的调度（创建、更新、启用/禁用、删除后调用）
+func (s *Scheduler) Reload(id uuid.UUID) {
+	s.unregister(id)
+
+	task, err := s.repo.GetByID(id)
+	if err != nil {
+		// 任务已删除
+		return
+	}
+	if !task.Enabled {
+		return
+	}
+
+	if err := s.register(task); err != nil {
+		log.Printf("[Scheduler] 注册任务失败: taskID=%s, error=%v", id, err)
+	}
+}
+
+// catchUpMissedRuns 按各任务的 missed_run_policy 处理停机期间错过的触发
+// 判断依据是数据库中的 next_run_at 早于当前时间（每次执行后都会刷新为下一次的计划时间）
+func (s *Scheduler) catchUpMissedRuns() {
+	tasks, err := s.repo.GetEnabledTasks()
+	if err != nil {
+		log.Printf("[Scheduler] 加载任务失败，无法处理错过的触发: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := range tasks {
+		task := tasks[i]
+		if task.NextRunAt == nil || !task.NextRunAt.Before(now) {
+			continue
+		}
+
+		occurrences, err := missedOccurrences(&task, now)
+		if err != nil || len(occurrences) == 0 {
+			continue
+		}
+
+		switch task.MissedRunPolicy {
+		case MissedRunOnce:
+			log.Printf("[Scheduler] 补执行错过的触发（一次）: taskID=%s, missed=%d", task.ID, len(occurrences))
+			wg.Add(1)
+			go func(id uuid.UUID, scheduledAt time.Time) {
+				defer wg.Done()
+				s.run(id, TriggerCatchUp, scheduledAt)
+			}(task.ID, occurrences[len(occurrences)-1])
+		case MissedRunAll:
+			log.Printf("[Scheduler] 补执行错过的触发（全部）: taskID=%s, missed=%d", task.ID, len(occurrences))
+			wg.Add(1)
+			go func(id uuid.UUID, occurrences []time.Time) {
+				defer wg.Done()
+				for _, scheduledAt := range occurrences {
+					if s.ctx.Err() != nil {
+						return
+					}
+					s.run(id, TriggerCatchUp, scheduledAt)
+				}
+			}(task.ID, occurrences)
+		default:
+			log.Printf("[Scheduler] 跳过错过的触发: taskID=%s, missed=%d", task.ID, len(occurrences))
+			if nextRunAt, err := nextRunTime(task.CronExpression, task.Timezone, now); err == nil {
+				if err := s.repo.Update(task.ID, map[string]interface{}{"next_run_at": nextRunAt}); err != nil {
+					log.Printf("[Scheduler] 更新下次运行时间失败: taskID=%s, error=%v", task.ID, err)
+				}
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// Trigger 立即异步执行一次任务（手动触发），调度器已停止时返回 ErrSchedulerStopped
+func (s *Scheduler) Trigger(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.run(id, TriggerManual, time.Now())
+	}()
+	return nil
+}
+
+// register 注册任务到 Cron
+func (s *Scheduler) register(task *ScheduledTask) error {
+	spec, err := scheduleSpec(task.CronExpression, task.Timezone)
+	if err != nil {
+		return err
+	}
+
+	id := task.ID
+	entryID, err := s.cron.AddFunc(spec, func() {
+		s.running.Add(1)
+		defer s.running.Done()
+		// Cron 在整分钟触发，取整得到本次计划触发时间，各副本据此生成相同的锁键
+		s.run(id, TriggerCron, time.Now().Round(time.Minute))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.entries[id] = scheduledEntry{entryID: entryID, signature: scheduleSignature(task)}
+	s.mu.Unlock()
+	return nil
+}
+
+// unregister 从 Cron 中移除任务
+func (s *Scheduler) unregister(id uuid.UUID) {
+	s.mu.Lock()
+	entry, ok := s.entries[id]
+	delete(s.entries, id)
+	s.mu.Unlock()
+
+	if ok {
+		s.cron.Remove(entry.entryID)
+	}
+}
+
+// run 执行一次任务并更新运行统计
+// scheduledAt 为计划触发时间（手动触发时为当前时间）
+func (s *Scheduler) run(id uuid.UUID, trigger string, scheduledAt time.Time) {
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	task, err := s.repo.GetByID(id)
+	if err != nil {
+		log.Printf("[Scheduler] 任务不存在，移除调度: taskID=%s", id)
+		s.unregister(id)
+		return
+	}
+	if trigger != TriggerManual && !task.Enabled {
+		s.unregister(id)
+		return
+	}
+
+	// Cron/补执行触发时抢占本次触发的锁，未抢到说明已由其他副本执行
+	// 手动触发只会到达处理请求的实例，无需加锁
+	if trigger != TriggerManual {
+		acquired, err := s.locker.SetNX(s.ctx, occurrenceLockKey(task.ID, scheduledAt), s.instanceID, lockTTL)
+		if err != nil {
+			log.Printf("[Scheduler] 获取执行锁失败，跳过本次触发: taskID=%s, error=%v", task.ID, err)
+			return
+		}
+		if !acquired {
+			log.Printf("[Scheduler] 本次触发已由其他实例执行: taskID=%s, scheduledAt=%s", task.ID, scheduledAt.Format(time.RFC3339))
+			return
+		}
+	}
+
+	// 按重叠策略占用运行槽位
+	release, acquired, err := s.acquireRunSlot(task)
+	if err != nil {
+		log.Printf("[Scheduler] 获取运行中标记失败: taskID=%s, error=%v", task.ID, err)
+		if s.ctx.Err() == nil {
+			s.recordUnstartedRun(task, trigger, scheduledAt, RunStatusFailed, fmt.Sprintf("failed to check running state: %v", err))
+		}
+		return
+	}
+	if !acquired {
+		if s.ctx.Err() == nil {
+			log.Printf("[Scheduler] 上一次执行尚未结束，跳过本次触发: taskID=%s, trigger=%s", task.ID, trigger)
+			s.recordUnstartedRun(task, trigger, scheduledAt, RunStatusSkipped, "previous run is still in progress")
+		}
+		return
+	}
+	defer release()
+
+	log.Printf("[Scheduler] 开始执行: taskID=%s, name=%s, trigger=%s", task.ID, task.TaskName, trigger)
+	startedAt := time.Now()
+
+	// 记录本次运行
+	run := &ScheduledTaskRun{
+		TaskID:        task.ID,
+		TriggerSource: trigger,
+		Status:        RunStatusRunning,
+		LockOwner:     s.instanceID,
+		ScheduledAt:   scheduledAt,
+		StartedAt:     startedAt,
+	}
+	if err := s.repo.CreateRun(run); err != nil {
+		log.Printf("[Scheduler] 创建运行记录失败: taskID=%s, error=%v", task.ID, err)
+	}
+
+	status := RunStatusSuccess
+	results, err := s.executor.Execute(s.ctx, task)
+	if err != nil {
+		status = RunStatusFailed
+		run.ErrorMessage = err.Error()
+		log.Printf("[Scheduler] 任务无法执行: taskID=%s, error=%v", task.ID, err)
+	}
+	for _, result := range results {
+		if result.Success() {
+			run.SuccessCount++
+		} else {
+			status = RunStatusFailed
+			run.FailedCount++
+			log.Printf("[Scheduler] 服务器执行失败: taskID=%s, serverID=%s, error=%s", task.ID, result.ServerID, result.Error)
+		}
+		run.Results = append(run.Results, *result)
+	}
+
+	completedAt := time.Now()
+	run.Status = status
+	run.CompletedAt = &completedAt
+	run.Duration = completedAt.Sub(startedAt).Milliseconds()
+	if err := s.repo.SaveRun(run); err != nil {
+		log.Printf("[Scheduler] 保存运行记录失败: taskID=%s, error=%v", task.ID, err)
+	}
+
+	updates := map[string]interface{}{
+		"last_run_at": startedAt,
+		"last_status": status,
+		"run_count":   gorm.Expr("run_count + ?", 1),
+	}
+	if status == RunStatusFailed {
+		updates["failure_count"] = gorm.Expr("failure_count + ?", 1)
+	}
+	if task.Enabled {
+		if nextRunAt, err := nextRunTime(task.CronExpression, task.Timezone, time.Now()); err == nil {
+			updates["next_run_at"] = nextRunAt
+		}
+	}
+	if err := s.repo.Update(task.ID, updates); err != nil {
+		log.Printf("[Scheduler] 更新运行状态失败: taskID=%s, error=%v", task.ID, err)
+	}
+
+	s.pruneRuns(task)
+
+	log.Printf("[Scheduler] 执行完成: taskID=%s, status=%s, duration=%s", task.ID, status, completedAt.Sub(startedAt))
+}
+
+// acquireRunSlot 按任务的重叠策略获取运行槽位
+// 运行中标记保存在 Locker 中，多副本部署时在集群范围内生效；返回的 release 用于执行结束后释放
+// 无法访问锁存储时返回 error，与"上一次执行尚未结束"区分
+func (s *Scheduler) acquireRunSlot(task *ScheduledTask) (func(), bool, error) {
+	if task.OverlapPolicy != OverlapSkip && task.OverlapPolicy != OverlapQueue {
+		return func() {}, true, nil
+	}
+
+	// 标记值在每次获取时唯一：同一实例上的并发执行（如手动触发与 Cron 同时到达）不会续期或释放彼此的标记
+	key := runningLockKey(task.ID)
+	token := s.instanceID + ":" + uuid.NewString()
+	acquired, err := s.locker.SetNX(s.ctx, key, token, runningLockTTL)
+	if err != nil {
+		return nil, false, err
+	}
+	if !acquired && task.OverlapPolicy == OverlapQueue {
+		s.mu.Lock()
+		if s.queued[task.ID] >= maxQueuedRuns {
+			s.mu.Unlock()
+			log.Printf("[Scheduler] 排队触发过多，跳过: taskID=%s", task.ID)
+			return nil, false, nil
+		}
+		s.queued[task.ID]++
+		s.mu.Unlock()
+
+		ticker := time.NewTicker(queuePollInterval)
+	wait:
+		for !acquired {
+			select {
+			case <-ticker.C:
+				acquired, err = s.locker.SetNX(s.ctx, key, token, runningLockTTL)
+				if err != nil {
+					break wait
+				}
+			case <-s.ctx.Done():
+				break wait
+			}
+		}
+		ticker.Stop()
+
+		s.mu.Lock()
+		s.queued[task.ID]--
+		if s.queued[task.ID] <= 0 {
+			delete(s.queued, task.ID)
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+
+	// 执行期间定期续期，实例崩溃时标记会自动过期
+	// 续期和释放只作用于本次获取的标记：标记过期后被其他执行抢占时不会误续期或误删除
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(runningLockRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				renewed, err := s.locker.ExpireIfValue(context.Background(), key, token, runningLockTTL)
+				if err != nil {
+					log.Printf("[Scheduler] 续期运行中标记失败: taskID=%s, error=%v", task.ID, err)
+				} else if !renewed {
+					log.Printf("[Scheduler] 运行中标记已失效或被其他执行持有: taskID=%s", task.ID)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		if _, err := s.locker.DeleteIfValue(context.Background(), key, token); err != nil {
+			log.Printf("[Scheduler] 释放运行中标记失败: taskID=%s, error=%v", task.ID, err)
+		}
+	}, true, nil
+}
+
+// recordUnstartedRun 记录未实际执行的触发（因重叠策略跳过，或无法获取运行中标记）
+func (s *Scheduler) recordUnstartedRun(task *ScheduledTask, trigger string, scheduledAt time.Time, status, message string) {
+	now := time.Now()
+	run := &ScheduledTaskRun{
+		TaskID:        task.ID,
+		TriggerSource: trigger,
+		Status:        status,
+		LockOwner:     s.instanceID,
+		ErrorMessage:  message,
+		ScheduledAt:   scheduledAt,
+		StartedAt:     now,
+		CompletedAt:   &now,
+	}
+	if err := s.repo.CreateRun(run); err != nil {
+		log.Printf("[Scheduler] 创建运行记录失败: taskID=%s, error=%v", task.ID, err)
+		return
+	}
+	if status == RunStatusFailed {
+		updates := map[string]interface{}{
+			"last_status":   status,
+			"failure_count": gorm.Expr("failure_count + ?", 1),
+		}
+		if err := s.repo.Update(task.ID, updates); err != nil {
+			log.Printf("[Scheduler] 更新运行状态失败: taskID=%s, error=%v", task.ID, err)
+		}
+	}
+	s.pruneRuns(task)
+}
+
+// pruneRuns 清理超出保留条数的运行记录
+func (s *Scheduler) pruneRuns(task *ScheduledTask) {
+	retention := task.HistoryRetention
+	if retention < 1 {
+		retention = defaultHistoryRetention
+	}
+	if err := s.repo.PruneRuns(task.ID, retention); err != nil {
+		log.Printf("[Scheduler] 清理运行记录失败: taskID=%s, error=%v", task.ID, err)
+	}
+}
+
+// scheduleSignature 调度相关字段的签名
+func scheduleSignature(task *ScheduledTask) string {
+	return task.CronExpression + "|" + task.Timezone
+}
+
+// scheduleSpec 生成带时区的 Cron 规格（无效时区回退到 UTC）
+func scheduleSpec(cronExpr, timezone string) (string, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		log.Printf("[Scheduler] 无效时区，使用 UTC: timezone=%s", timezone)
+		timezone = "UTC"
+	}
+
+	spec := fmt.Sprintf("CRON_TZ=%s %s", timezone, cronExpr)
+	if _, err := cronParser.Parse(spec); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCronExpression, err)
+	}
+	return spec, nil
+}
+
+// nextRunTime 计算 from 之后的下次运行时间
+func nextRunTime(cronExpr, timezone string, from time.Time) (time.Time, error) {
+	spec, err := scheduleSpec(cronExpr, timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(from), nil
+}
+
+// missedOccurrences 计算 next_run_at 到 now 之间错过的计划触发时间（最多保留最近 maxCatchUpRuns 次）
+func missedOccurrences(task *ScheduledTask, now time.Time) ([]time.Time, error) {
+	spec, err := scheduleSpec(task.CronExpression, task.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var occurrences []time.Time
+	for t := schedule.Next(task.NextRunAt.Add(-time.Second)); !t.After(now); t = schedule.Next(t) {
+		occurrences = append(occurrences, t)
+		if len(occurrences) > maxCatchUpRuns {
+			occurrences = occurrences[1:]
+		}
+	}
+	return occurrences, nil
+}
@@ -2,8 +2,10 @@ package scheduledtask
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/easyssh/server/internal/domain/script"
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 )
@@ -13,6 +15,19 @@ var (
 	ErrInvalidScheduledTaskData = errors.New("invalid scheduled task data")
 	ErrUnauthorized             = errors.New("unauthorized access to scheduled task")
 	ErrInvalidCronExpression    = errors.New("invalid cron expression")
+	ErrInvalidTimezone          = errors.New("invalid timezone")
+	ErrRunNotFound              = errors.New("scheduled task run not found")
+	ErrInvalidHistoryRetention  = errors.New("invalid history_retention, must be between 1 and 1000")
+	ErrInvalidMissedRunPolicy   = errors.New("invalid missed_run_policy, must be one of: skip, run_once, run_all")
+	ErrInvalidOverlapPolicy     = errors.New("invalid overlap_policy, must be one of: allow, skip, queue")
+	ErrSchedulerStopped         = errors.New("task scheduler is stopped")
+)
+
+const (
+	// defaultHistoryRetention 默认保留的运行记录条数
+	defaultHistoryRetention = 50
+	// maxHistoryRetention 允许保留的最大运行记录条数
+	maxHistoryRetention = 1000
 )
 
 // Service 定时任务业务逻辑接口
@@ -25,10 +40,14 @@ type Service interface {
 	GetStatistics(userID uuid.UUID) (*ScheduledTaskStatistics, error)
 	ToggleTask(userID uuid.UUID, id uuid.UUID, enabled bool) error
 	TriggerTask(userID uuid.UUID, id uuid.UUID) error
+	ListTaskRuns(userID uuid.UUID, id uuid.UUID, req *ListScheduledTaskRunsRequest) (*ListScheduledTaskRunsResponse, error)
+	GetTaskRun(userID uuid.UUID, id uuid.UUID, runID uuid.UUID) (*ScheduledTaskRun, error)
 }
 
 type service struct {
-	repo Repository
+	repo          Repository
+	scheduler     *Scheduler
+	scriptService script.Service // 用于校验固定的脚本版本（可选）
 }
 
 // NewService 创建定时任务服务实例
@@ -36,6 +55,53 @@ func NewService(repo Repository) Service {
 	return &service{repo: repo}
 }
 
+// SetScriptService 设置脚本服务（用于校验固定的脚本版本是否存在）
+func (s *service) SetScriptService(scriptService script.Service) {
+	s.scriptService = scriptService
+}
+
+// SetScheduler 设置调度器（任务变更时同步调度，手动触发时真正执行）
+func (s *service) SetScheduler(scheduler *Scheduler) {
+	s.scheduler = scheduler
+}
+
+// reloadSchedule 通知调度器重新加载任务（未设置调度器时忽略）
+func (s *service) reloadSchedule(id uuid.UUID) {
+	if s.scheduler != nil {
+		s.scheduler.Reload(id)
+	}
+}
+
+// validateHistoryRetention 验证运行记录保留条数
+func (s *service) validateHistoryRetention(retention int) error {
+	if retention < 1 || retention > maxHistoryRetention {
+		return ErrInvalidHistoryRetention
+	}
+	return nil
+}
+
+// validatePolicies 验证错过触发策略和重叠执行策略（空值表示不修改/使用默认值）
+func (s *service) validatePolicies(missedRunPolicy, overlapPolicy string) error {
+	validMissedRunPolicies := map[string]bool{MissedRunSkip: true, MissedRunOnce: true, MissedRunAll: true}
+	if missedRunPolicy != "" && !validMissedRunPolicies[missedRunPolicy] {
+		return ErrInvalidMissedRunPolicy
+	}
+
+	validOverlapPolicies := map[string]bool{OverlapAllow: true, OverlapSkip: true, OverlapQueue: true}
+	if overlapPolicy != "" && !validOverlapPolicies[overlapPolicy] {
+		return ErrInvalidOverlapPolicy
+	}
+	return nil
+}
+
+// validateTimezone 验证时区名称（IANA 格式，如 Asia/Shanghai）
+func (s *service) validateTimezone(timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return ErrInvalidTimezone
+	}
+	return nil
+}
+
 // validateCronExpression 验证Cron表达式
 func (s *service) validateCronExpression(expr string) error {
 	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
@@ -86,12 +152,35 @@ func (s *service) CreateScheduledTask(userID uuid.UUID, req *CreateScheduledTask
 	if timezone == "" {
 		timezone = "UTC"
 	}
+	if err := s.validateTimezone(timezone); err != nil {
+		return nil, err
+	}
 
 	enabled := true
 	if req.Enabled != nil {
 		enabled = *req.Enabled
 	}
 
+	historyRetention := defaultHistoryRetention
+	if req.HistoryRetention != nil {
+		if err := s.validateHistoryRetention(*req.HistoryRetention); err != nil {
+			return nil, err
+		}
+		historyRetention = *req.HistoryRetention
+	}
+
+	if err := s.validatePolicies(req.MissedRunPolicy, req.OverlapPolicy); err != nil {
+		return nil, err
+	}
+	missedRunPolicy := req.MissedRunPolicy
+	if missedRunPolicy == "" {
+		missedRunPolicy = MissedRunSkip
+	}
+	overlapPolicy := req.OverlapPolicy
+	if overlapPolicy == "" {
+		overlapPolicy = OverlapAllow
+	}
+
 	// 转换ID
 	var scriptID *uuid.UUID
 	if req.ScriptID != nil && *req.ScriptID != "" {
@@ -101,6 +190,17 @@ func (s *service) CreateScheduledTask(userID uuid.UUID, req *CreateScheduledTask
 		}
 		scriptID = &sid
 	}
+	if req.ScriptVersion != nil {
+		if scriptID == nil {
+			return nil, errors.New("script_version requires script_id")
+		}
+		if *req.ScriptVersion < 1 {
+			return nil, errors.New("invalid script_version")
+		}
+		if err := s.checkScriptVersion(userID, *scriptID, *req.ScriptVersion); err != nil {
+			return nil, err
+		}
+	}
 
 	var batchTaskID *uuid.UUID
 	if req.BatchTaskID != nil && *req.BatchTaskID != "" {
@@ -119,26 +219,32 @@ func (s *service) CreateScheduledTask(userID uuid.UUID, req *CreateScheduledTask
 
 	// 构建定时任务
 	task := &ScheduledTask{
-		UserID:         userID,
-		TaskName:       req.TaskName,
-		TaskType:       req.TaskType,
-		ScriptID:       scriptID,
-		BatchTaskID:    batchTaskID,
-		Command:        req.Command,
-		ServerIDs:      req.ServerIDs,
-		CronExpression: req.CronExpression,
-		Timezone:       timezone,
-		Enabled:        enabled,
-		NextRunAt:      nextRunAt,
-		RunCount:       0,
-		FailureCount:   0,
-		Description:    req.Description,
+		UserID:           userID,
+		TaskName:         req.TaskName,
+		TaskType:         req.TaskType,
+		ScriptID:         scriptID,
+		ScriptVersion:    req.ScriptVersion,
+		BatchTaskID:      batchTaskID,
+		Command:          req.Command,
+		ServerIDs:        req.ServerIDs,
+		CronExpression:   req.CronExpression,
+		Timezone:         timezone,
+		Enabled:          enabled,
+		NextRunAt:        nextRunAt,
+		RunCount:         0,
+		FailureCount:     0,
+		Description:      req.Description,
+		HistoryRetention: historyRetention,
+		MissedRunPolicy:  missedRunPolicy,
+		OverlapPolicy:    overlapPolicy,
 	}
 
 	if err := s.repo.Create(task); err != nil {
 		return nil, err
 	}
 
+	s.reloadSchedule(task.ID)
+
 	return task, nil
 }
 
@@ -155,6 +261,12 @@ func (s *service) UpdateScheduledTask(userID uuid.UUID, id uuid.UUID, req *Updat
 		return nil, ErrUnauthorized
 	}
 
+	if req.Timezone != "" {
+		if err := s.validateTimezone(req.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
 	// 构建更新字段
 	updates := make(map[string]interface{})
 
@@ -166,6 +278,22 @@ func (s *service) UpdateScheduledTask(userID uuid.UUID, id uuid.UUID, req *Updat
 		updates["command"] = req.Command
 	}
 
+	if req.ScriptVersion != nil {
+		switch {
+		case *req.ScriptVersion == 0:
+			updates["script_version"] = nil
+		case *req.ScriptVersion < 0:
+			return nil, errors.New("invalid script_version")
+		case existingTask.ScriptID == nil:
+			return nil, errors.New("script_version requires script_id")
+		default:
+			if err := s.checkScriptVersion(userID, *existingTask.ScriptID, *req.ScriptVersion); err != nil {
+				return nil, err
+			}
+			updates["script_version"] = *req.ScriptVersion
+		}
+	}
+
 	if len(req.ServerIDs) > 0 {
 		updates["server_ids"] = req.ServerIDs
 	}
@@ -191,6 +319,15 @@ func (s *service) UpdateScheduledTask(userID uuid.UUID, id uuid.UUID, req *Updat
 
 	if req.Timezone != "" {
 		updates["timezone"] = req.Timezone
+
+		// 仅修改时区时也需要重新计算下次运行时间
+		if req.CronExpression == "" {
+			nextRunAt, err := s.calculateNextRunTime(existingTask.CronExpression, req.Timezone)
+			if err != nil {
+				return nil, err
+			}
+			updates["next_run_at"] = nextRunAt
+		}
 	}
 
 	if req.Enabled != nil {
@@ -201,6 +338,23 @@ func (s *service) UpdateScheduledTask(userID uuid.UUID, id uuid.UUID, req *Updat
 		updates["description"] = req.Description
 	}
 
+	if req.HistoryRetention != nil {
+		if err := s.validateHistoryRetention(*req.HistoryRetention); err != nil {
+			return nil, err
+		}
+		updates["history_retention"] = *req.HistoryRetention
+	}
+
+	if err := s.validatePolicies(req.MissedRunPolicy, req.OverlapPolicy); err != nil {
+		return nil, err
+	}
+	if req.MissedRunPolicy != "" {
+		updates["missed_run_policy"] = req.MissedRunPolicy
+	}
+	if req.OverlapPolicy != "" {
+		updates["overlap_policy"] = req.OverlapPolicy
+	}
+
 	if len(updates) == 0 {
 		return existingTask, nil
 	}
@@ -210,6 +364,8 @@ func (s *service) UpdateScheduledTask(userID uuid.UUID, id uuid.UUID, req *Updat
 		return nil, err
 	}
 
+	s.reloadSchedule(id)
+
 	// 返回更新后的任务
 	return s.repo.GetByID(id)
 }
@@ -227,7 +383,12 @@ func (s *service) DeleteScheduledTask(userID uuid.UUID, id uuid.UUID) error {
 		return ErrUnauthorized
 	}
 
-	return s.repo.Delete(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.reloadSchedule(id)
+	return nil
 }
 
 // GetScheduledTask 获取定时任务详情
@@ -306,7 +467,12 @@ func (s *service) ToggleTask(userID uuid.UUID, id uuid.UUID, enabled bool) error
 		updates["next_run_at"] = nextRunAt
 	}
 
-	return s.repo.Update(id, updates)
+	if err := s.repo.Update(id, updates); err != nil {
+		return err
+	}
+
+	s.reloadSchedule(id)
+	return nil
 }
 
 // TriggerTask 手动触发定时任务
@@ -322,8 +488,12 @@ func (s *service) TriggerTask(userID uuid.UUID, id uuid.UUID) error {
 		return ErrUnauthorized
 	}
 
-	// TODO: 这里应该调用实际的任务执行逻辑
-	// 现在只更新最后运行时间
+	// 由调度器异步执行，执行结束后更新运行统计
+	if s.scheduler != nil {
+		return s.scheduler.Trigger(task.ID)
+	}
+
+	// 未启用调度器时仅记录触发
 	now := time.Now()
 	updates := map[string]interface{}{
 		"last_run_at": now,
@@ -332,3 +502,79 @@ func (s *service) TriggerTask(userID uuid.UUID, id uuid.UUID) error {
 
 	return s.repo.Update(id, updates)
 }
+
+// ListTaskRuns 获取定时任务的运行记录列表
+func (s *service) ListTaskRuns(userID uuid.UUID, id uuid.UUID, req *ListScheduledTaskRunsRequest) (*ListScheduledTaskRunsResponse, error) {
+	if _, err := s.GetScheduledTask(userID, id); err != nil {
+		return nil, err
+	}
+
+	// 验证筛选条件
+	if req.Status != "" {
+		validStatuses := map[string]bool{RunStatusRunning: true, RunStatusSuccess: true, RunStatusFailed: true, RunStatusSkipped: true}
+		if !validStatuses[req.Status] {
+			return nil, errors.New("invalid status, must be one of: running, success, failed, skipped")
+		}
+	}
+	if req.TriggerSource != "" {
+		validTriggers := map[string]bool{TriggerCron: true, TriggerManual: true, TriggerCatchUp: true}
+		if !validTriggers[req.TriggerSource] {
+			return nil, errors.New("invalid trigger_source, must be one of: cron, manual, catchup")
+		}
+	}
+
+	// 设置默认值
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	runs, total, err := s.repo.ListRuns(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算总页数
+	totalPages := int(total) / req.Limit
+	if int(total)%req.Limit > 0 {
+		totalPages++
+	}
+
+	return &ListScheduledTaskRunsResponse{
+		Data:       runs,
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// GetTaskRun 获取定时任务的单条运行记录（含各服务器输出）
+func (s *service) GetTaskRun(userID uuid.UUID, id uuid.UUID, runID uuid.UUID) (*ScheduledTaskRun, error) {
+	if _, err := s.GetScheduledTask(userID, id); err != nil {
+		return nil, err
+	}
+
+	run, err := s.repo.GetRun(id, runID)
+	if err != nil {
+		return nil, ErrRunNotFound
+	}
+
+	return run, nil
+}
+
+// checkScriptVersion 检查固定的脚本版本是否存在（未设置脚本服务时跳过）
+func (s *service) checkScriptVersion(userID, scriptID uuid.UUID, version int) error {
+	if s.scriptService == nil {
+		return nil
+	}
+	if _, err := s.scriptService.GetVersion(userID, scriptID, version); err != nil {
+		if errors.Is(err, script.ErrVersionNotFound) {
+			return fmt.Errorf("script_version %d does not exist", version)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package script
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	seq := func(from, to int, replace map[int]string) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			if s, ok := replace[i]; ok {
+				b.WriteString(s)
+			} else {
+				b.WriteString(strconv.Itoa(i))
+			}
+			b.WriteByte('\n')
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "identical",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "single line changed",
+			from: "a\nb\nc\n",
+			to:   "a\nB\nc\n",
+			want: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "distant changes produce separate hunks",
+			from: seq(1, 12, nil),
+			to:   seq(1, 12, map[int]string{2: "two", 11: "eleven"}),
+			want: "@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -8,5 +8,5 @@\n 8\n 9\n 10\n-11\n+eleven\n 12\n",
+		},
+		{
+			name: "nearby changes merge into one hunk",
+			from: seq(1, 8, nil),
+			to:   seq(1, 8, map[int]string{2: "two", 6: "six"}),
+			want: "@@ -1,8 +1,8 @@\n 1\n-2\n+two\n 3\n 4\n 5\n-6\n+six\n 7\n 8\n",
+		},
+		{
+			name: "from empty",
+			from: "",
+			to:   "x\ny\n",
+			want: "@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "to empty",
+			from: "x\ny\n",
+			to:   "",
+			want: "@@ -1,2 +0,0 @@\n-x\n-y\n",
+		},
+		{
+			name: "line removed at end",
+			from: "a\nb\nc\n",
+			to:   "a\nb\n",
+			want: "@@ -1,3 +1,2 @@\n a\n b\n-c\n",
+		},
+		{
+			name: "line inserted at start",
+			from: "a\nb\n",
+			to:   "new\na\nb\n",
+			want: "@@ -1,2 +1,3 @@\n+new\n a\n b\n",
+		},
+		{
+			name: "missing trailing newline is ignored",
+			from: "a\nb",
+			to:   "a\nb\n",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("v1", "v2", tt.from, tt.to)
+			want := "--- v1\n+++ v2\n" + tt.want
+			if got != want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesFallsBackToReplaceWhenTooLarge(t *testing.T) {
+	a := make([]string, maxDiffEdits)
+	b := make([]string, maxDiffEdits)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+
+	ops := diffLines(a, b)
+	if len(ops) != len(a)+len(b) {
+		t.Fatalf("len(ops) = %d, want %d", len(ops), len(a)+len(b))
+	}
+	for i, op := range ops {
+		want := byte('-')
+		if i >= len(a) {
+			want = '+'
+		}
+		if op.kind != want {
+			t.Fatalf("ops[%d].kind = %q, want %q", i, op.kind, want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/server"
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// interpreters 脚本语言对应的远程解释器命令（脚本内容通过 stdin 传入）
+var interpreters = map[string]string{
+	"bash":   "bash -s",
+	"sh":     "sh -s",
+	"python": "python3 -",
+}
+
+// placeholderRefs 各语言中参数占位符替换成的环境变量引用
+// 参数值只通过环境变量传入，不拼接进脚本源码，避免值中的 ;、$()、引号等被当作代码执行
+// shell 中的引用带双引号，值中的空格和通配符不会被拆分或展开
+var placeholderRefs = map[string]func(name string) string{
+	"bash":   func(name string) string { return `"${` + name + `}"` },
+	"sh":     func(name string) string { return `"${` + name + `}"` },
+	"python": func(name string) string { return `__import__("os").environ["` + name + `"]` },
+}
+
+// placeholderPattern 脚本中的参数占位符，如 {{ backup_dir }}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// parameterNamePattern 参数名规则
+var parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// InterpreterCommand 返回语言对应的远程命令，env 中的参数值以环境变量形式传给解释器
+func InterpreterCommand(language string, env map[string]string) (string, error) {
+	cmd, ok := interpreters[language]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedLanguage, language)
+	}
+	if len(env) == 0 {
+		return cmd, nil
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names)+2)
+	parts = append(parts, "env")
+	for _, name := range names {
+		parts = append(parts, name+"="+shellQuote(env[name]))
+	}
+	parts = append(parts, cmd)
+	return strings.Join(parts, " "), nil
+}
+
+// shellQuote 将字符串转为 POSIX shell 单引号字面量
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// validateParameterDefinitions 验证脚本声明的参数
+func validateParameterDefinitions(params []ScriptParameter) error {
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !parameterNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("%w: invalid parameter name %q", ErrInvalidParameters, p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("%w: duplicate parameter %q", ErrInvalidParameters, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// RenderContent 将脚本中的参数占位符替换为环境变量引用，返回替换后的内容和各参数的取值
+// 未传入的参数使用默认值；必填参数缺失或传入未声明的参数时返回错误；未声明的占位符保持原样
+func RenderContent(s *Script, values map[string]string) (string, map[string]string, error) {
+	ref, ok := placeholderRefs[s.Language]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, s.Language)
+	}
+
+	declared := make(map[string]ScriptParameter, len(s.Parameters))
+	for _, p := range s.Parameters {
+		declared[p.Name] = p
+	}
+
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			return "", nil, fmt.Errorf("%w: %s", ErrUnknownParameter, name)
+		}
+	}
+
+	resolved := make(map[string]string, len(declared))
+	for name, p := range declared {
+		value, ok := values[name]
+		if !ok || value == "" {
+			if p.Required && p.Default == "" {
+				return "", nil, fmt.Errorf("%w: %s", ErrMissingParameter, name)
+			}
+			value = p.Default
+		}
+		if strings.ContainsRune(value, 0) {
+			return "", nil, fmt.Errorf("%w: parameter %q contains NUL character", ErrInvalidParameters, name)
+		}
+		resolved[name] = value
+	}
+
+	content := placeholderPattern.ReplaceAllStringFunc(s.Content, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		if _, ok := resolved[name]; ok {
+			return ref(name)
+		}
+		return match
+	})
+	return content, resolved, nil
+}
+
+// PreparedScript 可直接远程执行的脚本
+type PreparedScript struct {
+	Command string // 远程命令（解释器及参数环境变量；直接执行的命令本身）
+	Content string // 通过 stdin 传给解释器的脚本内容（直接执行命令时为空）
+}
+
+// Stdin 返回脚本内容的 Reader，没有脚本内容（直接执行命令）时返回 nil
+func (p *PreparedScript) Stdin() io.Reader {
+	if p.Content == "" {
+		return nil
+	}
+	return strings.NewReader(p.Content)
+}
+
+// Prepare 按脚本语言和参数生成远程执行所需的命令和内容
+// 所有脚本执行（脚本执行接口、批量任务、定时任务）都应经过这里，保证解释器和参数处理一致
+func Prepare(s *Script, values map[string]string) (*PreparedScript, error) {
+	content, env, err := RenderContent(s, values)
+	if err != nil {
+		return nil, err
+	}
+	command, err := InterpreterCommand(s.Language, env)
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedScript{Command: command, Content: content}, nil
+}
+
+// Executor 脚本执行器，通过 SSH 在目标服务器上运行脚本
+type Executor struct {
+	serverService   server.Service
+	encryptor       *crypto.Encryptor
+	hostKeyCallback ssh.HostKeyCallback
+	maxParallel     int
+}
+
+// NewExecutor 创建脚本执行器
+func NewExecutor(serverService server.Service, encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback) *Executor {
+	return &Executor{
+		serverService:   serverService,
+		encryptor:       encryptor,
+		hostKeyCallback: hostKeyCallback,
+		maxParallel:     sshDomain.DefaultMaxParallel,
+	}
+}
+
+// Run 在多台服务器上并行执行已渲染的脚本，阻塞直到全部结束
+func (e *Executor) Run(ctx context.Context, userID uuid.UUID, prepared *PreparedScript, serverIDs []string, timeout time.Duration) ([]*ServerExecutionResult, error) {
+	results := make([]*ServerExecutionResult, len(serverIDs))
+	for i, serverID := range serverIDs {
+		results[i] = &ServerExecutionResult{ServerID: serverID}
+	}
+	sshDomain.RunEach(len(results), e.maxParallel, func(i int) {
+		e.runOnServer(ctx, userID, results[i], prepared, timeout)
+	})
+
+	return results, nil
+}
+
+// runOnServer 在单台服务器上执行脚本，并将结果写回 result
+func (e *Executor) runOnServer(ctx context.Context, userID uuid.UUID, result *ServerExecutionResult, prepared *PreparedScript, timeout time.Duration) {
+	// stdout/stderr 分别保留
+	stdout := sshDomain.NewOutputBuffer(sshDomain.DefaultOutputLimit)
+	stderr := sshDomain.NewOutputBuffer(sshDomain.DefaultOutputLimit)
+	startedAt := time.Now()
+
+	defer func() {
+		result.Duration = time.Since(startedAt).Milliseconds()
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+		result.OutputTruncated = stdout.Truncated() || stderr.Truncated()
+		result.Success = result.Error == "" && result.ExitCode != nil && *result.ExitCode == 0
+	}()
+
+	// 整体执行时间已用尽（或请求已取消）时不再连接剩余的服务器
+	if err := ctx.Err(); err != nil {
+		result.Error = fmt.Sprintf("not started: %v", err)
+		return
+	}
+
+	sid, err := uuid.Parse(result.ServerID)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid server id: %v", err)
+		return
+	}
+
+	srv, err := e.serverService.GetByID(ctx, userID, sid)
+	if err != nil {
+		result.Error = fmt.Sprintf("server not found: %v", err)
+		return
+	}
+	result.ServerName = srv.Name
+
+	exitCode, err := sshDomain.RunRemote(ctx, srv, e.encryptor, e.hostKeyCallback, &sshDomain.RemoteExec{
+		Command: prepared.Command,
+		Stdin:   prepared.Stdin(),
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Timeout: timeout,
+	})
+	if err != nil {
+		if errors.Is(err, sshDomain.ErrExecCanceled) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: overall execution deadline exceeded", sshDomain.ErrExecTimeout)
+		}
+		result.Error = err.Error()
+		return
+	}
+
+	result.ExitCode = &exitCode
+}
@@ -0,0 +1,215 @@
+package script
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRenderContent(t *testing.T) {
+	params := []ScriptParameter{
+		{Name: "dir", Default: "/tmp"},
+		{Name: "name", Required: true},
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		values   map[string]string
+		want     string
+		wantEnv  map[string]string
+		wantErr  error
+	}{
+		{
+			name:     "bash placeholder is quoted",
+			language: "bash",
+			content:  "cp {{ name }} {{dir}}",
+			values:   map[string]string{"name": "a b"},
+			want:     `cp "${name}" "${dir}"`,
+			wantEnv:  map[string]string{"name": "a b", "dir": "/tmp"},
+		},
+		{
+			name:     "sh placeholder is quoted",
+			language: "sh",
+			content:  "ls {{name}}",
+			values:   map[string]string{"name": "*"},
+			want:     `ls "${name}"`,
+			wantEnv:  map[string]string{"name": "*", "dir": "/tmp"},
+		},
+		{
+			name:     "python placeholder reads environment",
+			language: "python",
+			content:  "print({{name}})",
+			values:   map[string]string{"name": "x"},
+			want:     `print(__import__("os").environ["name"])`,
+			wantEnv:  map[string]string{"name": "x", "dir": "/tmp"},
+		},
+		{
+			name:     "value is not spliced into content",
+			language: "bash",
+			content:  "echo {{name}}",
+			values:   map[string]string{"name": "$(reboot); 'x'"},
+			want:     `echo "${name}"`,
+			wantEnv:  map[string]string{"name": "$(reboot); 'x'", "dir": "/tmp"},
+		},
+		{
+			name:     "undeclared placeholder is kept",
+			language: "bash",
+			content:  "echo {{other}} {{name}}",
+			values:   map[string]string{"name": "x"},
+			want:     `echo {{other}} "${name}"`,
+			wantEnv:  map[string]string{"name": "x", "dir": "/tmp"},
+		},
+		{
+			name:     "empty value falls back to default",
+			language: "bash",
+			content:  "cd {{dir}}",
+			values:   map[string]string{"name": "x", "dir": ""},
+			want:     `cd "${dir}"`,
+			wantEnv:  map[string]string{"name": "x", "dir": "/tmp"},
+		},
+		{
+			name:     "missing required parameter",
+			language: "bash",
+			content:  "echo {{name}}",
+			wantErr:  ErrMissingParameter,
+		},
+		{
+			name:     "unknown parameter",
+			language: "bash",
+			content:  "echo",
+			values:   map[string]string{"name": "x", "extra": "y"},
+			wantErr:  ErrUnknownParameter,
+		},
+		{
+			name:     "nul character",
+			language: "bash",
+			content:  "echo {{name}}",
+			values:   map[string]string{"name": "a\x00b"},
+			wantErr:  ErrInvalidParameters,
+		},
+		{
+			name:     "unsupported language",
+			language: "ruby",
+			content:  "puts 1",
+			wantErr:  ErrUnsupportedLanguage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Script{Language: tt.language, Content: tt.content, Parameters: params}
+			got, env, err := RenderContent(s, tt.values)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("RenderContent() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderContent() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderContent() content = %q, want %q", got, tt.want)
+			}
+			if len(env) != len(tt.wantEnv) {
+				t.Fatalf("RenderContent() env = %q, want %q", env, tt.wantEnv)
+			}
+			for name, value := range tt.wantEnv {
+				if env[name] != value {
+					t.Errorf("RenderContent() env[%s] = %q, want %q", name, env[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestInterpreterCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		env      map[string]string
+		want     string
+	}{
+		{name: "no parameters", language: "bash", want: "bash -s"},
+		{name: "python", language: "python", want: "python3 -"},
+		{name: "sorted names", language: "sh", env: map[string]string{"b": "2", "a": "1"}, want: "env a='1' b='2' sh -s"},
+		{name: "spaces", language: "bash", env: map[string]string{"a": "x y"}, want: "env a='x y' bash -s"},
+		{name: "glob", language: "bash", env: map[string]string{"a": "*.log"}, want: "env a='*.log' bash -s"},
+		{name: "single quote", language: "bash", env: map[string]string{"a": "it's"}, want: `env a='it'\''s' bash -s`},
+		{name: "command substitution", language: "bash", env: map[string]string{"a": "$(id)`id`"}, want: "env a='$(id)`id`' bash -s"},
+		{name: "empty value", language: "bash", env: map[string]string{"a": ""}, want: "env a='' bash -s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InterpreterCommand(tt.language, tt.env)
+			if err != nil {
+				t.Fatalf("InterpreterCommand() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("InterpreterCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := InterpreterCommand("ruby", nil); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("InterpreterCommand(ruby) error = %v, want %v", err, ErrUnsupportedLanguage)
+	}
+}
+
+// TestPrepareRoundTrip 在本机解释器中运行 Prepare 的结果，确认参数值原样到达脚本
+func TestPrepareRoundTrip(t *testing.T) {
+	values := []string{
+		"plain",
+		"with  two spaces",
+		"*",
+		"/etc/*.conf",
+		`it's "quoted"`,
+		"$(echo injected)",
+		"`echo injected`",
+		"a; echo injected",
+		"line1\nline2",
+		"-n",
+	}
+
+	scripts := map[string]string{
+		"bash":   "printf '%s' {{value}}",
+		"sh":     "printf '%s' {{value}}",
+		"python": "import sys\nsys.stdout.write({{value}})",
+	}
+
+	for language, content := range scripts {
+		interpreter := strings.Fields(interpreters[language])[0]
+		if _, err := exec.LookPath(interpreter); err != nil {
+			t.Logf("skip %s: %s not found", language, interpreter)
+			continue
+		}
+
+		s := &Script{
+			Language:   language,
+			Content:    content,
+			Parameters: []ScriptParameter{{Name: "value", Required: true}},
+		}
+		for _, value := range values {
+			t.Run(language+"/"+value, func(t *testing.T) {
+				prepared, err := Prepare(s, map[string]string{"value": value})
+				if err != nil {
+					t.Fatalf("Prepare() error = %v", err)
+				}
+
+				cmd := exec.Command("sh", "-c", prepared.Command)
+				cmd.Stdin = prepared.Stdin()
+				out, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("run %q: %v", prepared.Command, err)
+				}
+				if string(out) != value {
+					t.Errorf("script output = %q, want %q", out, value)
+				}
+			})
+		}
+	}
+}
@@ -8,19 +8,34 @@ import (
 )
 
 // Script 脚本模型
+//
+// Parameters 中声明的参数在内容中以 {{name}} 引用，执行时占位符被替换为读取同名环境变量的完整表达式，
+// 因此占位符只能作为独立的表达式使用，不能写在引号或字符串字面量中：
+//   - bash/sh 替换为 "${name}"：写 cp {{src}} {{dst}}，而不是 cp "{{src}}" '{{dst}}'
+//   - python 替换为 os.environ 取值：写 path = {{dir}} 或 "prefix-" + {{dir}}，而不是 "{{dir}}"
 type Script struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
-	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
-	Description string         `gorm:"type:text" json:"description"`
-	Content     string         `gorm:"type:text;not null" json:"content"`
-	Language    string         `gorm:"type:varchar(20);default:'bash'" json:"language"`
-	Tags        []string       `gorm:"type:jsonb;serializer:json" json:"tags"`
-	Executions  int            `gorm:"default:0" json:"executions"`
-	Author      string         `gorm:"type:varchar(50)" json:"author"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name        string            `gorm:"type:varchar(100);not null" json:"name"`
+	Description string            `gorm:"type:text" json:"description"`
+	Content     string            `gorm:"type:text;not null" json:"content"`
+	Language    string            `gorm:"type:varchar(20);default:'bash'" json:"language"`
+	Tags        []string          `gorm:"type:jsonb;serializer:json" json:"tags"`
+	Parameters  []ScriptParameter `gorm:"type:jsonb;serializer:json" json:"parameters"` // 声明的参数，内容中以 {{name}} 引用（写法见类型注释）
+	Version     int               `gorm:"default:1" json:"version"`                     // 当前版本号，每次修改递增
+	Executions  int               `gorm:"default:0" json:"executions"`
+	Author      string            `gorm:"type:varchar(50)" json:"author"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// ScriptParameter 脚本参数声明
+type ScriptParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
 }
 
 // TableName 指定表名
@@ -38,20 +53,23 @@ func (s *Script) BeforeCreate(tx *gorm.DB) error {
 
 // CreateScriptRequest 创建脚本请求
 type CreateScriptRequest struct {
-	Name        string   `json:"name" binding:"required"`
-	Description string   `json:"description"`
-	Content     string   `json:"content" binding:"required"`
-	Language    string   `json:"language"`
-	Tags        []string `json:"tags"`
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Content     string            `json:"content" binding:"required"`
+	Language    string            `json:"language"`
+	Tags        []string          `json:"tags"`
+	Parameters  []ScriptParameter `json:"parameters"`
 }
 
 // UpdateScriptRequest 更新脚本请求
 type UpdateScriptRequest struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Content     string   `json:"content"`
-	Language    string   `json:"language"`
-	Tags        []string `json:"tags"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Content     string            `json:"content"`
+	Language    string            `json:"language"`
+	Tags        []string          `json:"tags"`
+	Parameters  []ScriptParameter `json:"parameters"`
+	ChangeNote  string            `json:"change_note"` // 本次修改说明（记录在版本历史中）
 }
 
 // ListScriptsRequest 脚本列表查询请求
@@ -71,3 +89,119 @@ type ListScriptsResponse struct {
 	PageSize   int      `json:"page_size"`
 	TotalPages int      `json:"total_pages"`
 }
+
+// ExecuteScriptRequest 执行脚本请求
+type ExecuteScriptRequest struct {
+	ServerIDs  []string          `json:"server_ids" binding:"required,min=1,max=100"` // 最多 100 台，整个请求最长执行 270 秒
+	Parameters map[string]string `json:"parameters"`
+	Timeout    int               `json:"timeout"` // 单台服务器超时（秒），默认 60，最大 240
+}
+
+// ServerExecutionResult 脚本在单台服务器上的执行结果
+type ServerExecutionResult struct {
+	ServerID        string `json:"server_id"`
+	ServerName      string `json:"server_name,omitempty"`
+	Success         bool   `json:"success"`
+	ExitCode        *int   `json:"exit_code,omitempty"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	OutputTruncated bool   `json:"output_truncated"`
+	Error           string `json:"error,omitempty"`
+	Duration        int64  `json:"duration"` // 毫秒
+}
+
+// ExecuteScriptResponse 执行脚本响应
+type ExecuteScriptResponse struct {
+	ScriptID     uuid.UUID                `json:"script_id"`
+	Language     string                   `json:"language"`
+	Results      []*ServerExecutionResult `json:"results"`
+	SuccessCount int                      `json:"success_count"`
+	FailedCount  int                      `json:"failed_count"`
+}
+
+// ScriptVersion 脚本版本（每次创建或修改脚本时生成的不可变快照）
+type ScriptVersion struct {
+	ID          uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	ScriptID    uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_script_versions_script_version" json:"script_id"`
+	Version     int               `gorm:"not null;uniqueIndex:idx_script_versions_script_version" json:"version"`
+	Name        string            `gorm:"type:varchar(100);not null" json:"name"`
+	Description string            `gorm:"type:text" json:"description"`
+	Content     string            `gorm:"type:text;not null" json:"content,omitempty"`
+	Language    string            `gorm:"type:varchar(20)" json:"language"`
+	Tags        []string          `gorm:"type:jsonb;serializer:json" json:"tags"`
+	Parameters  []ScriptParameter `gorm:"type:jsonb;serializer:json" json:"parameters"`
+	ChangeNote  string            `gorm:"type:text" json:"change_note,omitempty"`
+	CreatedBy   uuid.UUID         `gorm:"type:uuid" json:"created_by"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ScriptVersion) TableName() string {
+	return "script_versions"
+}
+
+// BeforeCreate GORM 钩子：创建前自动生成 UUID
+func (v *ScriptVersion) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// AsScript 将版本快照转换为脚本（用于按固定版本执行）
+func (v *ScriptVersion) AsScript() *Script {
+	return &Script{
+		ID:          v.ScriptID,
+		Name:        v.Name,
+		Description: v.Description,
+		Content:     v.Content,
+		Language:    v.Language,
+		Tags:        v.Tags,
+		Parameters:  v.Parameters,
+		Version:     v.Version,
+	}
+}
+
+// newScriptVersion 根据脚本当前状态生成版本快照
+func newScriptVersion(s *Script, createdBy uuid.UUID, changeNote string) *ScriptVersion {
+	return &ScriptVersion{
+		ScriptID:    s.ID,
+		Version:     s.Version,
+		Name:        s.Name,
+		Description: s.Description,
+		Content:     s.Content,
+		Language:    s.Language,
+		Tags:        s.Tags,
+		Parameters:  s.Parameters,
+		ChangeNote:  changeNote,
+		CreatedBy:   createdBy,
+	}
+}
+
+// ListScriptVersionsRequest 版本列表查询请求
+type ListScriptVersionsRequest struct {
+	Page  int `form:"page" json:"page"`
+	Limit int `form:"limit" json:"limit"`
+}
+
+// ListScriptVersionsResponse 版本列表响应（不含脚本内容）
+type ListScriptVersionsResponse struct {
+	Data       []ScriptVersion `json:"data"`
+	Total      int64           `json:"total"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"page_size"`
+	TotalPages int             `json:"total_pages"`
+}
+
+// RollbackScriptRequest 回滚脚本请求
+type RollbackScriptRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+// ScriptDiffResponse 两个版本之间的差异
+type ScriptDiffResponse struct {
+	ScriptID    uuid.UUID `json:"script_id"`
+	FromVersion int       `json:"from_version"`
+	ToVersion   int       `json:"to_version"`
+	Diff        string    `json:"diff"` // unified diff 格式
+}
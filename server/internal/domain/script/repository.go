@@ -3,6 +3,7 @@ package script
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository 脚本数据访问接口
@@ -13,6 +14,10 @@ type Repository interface {
 	GetByID(id uuid.UUID) (*Script, error)
 	List(userID uuid.UUID, req *ListScriptsRequest) ([]Script, int64, error)
 	IncrementExecutions(id uuid.UUID) error
+	CreateWithVersion(script *Script, createdBy uuid.UUID) error
+	UpdateWithVersion(id uuid.UUID, updates map[string]interface{}, createdBy uuid.UUID, changeNote string) (*Script, error)
+	GetVersion(scriptID uuid.UUID, version int) (*ScriptVersion, error)
+	ListVersions(scriptID uuid.UUID, req *ListScriptVersionsRequest) ([]ScriptVersion, int64, error)
 }
 
 type repository struct {
@@ -108,3 +113,87 @@ func (r *repository) IncrementExecutions(id uuid.UUID) error {
 		UpdateColumn("executions", gorm.Expr("executions + ?", 1)).
 		Error
 }
+
+// CreateWithVersion 创建脚本并记录第 1 个版本
+func (r *repository) CreateWithVersion(script *Script, createdBy uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		script.Version = 1
+		if err := tx.Create(script).Error; err != nil {
+			return err
+		}
+		return tx.Create(newScriptVersion(script, createdBy, "")).Error
+	})
+}
+
+// UpdateWithVersion 更新脚本、递增版本号并记录新版本快照
+// 对于引入版本历史之前创建的脚本，会先补记修改前的版本，保证可以回滚
+func (r *repository) UpdateWithVersion(id uuid.UUID, updates map[string]interface{}, createdBy uuid.UUID, changeNote string) (*Script, error) {
+	var script Script
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定脚本行，串行化并发修改
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&script).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&ScriptVersion{}).
+			Where("script_id = ? AND version = ?", id, script.Version).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := tx.Create(newScriptVersion(&script, script.UserID, "")).Error; err != nil {
+				return err
+			}
+		}
+
+		// 版本号在数据库中递增
+		updates["version"] = gorm.Expr("version + ?", 1)
+		if err := tx.Model(&Script{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		script = Script{}
+		if err := tx.Where("id = ?", id).First(&script).Error; err != nil {
+			return err
+		}
+		return tx.Create(newScriptVersion(&script, createdBy, changeNote)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// GetVersion 获取脚本的指定版本
+func (r *repository) GetVersion(scriptID uuid.UUID, version int) (*ScriptVersion, error) {
+	var v ScriptVersion
+	err := r.db.Where("script_id = ? AND version = ?", scriptID, version).First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListVersions 获取脚本的版本列表（不含内容，按版本号倒序）
+func (r *repository) ListVersions(scriptID uuid.UUID, req *ListScriptVersionsRequest) ([]ScriptVersion, int64, error) {
+	var versions []ScriptVersion
+	var total int64
+
+	query := r.db.Model(&ScriptVersion{}).Where("script_id = ?", scriptID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (req.Page - 1) * req.Limit
+	if err := query.Omit("content").
+		Order("version DESC").
+		Offset(offset).
+		Limit(req.Limit).
+		Find(&versions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return versions, total, nil
+}
@@ -1,8 +1,11 @@
 package script
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,6 +15,22 @@ var (
 	ErrUnauthorized        = errors.New("unauthorized to access this script")
 	ErrInvalidScriptData   = errors.New("invalid script data")
 	ErrScriptNameDuplicate = errors.New("script name already exists")
+	ErrInvalidParameters   = errors.New("invalid script parameters")
+	ErrMissingParameter    = errors.New("missing required parameter")
+	ErrUnknownParameter    = errors.New("unknown parameter")
+	ErrUnsupportedLanguage = errors.New("unsupported script language, must be one of: bash, sh, python")
+	ErrExecutorUnavailable = errors.New("script executor is not configured")
+	ErrVersionNotFound     = errors.New("script version not found")
+)
+
+const (
+	// defaultExecuteTimeout 单台服务器默认执行超时
+	defaultExecuteTimeout = 60 * time.Second
+	// maxExecuteTimeout 单台服务器最大执行超时
+	maxExecuteTimeout = 240 * time.Second
+	// maxExecuteDuration 一次执行请求的总时长上限，服务器较多需要分批执行时同样受限
+	// 执行结果随 HTTP 响应同步返回，需小于 HTTP 写超时（300 秒）并留出写回响应的时间
+	maxExecuteDuration = 270 * time.Second
 )
 
 // Service 脚本业务逻辑接口
@@ -21,11 +40,17 @@ type Service interface {
 	DeleteScript(userID uuid.UUID, scriptID uuid.UUID) error
 	GetScript(userID uuid.UUID, scriptID uuid.UUID) (*Script, error)
 	ListScripts(userID uuid.UUID, req *ListScriptsRequest) (*ListScriptsResponse, error)
-	ExecuteScript(userID uuid.UUID, scriptID uuid.UUID) error
+	ExecuteScript(ctx context.Context, userID uuid.UUID, scriptID uuid.UUID, req *ExecuteScriptRequest) (*ExecuteScriptResponse, error)
+	ListVersions(userID uuid.UUID, scriptID uuid.UUID, req *ListScriptVersionsRequest) (*ListScriptVersionsResponse, error)
+	GetVersion(userID uuid.UUID, scriptID uuid.UUID, version int) (*ScriptVersion, error)
+	DiffVersions(userID uuid.UUID, scriptID uuid.UUID, fromVersion, toVersion int) (*ScriptDiffResponse, error)
+	RollbackScript(userID uuid.UUID, scriptID uuid.UUID, version int) (*Script, error)
+	PrepareScript(userID uuid.UUID, scriptID uuid.UUID, version *int) (*PreparedScript, error)
 }
 
 type service struct {
-	repo Repository
+	repo     Repository
+	executor *Executor
 }
 
 // NewService 创建脚本服务实例
@@ -35,6 +60,11 @@ func NewService(repo Repository) Service {
 	}
 }
 
+// SetExecutor 设置脚本执行器
+func (s *service) SetExecutor(executor *Executor) {
+	s.executor = executor
+}
+
 // CreateScript 创建脚本
 func (s *service) CreateScript(userID uuid.UUID, username string, req *CreateScriptRequest) (*Script, error) {
 	// 验证输入
@@ -47,6 +77,10 @@ func (s *service) CreateScript(userID uuid.UUID, username string, req *CreateScr
 		req.Language = "bash"
 	}
 
+	if err := validateParameterDefinitions(req.Parameters); err != nil {
+		return nil, err
+	}
+
 	// 创建脚本
 	script := &Script{
 		UserID:      userID,
@@ -55,11 +89,12 @@ func (s *service) CreateScript(userID uuid.UUID, username string, req *CreateScr
 		Content:     req.Content,
 		Language:    req.Language,
 		Tags:        req.Tags,
+		Parameters:  req.Parameters,
 		Author:      username,
 		Executions:  0,
 	}
 
-	if err := s.repo.Create(script); err != nil {
+	if err := s.repo.CreateWithVersion(script, userID); err != nil {
 		return nil, err
 	}
 
@@ -95,14 +130,19 @@ func (s *service) UpdateScript(userID uuid.UUID, scriptID uuid.UUID, req *Update
 	if req.Tags != nil {
 		updates["tags"] = req.Tags
 	}
+	if req.Parameters != nil {
+		if err := validateParameterDefinitions(req.Parameters); err != nil {
+			return nil, err
+		}
+		updates["parameters"] = req.Parameters
+	}
 
-	// 更新脚本
-	if err := s.repo.Update(scriptID, updates); err != nil {
-		return nil, err
+	if len(updates) == 0 {
+		return script, nil
 	}
 
-	// 返回更新后的脚本
-	return s.repo.GetByID(scriptID)
+	// 更新脚本并记录新版本
+	return s.repo.UpdateWithVersion(scriptID, updates, userID, req.ChangeNote)
 }
 
 // DeleteScript 删除脚本
@@ -163,18 +203,186 @@ func (s *service) ListScripts(userID uuid.UUID, req *ListScriptsRequest) (*ListS
 	}, nil
 }
 
-// ExecuteScript 执行脚本（增加执行计数）
-func (s *service) ExecuteScript(userID uuid.UUID, scriptID uuid.UUID) error {
+// ExecuteScript 在目标服务器上执行脚本，返回每台服务器的输出和退出码
+func (s *service) ExecuteScript(ctx context.Context, userID uuid.UUID, scriptID uuid.UUID, req *ExecuteScriptRequest) (*ExecuteScriptResponse, error) {
 	// 检查脚本是否存在且属于当前用户
 	script, err := s.repo.GetByID(scriptID)
 	if err != nil {
-		return ErrScriptNotFound
+		return nil, ErrScriptNotFound
 	}
 
 	if script.UserID != userID {
-		return ErrUnauthorized
+		return nil, ErrUnauthorized
+	}
+
+	if s.executor == nil {
+		return nil, ErrExecutorUnavailable
+	}
+
+	if len(req.ServerIDs) == 0 {
+		return nil, ErrInvalidScriptData
+	}
+
+	prepared, err := Prepare(script, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultExecuteTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+		if timeout > maxExecuteTimeout {
+			timeout = maxExecuteTimeout
+		}
+	}
+
+	// 超出总时长时，未开始的服务器直接记为失败，执行中的服务器被中断
+	ctx, cancel := context.WithTimeout(ctx, maxExecuteDuration)
+	defer cancel()
+
+	results, err := s.executor.Run(ctx, userID, prepared, req.ServerIDs, timeout)
+	if err != nil {
+		return nil, err
 	}
 
 	// 增加执行次数
-	return s.repo.IncrementExecutions(scriptID)
+	if err := s.repo.IncrementExecutions(scriptID); err != nil {
+		return nil, err
+	}
+
+	response := &ExecuteScriptResponse{
+		ScriptID: scriptID,
+		Language: script.Language,
+		Results:  results,
+	}
+	for _, result := range results {
+		if result.Success {
+			response.SuccessCount++
+		} else {
+			response.FailedCount++
+		}
+	}
+
+	return response, nil
+}
+
+// PrepareScript 读取脚本（version 不为空时使用固定版本）并按其语言和参数默认值生成可远程执行的内容
+// 供批量任务、定时任务等不传参数的场景使用
+func (s *service) PrepareScript(userID uuid.UUID, scriptID uuid.UUID, version *int) (*PreparedScript, error) {
+	var target *Script
+	if version != nil {
+		v, err := s.GetVersion(userID, scriptID, *version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load script version %d: %w", *version, err)
+		}
+		target = v.AsScript()
+	} else {
+		script, err := s.GetScript(userID, scriptID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load script: %w", err)
+		}
+		target = script
+	}
+
+	return Prepare(target, nil)
+}
+
+// ListVersions 获取脚本的版本历史
+func (s *service) ListVersions(userID uuid.UUID, scriptID uuid.UUID, req *ListScriptVersionsRequest) (*ListScriptVersionsResponse, error) {
+	if _, err := s.GetScript(userID, scriptID); err != nil {
+		return nil, err
+	}
+
+	// 设置默认值
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+
+	versions, total, err := s.repo.ListVersions(scriptID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算总页数
+	totalPages := int(math.Ceil(float64(total) / float64(req.Limit)))
+
+	return &ListScriptVersionsResponse{
+		Data:       versions,
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// GetVersion 获取脚本的指定版本
+func (s *service) GetVersion(userID uuid.UUID, scriptID uuid.UUID, version int) (*ScriptVersion, error) {
+	script, err := s.GetScript(userID, scriptID)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.repo.GetVersion(scriptID, version)
+	if err != nil {
+		// 引入版本历史之前创建且从未修改过的脚本没有版本记录，当前内容即为该版本
+		if version == script.Version {
+			return newScriptVersion(script, script.UserID, ""), nil
+		}
+		return nil, ErrVersionNotFound
+	}
+
+	return v, nil
+}
+
+// DiffVersions 比较脚本两个版本的内容，toVersion 为 0 时与当前版本比较
+func (s *service) DiffVersions(userID uuid.UUID, scriptID uuid.UUID, fromVersion, toVersion int) (*ScriptDiffResponse, error) {
+	script, err := s.GetScript(userID, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	if toVersion == 0 {
+		toVersion = script.Version
+	}
+
+	from, err := s.GetVersion(userID, scriptID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetVersion(userID, scriptID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScriptDiffResponse{
+		ScriptID:    scriptID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Diff: UnifiedDiff(
+			fmt.Sprintf("%s (v%d)", from.Name, from.Version),
+			fmt.Sprintf("%s (v%d)", to.Name, to.Version),
+			from.Content, to.Content,
+		),
+	}, nil
+}
+
+// RollbackScript 回滚到指定版本（生成一个内容与该版本相同的新版本，历史版本保持不变）
+func (s *service) RollbackScript(userID uuid.UUID, scriptID uuid.UUID, version int) (*Script, error) {
+	target, err := s.GetVersion(userID, scriptID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"name":        target.Name,
+		"description": target.Description,
+		"content":     target.Content,
+		"language":    target.Language,
+		"tags":        target.Tags,
+		"parameters":  target.Parameters,
+	}
+
+	return s.repo.UpdateWithVersion(scriptID, updates, userID, fmt.Sprintf("rollback to v%d", version))
 }
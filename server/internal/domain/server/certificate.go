@@ -0,0 +1,63 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrInvalidCertificate     = errors.New("certificate must be an OpenSSH user certificate")
+	ErrCertificateNotYetValid = errors.New("certificate is not yet valid")
+	ErrCertificateExpired     = errors.New("certificate has expired")
+)
+
+// ParseUserCertificate 解析 OpenSSH 用户证书（authorized_keys 格式，如 id_ed25519-cert.pub 的内容）
+func ParseUserCertificate(text string) (*ssh.Certificate, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(text)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok || cert.CertType != ssh.UserCert {
+		return nil, ErrInvalidCertificate
+	}
+	return cert, nil
+}
+
+// CheckCertificateValidity 检查证书在指定时间是否处于有效期内
+func CheckCertificateValidity(cert *ssh.Certificate, now time.Time) error {
+	unix := uint64(now.Unix())
+	if unix < cert.ValidAfter {
+		return fmt.Errorf("%w: valid after %s", ErrCertificateNotYetValid, certTime(cert.ValidAfter).Format(time.RFC3339))
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && unix >= cert.ValidBefore {
+		return fmt.Errorf("%w: valid before %s", ErrCertificateExpired, certTime(cert.ValidBefore).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// certificateInfo 证书公开信息（用于 ToPublic）
+func certificateInfo(cert *ssh.Certificate) map[string]interface{} {
+	info := map[string]interface{}{
+		"key_id":      cert.KeyId,
+		"principals":  cert.ValidPrincipals,
+		"valid_after": certTime(cert.ValidAfter),
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		info["valid_before"] = certTime(cert.ValidBefore)
+	}
+	return info
+}
+
+// certTime 将证书中的时间戳转换为 time.Time
+func certTime(t uint64) time.Time {
+	if t > uint64(1<<63-1) {
+		t = uint64(1<<63 - 1)
+	}
+	return time.Unix(int64(t), 0)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"time"
 
+	"github.com/easyssh/server/internal/pkg/netproxy"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
@@ -12,10 +13,21 @@ import (
 type AuthMethod string
 
 const (
-	AuthMethodPassword AuthMethod = "password"
-	AuthMethodKey      AuthMethod = "key"
+	AuthMethodPassword            AuthMethod = "password"
+	AuthMethodKey                 AuthMethod = "key"
+	AuthMethodKeyboardInteractive AuthMethod = "keyboard_interactive" // 键盘交互（如 OTP），问题转发给用户回答，保存的密码用于自动回答密码问题
+	AuthMethodCertificate         AuthMethod = "certificate"          // OpenSSH 用户证书（私钥 + 签名证书）
 )
 
+// Valid 检查认证方式是否受支持
+func (m AuthMethod) Valid() bool {
+	switch m {
+	case AuthMethodPassword, AuthMethodKey, AuthMethodKeyboardInteractive, AuthMethodCertificate:
+		return true
+	}
+	return false
+}
+
 // ServerStatus 服务器状态
 type ServerStatus string
 
@@ -37,12 +49,23 @@ type Server struct {
 	AuthMethod    AuthMethod     `gorm:"type:varchar(20);not null" json:"auth_method"`
 	Password      string         `gorm:"type:text" json:"-"`       // 加密存储，不在 JSON 中返回
 	PrivateKey    string         `gorm:"type:text" json:"-"`       // 加密存储，不在 JSON 中返回
+	Passphrase    string         `gorm:"type:text" json:"-"` // 私钥口令，加密存储（为空时连接时向用户询问）
+	Certificate   string         `gorm:"type:text" json:"-"` // OpenSSH 用户证书（certificate 认证方式），信息通过 ToPublic 返回
 	Group         string         `gorm:"size:50" json:"group"`
 	Tags          pq.StringArray `gorm:"type:text[]" json:"tags"`
 	Status        ServerStatus   `gorm:"type:varchar(20);default:'unknown'" json:"status"`
 	LastConnected *time.Time     `json:"last_connected,omitempty"`
 	Description   string         `gorm:"type:text" json:"description"`
 	SortOrder     int            `gorm:"default:0;index" json:"sort_order"` // 用户自定义排序顺序
+	RecordSession *bool          `json:"record_session,omitempty"`                        // 是否录制终端会话（为空时沿用全局设置）
+	JumpServerID  *uuid.UUID     `gorm:"type:uuid;index" json:"jump_server_id,omitempty"` // 跳板机（为空时直连）
+	JumpServer    *Server        `gorm:"-" json:"-"`                                      // 已解析的跳板机（GetByID 时加载，可继续经由其跳板机）
+	ProxyType     netproxy.Type  `gorm:"type:varchar(20)" json:"proxy_type,omitempty"`    // 出站代理（为空时沿用全局设置，none 表示直连）
+	ProxyHost     string         `gorm:"size:255" json:"proxy_host,omitempty"`
+	ProxyPort     int            `json:"proxy_port,omitempty"`
+	ProxyUsername string         `gorm:"size:100" json:"proxy_username,omitempty"`
+	ProxyPassword string         `gorm:"type:text" json:"-"`                    // 加密存储，不在 JSON 中返回
+	WebProxyPorts pq.Int64Array  `gorm:"type:integer[]" json:"web_proxy_ports"` // 允许通过 Web 反向代理访问的端口（为空时不允许）
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"` // 软删除
@@ -89,9 +112,65 @@ func (s *Server) ToPublic() map[string]interface{} {
 		result["last_connected"] = s.LastConnected
 	}
 
+	if s.RecordSession != nil {
+		result["record_session"] = *s.RecordSession
+	}
+
+	if s.PrivateKey != "" {
+		result["passphrase_saved"] = s.Passphrase != ""
+	}
+
+	if s.Certificate != "" {
+		if cert, err := ParseUserCertificate(s.Certificate); err == nil {
+			result["certificate"] = certificateInfo(cert)
+		}
+	}
+
+	if s.JumpServerID != nil {
+		result["jump_server_id"] = s.JumpServerID
+	}
+
+	if s.ProxyType != "" {
+		result["proxy_type"] = s.ProxyType
+		result["proxy_host"] = s.ProxyHost
+		result["proxy_port"] = s.ProxyPort
+		result["proxy_username"] = s.ProxyUsername
+	}
+
+	if len(s.WebProxyPorts) > 0 {
+		result["web_proxy_ports"] = s.WebProxyPorts
+	}
+
 	return result
 }
 
+// JumpChain 获取连接需要依次经过的跳板机（最外层在前），直连时为空
+func (s *Server) JumpChain() []*Server {
+	var chain []*Server
+	for jump := s.JumpServer; jump != nil; jump = jump.JumpServer {
+		chain = append([]*Server{jump}, chain...)
+	}
+	return chain
+}
+
+// AllowsWebProxyPort 判断端口是否允许通过 Web 反向代理访问
+func (s *Server) AllowsWebProxyPort(port int) bool {
+	for _, allowed := range s.WebProxyPorts {
+		if allowed == int64(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldRecord 判断终端会话是否需要录制（服务器级设置优先于全局设置）
+func (s *Server) ShouldRecord(globalEnabled bool) bool {
+	if s.RecordSession != nil {
+		return *s.RecordSession
+	}
+	return globalEnabled
+}
+
 // UpdateStatus 更新服务器状态
 func (s *Server) UpdateStatus(status ServerStatus) {
 	s.Status = status
@@ -13,6 +13,7 @@ var (
 	ErrServerNotFound      = errors.New("server not found")
 	ErrServerAlreadyExists = errors.New("server already exists")
 	ErrUnauthorized        = errors.New("unauthorized to access this server")
+	ErrJumpServerInUse     = errors.New("server is used as a jump server by other servers")
 )
 
 // Repository 服务器数据访问接口
@@ -52,6 +53,9 @@ type Repository interface {
 
 	// UpdateSortOrders 批量更新服务器排序顺序
 	UpdateSortOrders(ctx context.Context, userID uuid.UUID, orders map[uuid.UUID]int) error
+
+	// CountByJumpServerID 统计以指定服务器为跳板机的服务器数量
+	CountByJumpServerID(ctx context.Context, jumpServerID uuid.UUID) (int64, error)
 }
 
 // gormRepository GORM 实现
@@ -231,6 +235,16 @@ func (r *gormRepository) FindByGroup(ctx context.Context, userID uuid.UUID, grou
 	return servers, total, nil
 }
 
+func (r *gormRepository) CountByJumpServerID(ctx context.Context, jumpServerID uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&Server{}).
+		Where("jump_server_id = ?", jumpServerID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (r *gormRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
 	var count int64
 	if err := r.db.WithContext(ctx).Model(&Server{}).
@@ -5,10 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/easyssh/server/internal/pkg/netproxy"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxJumpHops 跳板机链的最大长度
+const maxJumpHops = 5
+
+var (
+	ErrInvalidJumpServer = errors.New("jump server must be another server owned by the same user")
+	ErrJumpChainCycle    = errors.New("jump server chain contains a cycle")
+	ErrJumpChainTooLong  = errors.New("jump server chain is too long")
+	ErrInvalidProxy      = errors.New("invalid proxy settings")
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+	ErrWrongPassphrase   = errors.New("passphrase does not decrypt the private key")
+	ErrInvalidWebPorts   = errors.New("web proxy ports must be between 1 and 65535")
 )
 
 // Service 服务器服务接口
@@ -50,9 +66,23 @@ type CreateServerRequest struct {
 	AuthMethod  AuthMethod `json:"auth_method" binding:"required"`
 	Password    string     `json:"password"`
 	PrivateKey  string     `json:"private_key"`
+	Passphrase  string     `json:"passphrase"`  // 私钥口令（可选，不保存时连接时询问）
+	Certificate string     `json:"certificate"` // OpenSSH 用户证书（certificate 认证方式）
 	Group       string     `json:"group"`
 	Tags        []string   `json:"tags"`
 	Description string     `json:"description"`
+
+	RecordSession *bool      `json:"record_session"` // 为空时沿用全局录像设置
+	JumpServerID  *uuid.UUID `json:"jump_server_id"` // 经由跳板机连接（为空时直连）
+
+	// 出站代理（ProxyType 为空时沿用全局设置，none 表示直连）
+	ProxyType     netproxy.Type `json:"proxy_type"`
+	ProxyHost     string        `json:"proxy_host"`
+	ProxyPort     int           `json:"proxy_port"`
+	ProxyUsername string        `json:"proxy_username"`
+	ProxyPassword string        `json:"proxy_password"`
+
+	WebProxyPorts []int64 `json:"web_proxy_ports"` // 允许通过 Web 反向代理访问的端口
 }
 
 // UpdateServerRequest 更新服务器请求
@@ -64,9 +94,23 @@ type UpdateServerRequest struct {
 	AuthMethod  *AuthMethod `json:"auth_method"`
 	Password    *string     `json:"password"`
 	PrivateKey  *string     `json:"private_key"`
+	Passphrase  *string     `json:"passphrase"` // 空字符串表示清除保存的口令
+	Certificate *string     `json:"certificate"`
 	Group       *string     `json:"group"`
 	Tags        *[]string   `json:"tags"`
 	Description *string     `json:"description"`
+
+	RecordSession *bool   `json:"record_session"`
+	JumpServerID  *string `json:"jump_server_id"` // 空字符串表示改为直连
+
+	// 出站代理（ProxyType 为空字符串时改为沿用全局设置）
+	ProxyType     *netproxy.Type `json:"proxy_type"`
+	ProxyHost     *string        `json:"proxy_host"`
+	ProxyPort     *int           `json:"proxy_port"`
+	ProxyUsername *string        `json:"proxy_username"`
+	ProxyPassword *string        `json:"proxy_password"`
+
+	WebProxyPorts *[]int64 `json:"web_proxy_ports"` // 空数组表示禁止 Web 反向代理
 }
 
 // ServerStatistics 服务器统计
@@ -105,12 +149,42 @@ func (s *serverService) Create(ctx context.Context, userID uuid.UUID, req *Creat
 	}
 
 	// 验证认证方式
+	if !req.AuthMethod.Valid() {
+		return nil, fmt.Errorf("unsupported auth method: %s", req.AuthMethod)
+	}
 	if req.AuthMethod == AuthMethodPassword && req.Password == "" {
 		return nil, errors.New("password is required for password authentication")
 	}
-	if req.AuthMethod == AuthMethodKey && req.PrivateKey == "" {
+	if (req.AuthMethod == AuthMethodKey || req.AuthMethod == AuthMethodCertificate) && req.PrivateKey == "" {
 		return nil, errors.New("private_key is required for key authentication")
 	}
+	if req.AuthMethod == AuthMethodCertificate {
+		if _, err := ParseUserCertificate(req.Certificate); err != nil {
+			return nil, err
+		}
+	}
+	if req.PrivateKey != "" {
+		if err := checkPrivateKey(req.PrivateKey, req.Passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	// 验证代理
+	proxy := netproxy.Config{Type: req.ProxyType, Host: req.ProxyHost, Port: req.ProxyPort}
+	if err := proxy.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+
+	if err := validateWebProxyPorts(req.WebProxyPorts); err != nil {
+		return nil, err
+	}
+
+	// 验证跳板机
+	if req.JumpServerID != nil {
+		if err := s.validateJumpServer(ctx, userID, uuid.Nil, *req.JumpServerID); err != nil {
+			return nil, err
+		}
+	}
 
 	// 创建服务器
 	server := &Server{
@@ -120,10 +194,28 @@ func (s *serverService) Create(ctx context.Context, userID uuid.UUID, req *Creat
 		Port:        req.Port,
 		Username:    req.Username,
 		AuthMethod:  req.AuthMethod,
+		Certificate: req.Certificate,
 		Group:       req.Group,
 		Tags:        req.Tags,
 		Description: req.Description,
 		Status:      StatusUnknown,
+
+		RecordSession: req.RecordSession,
+		JumpServerID:  req.JumpServerID,
+		ProxyType:     req.ProxyType,
+		ProxyHost:     req.ProxyHost,
+		ProxyPort:     req.ProxyPort,
+		ProxyUsername: req.ProxyUsername,
+		WebProxyPorts: req.WebProxyPorts,
+	}
+
+	// 加密代理密码
+	if req.ProxyPassword != "" {
+		encrypted, err := s.encryptor.Encrypt(req.ProxyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt proxy password: %w", err)
+		}
+		server.ProxyPassword = encrypted
 	}
 
 	// 加密密码
@@ -144,6 +236,15 @@ func (s *serverService) Create(ctx context.Context, userID uuid.UUID, req *Creat
 		server.PrivateKey = encrypted
 	}
 
+	// 加密私钥口令
+	if req.Passphrase != "" {
+		encrypted, err := s.encryptor.Encrypt(req.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt passphrase: %w", err)
+		}
+		server.Passphrase = encrypted
+	}
+
 	// 保存到数据库
 	if err := s.repo.Create(ctx, server); err != nil {
 		return nil, err
@@ -153,7 +254,17 @@ func (s *serverService) Create(ctx context.Context, userID uuid.UUID, req *Creat
 }
 
 func (s *serverService) GetByID(ctx context.Context, userID, serverID uuid.UUID) (*Server, error) {
-	return s.repo.FindByUserIDAndID(ctx, userID, serverID)
+	server, err := s.repo.FindByUserIDAndID(ctx, userID, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加载跳板机链，建立 SSH 连接时依次经过
+	if err := s.loadJumpChain(ctx, userID, server); err != nil {
+		return nil, err
+	}
+
+	return server, nil
 }
 
 func (s *serverService) List(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*Server, int64, error) {
@@ -181,8 +292,19 @@ func (s *serverService) Update(ctx context.Context, userID, serverID uuid.UUID,
 		server.Username = *req.Username
 	}
 	if req.AuthMethod != nil {
+		if !req.AuthMethod.Valid() {
+			return nil, fmt.Errorf("unsupported auth method: %s", *req.AuthMethod)
+		}
 		server.AuthMethod = *req.AuthMethod
 	}
+	if req.Certificate != nil {
+		server.Certificate = *req.Certificate
+	}
+	if server.AuthMethod == AuthMethodCertificate {
+		if _, err := ParseUserCertificate(server.Certificate); err != nil {
+			return nil, err
+		}
+	}
 	if req.Group != nil {
 		server.Group = *req.Group
 	}
@@ -192,6 +314,23 @@ func (s *serverService) Update(ctx context.Context, userID, serverID uuid.UUID,
 	if req.Description != nil {
 		server.Description = *req.Description
 	}
+	if req.RecordSession != nil {
+		server.RecordSession = req.RecordSession
+	}
+	if req.JumpServerID != nil {
+		if *req.JumpServerID == "" {
+			server.JumpServerID = nil
+		} else {
+			jumpID, err := uuid.Parse(*req.JumpServerID)
+			if err != nil {
+				return nil, ErrInvalidJumpServer
+			}
+			if err := s.validateJumpServer(ctx, userID, server.ID, jumpID); err != nil {
+				return nil, err
+			}
+			server.JumpServerID = &jumpID
+		}
+	}
 
 	// 更新密码
 	if req.Password != nil {
@@ -206,6 +345,43 @@ func (s *serverService) Update(ctx context.Context, userID, serverID uuid.UUID,
 		}
 	}
 
+	// 更新代理
+	if req.ProxyType != nil {
+		server.ProxyType = *req.ProxyType
+	}
+	if req.ProxyHost != nil {
+		server.ProxyHost = *req.ProxyHost
+	}
+	if req.ProxyPort != nil {
+		server.ProxyPort = *req.ProxyPort
+	}
+	if req.ProxyUsername != nil {
+		server.ProxyUsername = *req.ProxyUsername
+	}
+	proxy := netproxy.Config{Type: server.ProxyType, Host: server.ProxyHost, Port: server.ProxyPort}
+	if err := proxy.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+	if req.ProxyPassword != nil {
+		if *req.ProxyPassword == "" {
+			server.ProxyPassword = ""
+		} else {
+			encrypted, err := s.encryptor.Encrypt(*req.ProxyPassword)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt proxy password: %w", err)
+			}
+			server.ProxyPassword = encrypted
+		}
+	}
+
+	// 更新 Web 反向代理端口
+	if req.WebProxyPorts != nil {
+		if err := validateWebProxyPorts(*req.WebProxyPorts); err != nil {
+			return nil, err
+		}
+		server.WebProxyPorts = *req.WebProxyPorts
+	}
+
 	// 更新私钥
 	if req.PrivateKey != nil {
 		if *req.PrivateKey == "" {
@@ -219,6 +395,26 @@ func (s *serverService) Update(ctx context.Context, userID, serverID uuid.UUID,
 		}
 	}
 
+	// 更新私钥口令
+	if req.Passphrase != nil {
+		if *req.Passphrase == "" {
+			server.Passphrase = ""
+		} else {
+			encrypted, err := s.encryptor.Encrypt(*req.Passphrase)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt passphrase: %w", err)
+			}
+			server.Passphrase = encrypted
+		}
+	}
+
+	// 私钥或口令变更时验证口令能否解密私钥
+	if (req.PrivateKey != nil || req.Passphrase != nil) && server.PrivateKey != "" {
+		if err := s.checkStoredPrivateKey(server); err != nil {
+			return nil, err
+		}
+	}
+
 	// 保存更新
 	if err := s.repo.Update(ctx, server); err != nil {
 		return nil, err
@@ -233,6 +429,15 @@ func (s *serverService) Delete(ctx context.Context, userID, serverID uuid.UUID)
 		return err
 	}
 
+	// 仍被其他服务器用作跳板机时不允许删除
+	count, err := s.repo.CountByJumpServerID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrJumpServerInUse
+	}
+
 	return s.repo.Delete(ctx, serverID)
 }
 
@@ -303,9 +508,107 @@ func (s *serverService) ReorderServers(ctx context.Context, userID uuid.UUID, se
 	return s.repo.UpdateSortOrders(ctx, userID, orders)
 }
 
+// validateJumpServer 验证跳板机属于同一用户，且跳板机链不经过服务器自身、长度不超过限制
+// serverID 为 uuid.Nil 时表示新建服务器
+func (s *serverService) validateJumpServer(ctx context.Context, userID, serverID, jumpServerID uuid.UUID) error {
+	id := jumpServerID
+	for hops := 1; ; hops++ {
+		if id == serverID {
+			return ErrJumpChainCycle
+		}
+		if hops > maxJumpHops {
+			return ErrJumpChainTooLong
+		}
+
+		jump, err := s.repo.FindByUserIDAndID(ctx, userID, id)
+		if err != nil {
+			if errors.Is(err, ErrServerNotFound) {
+				return ErrInvalidJumpServer
+			}
+			return err
+		}
+		if jump.JumpServerID == nil {
+			return nil
+		}
+		id = *jump.JumpServerID
+	}
+}
+
+// validateWebProxyPorts 验证 Web 反向代理允许的端口
+func validateWebProxyPorts(ports []int64) error {
+	for _, port := range ports {
+		if port < 1 || port > 65535 {
+			return ErrInvalidWebPorts
+		}
+	}
+	return nil
+}
+
+// checkStoredPrivateKey 解密已保存的私钥和口令并验证
+func (s *serverService) checkStoredPrivateKey(server *Server) error {
+	privateKey, err := s.encryptor.Decrypt(server.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	var passphrase string
+	if server.Passphrase != "" {
+		if passphrase, err = s.encryptor.Decrypt(server.Passphrase); err != nil {
+			return fmt.Errorf("failed to decrypt passphrase: %w", err)
+		}
+	}
+
+	return checkPrivateKey(privateKey, passphrase)
+}
+
+// checkPrivateKey 验证私钥格式，私钥有口令且提供了口令时验证口令是否正确
+// 有口令的私钥可以不保存口令，连接时再向用户询问
+func checkPrivateKey(privateKey, passphrase string) error {
+	_, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err == nil {
+		return nil
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	if passphrase == "" {
+		return nil
+	}
+	if _, err := ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase)); err != nil {
+		return ErrWrongPassphrase
+	}
+	return nil
+}
+
+// loadJumpChain 依次加载服务器的跳板机（JumpServer 字段）
+func (s *serverService) loadJumpChain(ctx context.Context, userID uuid.UUID, server *Server) error {
+	visited := map[uuid.UUID]bool{server.ID: true}
+	for current := server; current.JumpServerID != nil; current = current.JumpServer {
+		if visited[*current.JumpServerID] {
+			return ErrJumpChainCycle
+		}
+		if len(visited) > maxJumpHops {
+			return ErrJumpChainTooLong
+		}
+		visited[*current.JumpServerID] = true
+
+		jump, err := s.repo.FindByUserIDAndID(ctx, userID, *current.JumpServerID)
+		if err != nil {
+			if errors.Is(err, ErrServerNotFound) {
+				return fmt.Errorf("jump server of %s: %w", current.Host, ErrInvalidJumpServer)
+			}
+			return err
+		}
+		current.JumpServer = jump
+	}
+	return nil
+}
+
 // Helper function to check TCP connectivity
 func checkTCPConnection(host string, port int, timeout time.Duration) error {
-	addr := fmt.Sprintf("%s:%d", host, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", addr, timeout)
 	if err != nil {
 		return err
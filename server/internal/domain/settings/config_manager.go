@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/easyssh/server/internal/pkg/netproxy"
 )
 
 // ConfigManager 配置管理器，提供带缓存的配置读取功能
@@ -133,3 +135,37 @@ func (m *ConfigManager) GetCookieConfig(ctx context.Context) (*CookieConfig, err
 	m.setToCache(cacheKey, config)
 	return config, nil
 }
+
+// GetRecordingConfig 获取终端录像配置（带缓存）
+func (m *ConfigManager) GetRecordingConfig(ctx context.Context) (*RecordingConfig, error) {
+	const cacheKey = "recording_config"
+
+	if cached, found := m.getFromCache(cacheKey); found {
+		return cached.(*RecordingConfig), nil
+	}
+
+	config, err := m.service.GetRecordingConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.setToCache(cacheKey, config)
+	return config, nil
+}
+
+// GetProxyConfig 获取 SSH 出站代理配置（带缓存）
+func (m *ConfigManager) GetProxyConfig(ctx context.Context) (*netproxy.Config, error) {
+	const cacheKey = "proxy_config"
+
+	if cached, found := m.getFromCache(cacheKey); found {
+		return cached.(*netproxy.Config), nil
+	}
+
+	config, err := m.service.GetProxyConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.setToCache(cacheKey, config)
+	return config, nil
+}
@@ -76,7 +76,7 @@ const (
 	KeyTabMaxTabs         = "tabsession.max_tabs"
 	KeyTabInactiveMinutes = "tabsession.inactive_minutes"
 	KeyTabHibernate       = "tabsession.hibernate"
-	KeySessionTimeout     = "tabsession.session_timeout"     // 会话超时时间（分钟）
+	KeySessionTimeout     = "tabsession.session_timeout"     // 无操作自动退出登录的时间（分钟，仅前端使用）
 	KeyRememberLogin      = "tabsession.remember_login"      // 是否允许记住登录状态
 )
 
@@ -99,6 +99,22 @@ const (
 	KeyCookieDomain = "cookie.domain" // Cookie 域名
 )
 
+// 终端录像配置相关的键名
+const (
+	KeyRecordingEnabled       = "recording.enabled"        // 是否录制终端会话（全局开关，可被服务器级设置覆盖）
+	KeyRecordingRecordInput   = "recording.record_input"   // 是否同时录制用户输入
+	KeyRecordingRetentionDays = "recording.retention_days" // 录像保留天数（0 表示永久保留）
+)
+
+// SSH 出站代理配置相关的键名
+const (
+	KeyProxyType     = "proxy.type"     // 代理类型（none/socks5/http）
+	KeyProxyHost     = "proxy.host"     // 代理服务器地址
+	KeyProxyPort     = "proxy.port"     // 代理服务器端口
+	KeyProxyUsername = "proxy.username" // 代理认证用户名
+	KeyProxyPassword = "proxy.password" // 代理认证密码
+)
+
 // SMTPConfig SMTP 配置结构
 type SMTPConfig struct {
 	Enabled   bool   `json:"enabled"`
@@ -159,7 +175,7 @@ type TabSessionConfig struct {
 	MaxTabs         int  `json:"max_tabs"`          // 最大标签页数
 	InactiveMinutes int  `json:"inactive_minutes"`  // 非活动断开提醒时间（分钟）
 	Hibernate       bool `json:"hibernate"`         // 是否启用后台标签页休眠
-	SessionTimeout  int  `json:"session_timeout"`   // 会话超时时间（分钟）
+	SessionTimeout  int  `json:"session_timeout"`   // 无操作自动退出登录的时间（分钟，仅前端使用）
 	RememberLogin   bool `json:"remember_login"`    // 是否允许记住登录状态
 }
 
@@ -181,3 +197,10 @@ type CookieConfig struct {
 	Secure bool   `json:"secure"` // Cookie Secure 标志
 	Domain string `json:"domain"` // Cookie 域名
 }
+
+// RecordingConfig 终端录像配置结构
+type RecordingConfig struct {
+	Enabled       bool `json:"enabled"`        // 是否录制终端会话
+	RecordInput   bool `json:"record_input"`   // 是否录制用户输入（可能包含密码等敏感信息）
+	RetentionDays int  `json:"retention_days"` // 录像保留天数（0 表示永久保留）
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/easyssh/server/internal/domain/notification"
+	"github.com/easyssh/server/internal/pkg/netproxy"
 )
 
 // Service 系统设置服务接口
@@ -38,6 +39,9 @@ type Service interface {
 	SaveWeComConfig(ctx context.Context, config *WeComConfig) error
 	TestWeComConnection(ctx context.Context, config *WeComConfig) error
 
+	// 告警通知（发送到所有已启用的通知渠道）
+	SendAlert(ctx context.Context, event, title, text string, data map[string]interface{}) error
+
 	// 系统通用配置
 	GetSystemConfig(ctx context.Context) (*SystemConfig, error)
 	SaveSystemConfig(ctx context.Context, config *SystemConfig) error
@@ -53,6 +57,14 @@ type Service interface {
 	// Cookie 配置
 	GetCookieConfig(ctx context.Context) (*CookieConfig, error)
 	SaveCookieConfig(ctx context.Context, config *CookieConfig) error
+
+	// 终端录像配置
+	GetRecordingConfig(ctx context.Context) (*RecordingConfig, error)
+	SaveRecordingConfig(ctx context.Context, config *RecordingConfig) error
+
+	// SSH 出站代理配置
+	GetProxyConfig(ctx context.Context) (*netproxy.Config, error)
+	SaveProxyConfig(ctx context.Context, config *netproxy.Config) error
 }
 
 type service struct {
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/easyssh/server/internal/pkg/netproxy"
 )
 
 // GetCORSConfig 获取 CORS 配置
@@ -163,3 +165,117 @@ func (s *service) SaveCookieConfig(ctx context.Context, config *CookieConfig) er
 
 	return nil
 }
+
+// GetRecordingConfig 获取终端录像配置
+func (s *service) GetRecordingConfig(ctx context.Context) (*RecordingConfig, error) {
+	config := &RecordingConfig{
+		Enabled:       false, // 默认不录制
+		RecordInput:   false, // 默认不录制输入
+		RetentionDays: 90,    // 默认保留 90 天
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyRecordingEnabled); err == nil && setting != nil && setting.Value != "" {
+		config.Enabled = setting.Value == "true"
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyRecordingRecordInput); err == nil && setting != nil && setting.Value != "" {
+		config.RecordInput = setting.Value == "true"
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyRecordingRetentionDays); err == nil && setting != nil && setting.Value != "" {
+		if v, err := strconv.Atoi(setting.Value); err == nil {
+			config.RetentionDays = v
+		}
+	}
+
+	return config, nil
+}
+
+// SaveRecordingConfig 保存终端录像配置
+func (s *service) SaveRecordingConfig(ctx context.Context, config *RecordingConfig) error {
+	// 验证配置
+	if config.RetentionDays < 0 || config.RetentionDays > 3650 {
+		return fmt.Errorf("recording retention days must be between 0 and 3650")
+	}
+
+	// 保存到数据库
+	if err := s.repo.Set(ctx, KeyRecordingEnabled, strconv.FormatBool(config.Enabled), "recording", false); err != nil {
+		return err
+	}
+	if err := s.repo.Set(ctx, KeyRecordingRecordInput, strconv.FormatBool(config.RecordInput), "recording", false); err != nil {
+		return err
+	}
+	if err := s.repo.Set(ctx, KeyRecordingRetentionDays, strconv.Itoa(config.RetentionDays), "recording", false); err != nil {
+		return err
+	}
+
+	// 清除缓存
+	if s.configManager != nil {
+		s.configManager.InvalidateCache("recording_config")
+	}
+
+	return nil
+}
+
+// GetProxyConfig 获取 SSH 出站代理配置（全局，服务器可单独覆盖）
+func (s *service) GetProxyConfig(ctx context.Context) (*netproxy.Config, error) {
+	config := &netproxy.Config{
+		Type: netproxy.TypeNone, // 默认直连
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyProxyType); err == nil && setting != nil && setting.Value != "" {
+		config.Type = netproxy.Type(setting.Value)
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyProxyHost); err == nil && setting != nil {
+		config.Host = setting.Value
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyProxyPort); err == nil && setting != nil && setting.Value != "" {
+		if v, err := strconv.Atoi(setting.Value); err == nil {
+			config.Port = v
+		}
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyProxyUsername); err == nil && setting != nil {
+		config.Username = setting.Value
+	}
+
+	if setting, err := s.repo.GetByKey(ctx, KeyProxyPassword); err == nil && setting != nil {
+		config.Password = setting.Value
+	}
+
+	return config, nil
+}
+
+// SaveProxyConfig 保存 SSH 出站代理配置
+func (s *service) SaveProxyConfig(ctx context.Context, config *netproxy.Config) error {
+	// 验证配置
+	if config.Type == "" {
+		config.Type = netproxy.TypeNone
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	// 保存到数据库
+	values := map[string]string{
+		KeyProxyType:     string(config.Type),
+		KeyProxyHost:     config.Host,
+		KeyProxyPort:     strconv.Itoa(config.Port),
+		KeyProxyUsername: config.Username,
+		KeyProxyPassword: config.Password,
+	}
+	for key, value := range values {
+		if err := s.repo.Set(ctx, key, value, "proxy", false); err != nil {
+			return err
+		}
+	}
+
+	// 清除缓存
+	if s.configManager != nil {
+		s.configManager.InvalidateCache("proxy_config")
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/easyssh/server/internal/domain/notification"
+)
+
+// SendAlert 向所有已启用的通知渠道（Webhook、钉钉、企业微信）发送告警
+// text 为 Markdown 格式，Webhook 发送 event 和 data；未启用任何渠道时不做任何事
+func (s *service) SendAlert(ctx context.Context, event, title, text string, data map[string]interface{}) error {
+	var errs []error
+
+	if config, err := s.GetWebhookConfig(ctx); err == nil && config != nil && config.Enabled {
+		webhook, err := notification.NewWebhookService(&notification.WebhookConfig{
+			URL:    config.URL,
+			Secret: config.Secret,
+			Method: config.Method,
+		})
+		if err == nil {
+			err = webhook.SendNotification(ctx, event, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+
+	if config, err := s.GetDingTalkConfig(ctx); err == nil && config != nil && config.Enabled {
+		dingTalk, err := notification.NewDingTalkService(&notification.DingTalkConfig{
+			WebhookURL: config.WebhookURL,
+			Secret:     config.Secret,
+		})
+		if err == nil {
+			err = dingTalk.SendMarkdownMessage(ctx, title, text)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dingtalk: %w", err))
+		}
+	}
+
+	if config, err := s.GetWeComConfig(ctx); err == nil && config != nil && config.Enabled {
+		weCom, err := notification.NewWeComService(&notification.WeComConfig{
+			WebhookURL: config.WebhookURL,
+		})
+		if err == nil {
+			err = weCom.SendMarkdownMessage(ctx, "### "+title+"\n"+text)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wechat work: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -20,12 +20,15 @@ import (
 type Client struct {
 	sftpClient *sftp.Client
 	sshClient  *sshDomain.Client
+	lease      *sshDomain.Lease // 共享 SSH 连接的租用，关闭时释放
 	serverID   string
 }
 
-// NewClient 创建 SFTP 客户端
-func NewClient(sshClient *sshDomain.Client, srv *server.Server) (*Client, error) {
+// NewClient 在共享的 SSH 连接上打开 SFTP 通道，创建失败时释放租用
+func NewClient(lease *sshDomain.Lease, srv *server.Server) (*Client, error) {
+	sshClient := lease.Client()
 	if !sshClient.IsConnected() {
+		lease.Discard()
 		return nil, fmt.Errorf("SSH client not connected")
 	}
 
@@ -34,22 +37,28 @@ func NewClient(sshClient *sshDomain.Client, srv *server.Server) (*Client, error)
 	// 这里假设我们可以通过某种方式获取
 	sftpClient, err := sftp.NewClient(sshClient.GetRawConnection())
 	if err != nil {
+		lease.Release()
 		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
 	}
 
 	return &Client{
 		sftpClient: sftpClient,
 		sshClient:  sshClient,
+		lease:      lease,
 		serverID:   srv.ID.String(),
 	}, nil
 }
 
-// Close 关闭 SFTP 连接
+// Close 关闭 SFTP 通道并释放 SSH 连接租用（SSH 连接由连接管理器复用）
 func (c *Client) Close() error {
+	var err error
 	if c.sftpClient != nil {
-		return c.sftpClient.Close()
+		err = c.sftpClient.Close()
 	}
-	return nil
+	if c.lease != nil {
+		c.lease.Release()
+	}
+	return err
 }
 
 // ListDirectory 列出目录
@@ -0,0 +1,308 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// scrollbackSize 每个会话保留的最近输出大小（重新连接时回放给客户端）
+	scrollbackSize = 256 * 1024
+	// attachmentQueueSize 每个客户端等待推送的消息数上限，超出说明客户端跟不上输出
+	attachmentQueueSize = 256
+)
+
+// CloseReasonSlowClient 客户端接收输出过慢被断开
+const CloseReasonSlowClient = "client is too slow to keep up with terminal output"
+
+var ErrSessionNotStarted = errors.New("session shell not started")
+
+// SessionHooks 会话 I/O 回调（均可为空）
+// 回调中的 data 仅在调用期间有效，需要保留时必须复制
+type SessionHooks struct {
+	OnOutput func(data []byte)    // 收到远端输出（包括无客户端连接期间的输出）
+	OnInput  func(data []byte)    // 输入已写入远端
+	OnResize func(cols, rows int) // 终端尺寸已调整
+	OnEnd    func(reason string)  // 会话结束（只调用一次）
+}
+
+// Attachment 连接到会话的客户端（一个 WebSocket 连接）
+// Output、IdleWarning、Ended 由每个客户端独立的推送协程按顺序调用，慢客户端不会阻塞会话和其他客户端；
+// 等待推送的消息超过上限时客户端会被 Kick 断开
+type Attachment struct {
+	ID       string
+	UserID   string
+	Mode     ShareMode // 访问模式（所有者为 interactive）
+	JoinedAt time.Time
+	Output   func(data []byte)   // 推送终端输出，不能在回调中调用 Detach
+	Ended    func(reason string) // 会话结束通知（在已排队的输出推送完之后调用）
+	Kick     func(reason string) // 被强制断开（如撤销共享、接收过慢），客户端断开后需自行调用 Detach
+
+	IdleWarning func(remaining time.Duration) // 会话即将因空闲被关闭（可为空）
+
+	// 推送队列（由 Session 在 Attach 时创建，ioMu 保护 lagging）
+	queue    chan func()
+	detached chan struct{} // Detach 时关闭，推送协程丢弃剩余消息后退出
+	drained  chan struct{} // 推送协程退出时关闭
+	lagging  bool          // 队列已满，已被断开，不再排队
+}
+
+// deliver 推送协程：依次执行队列中的消息，直到队列关闭（会话结束）或客户端断开
+func (a *Attachment) deliver() {
+	defer close(a.drained)
+	for {
+		select {
+		case send, ok := <-a.queue:
+			if !ok {
+				return
+			}
+			send()
+		case <-a.detached:
+			return
+		}
+	}
+}
+
+// enqueue 将消息加入推送队列（调用方需持有 ioMu）
+// 队列已满时断开客户端，而不是等待它（会阻塞会话输出和其他客户端）
+func (a *Attachment) enqueue(send func()) {
+	if a.lagging {
+		return
+	}
+	select {
+	case a.queue <- send:
+	default:
+		a.lagging = true
+		if a.Kick != nil {
+			go a.Kick(CloseReasonSlowClient)
+		}
+	}
+}
+
+// Start 启动会话的输出转发，输出会写入回滚缓冲区并推送给所有已连接的客户端
+// 客户端断开后会话继续运行，直到远端退出或被关闭
+func (s *Session) Start(stdin io.Writer, stdout, stderr io.Reader, hooks SessionHooks) {
+	s.mu.Lock()
+	s.stdin = stdin
+	s.hooks = hooks
+	s.mu.Unlock()
+
+	go s.pump(stdout, true)
+	go s.pump(stderr, false)
+}
+
+// Done 返回在会话结束时关闭的通道
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Write 向远端写入输入
+func (s *Session) Write(p []byte) (int, error) {
+	s.mu.RLock()
+	stdin, onInput := s.stdin, s.hooks.OnInput
+	s.mu.RUnlock()
+
+	if stdin == nil {
+		return 0, ErrSessionNotStarted
+	}
+
+	n, err := stdin.Write(p)
+	if n > 0 {
+		s.touch()
+		if onInput != nil {
+			onInput(p[:n])
+		}
+	}
+	return n, err
+}
+
+// Attach 将客户端连接到会话，返回回滚缓冲区中的最近输出
+// 返回的输出与之后推送的输出之间不会重复或遗漏
+func (s *Session) Attach(a *Attachment) ([]byte, error) {
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+
+	s.mu.Lock()
+	if s.ended || s.Status == SessionStatusClosed {
+		s.mu.Unlock()
+		return nil, ErrSessionClosed
+	}
+	// 取消正在进行的断开保持计时
+	s.detachGen++
+	s.detachedAt = nil
+	s.mu.Unlock()
+
+	a.queue = make(chan func(), attachmentQueueSize)
+	a.detached = make(chan struct{})
+	a.drained = make(chan struct{})
+	go a.deliver()
+
+	s.attachments[a.ID] = a
+	return s.scrollback.Bytes(), nil
+}
+
+// Detach 断开客户端；最后一个客户端断开后会话在 grace 时间内保持运行等待重新连接，
+// grace 不大于 0 时立即关闭会话
+func (s *Session) Detach(id string, grace time.Duration) {
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+
+	a, exists := s.attachments[id]
+	if !exists {
+		return
+	}
+	delete(s.attachments, id)
+	close(a.detached)
+	if len(s.attachments) > 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+	if grace <= 0 {
+		go s.CloseWithReason(CloseReasonClientGone)
+		return
+	}
+
+	now := time.Now()
+	s.detachedAt = &now
+	s.detachGen++
+	gen := s.detachGen
+	time.AfterFunc(grace, func() {
+		s.expireDetached(gen)
+	})
+}
+
+// AttachmentCount 获取当前连接的客户端数量
+func (s *Session) AttachmentCount() int {
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	return len(s.attachments)
+}
+
+// expireDetached 断开保持时间到期后关闭会话（期间重新连接过则忽略）
+func (s *Session) expireDetached(gen uint64) {
+	s.ioMu.Lock()
+	s.mu.RLock()
+	expired := len(s.attachments) == 0 && s.detachGen == gen && !s.ended
+	s.mu.RUnlock()
+	s.ioMu.Unlock()
+
+	if expired {
+		s.CloseWithReason(CloseReasonDetachExpired)
+	}
+}
+
+// pump 读取远端输出并分发，主输出流结束时结束会话
+func (s *Session) pump(r io.Reader, primary bool) {
+	buf := make([]byte, 32768)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			s.broadcast(buf[:n])
+		}
+		if err != nil {
+			if primary {
+				reason := "remote session ended"
+				if err != io.EOF {
+					reason = fmt.Sprintf("ssh read error: %v", err)
+				}
+				s.end(reason)
+			}
+			return
+		}
+	}
+}
+
+// broadcast 将输出写入回滚缓冲区并推送给所有客户端
+func (s *Session) broadcast(data []byte) {
+	s.touch()
+
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+
+	if s.hooks.OnOutput != nil {
+		s.hooks.OnOutput(data)
+	}
+	s.scrollback.Write(data)
+	s.commandLine.trackOutput(data)
+	if len(s.attachments) == 0 {
+		return
+	}
+
+	// data 在返回后会被复用，所有客户端共享同一份副本
+	output := append([]byte(nil), data...)
+	for _, a := range s.attachments {
+		a.enqueue(func() { a.Output(output) })
+	}
+}
+
+// touch 记录输入输出活动，重新开始空闲计时
+func (s *Session) touch() {
+	s.mu.Lock()
+	s.lastActivity = time.Now()
+	s.idleWarned = false
+	s.mu.Unlock()
+}
+
+// warnIdle 通知所有客户端会话即将因空闲被关闭（每次空闲只通知一次）
+func (s *Session) warnIdle(remaining time.Duration) {
+	s.mu.Lock()
+	if s.idleWarned || s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.idleWarned = true
+	s.mu.Unlock()
+
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+	for _, a := range s.attachments {
+		if a.IdleWarning != nil {
+			a.enqueue(func() { a.IdleWarning(remaining) })
+		}
+	}
+}
+
+// end 结束会话并通知所有客户端（服务端主动关闭时以关闭原因为准）
+func (s *Session) end(reason string) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	if s.closeReason != "" {
+		reason = s.closeReason
+	}
+	s.detachGen++
+	close(s.done)
+	s.mu.Unlock()
+
+	s.ioMu.Lock()
+	attachments := s.attachments
+	s.attachments = make(map[string]*Attachment)
+	// 不再有新消息排队，关闭队列让推送协程发送完剩余输出后退出
+	for _, a := range attachments {
+		close(a.queue)
+	}
+	s.ioMu.Unlock()
+
+	for _, a := range attachments {
+		if a.Ended != nil {
+			go func(a *Attachment) {
+				<-a.drained
+				a.Ended(reason)
+			}(a)
+		}
+	}
+	if s.hooks.OnEnd != nil {
+		s.hooks.OnEnd(reason)
+	}
+}
@@ -0,0 +1,222 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrInteractiveAuthRequired = errors.New("keyboard-interactive authentication requires user input")
+	ErrCertificateKeyMismatch  = errors.New("certificate does not match private key")
+	ErrPassphraseRequired      = errors.New("private key is encrypted and no passphrase is available")
+	ErrIncorrectPassphrase     = errors.New("incorrect private key passphrase")
+)
+
+// 需要用户回答的问题类型
+const (
+	ChallengeKeyboardInteractive = "keyboard_interactive" // 服务器发起的键盘交互认证
+	ChallengePassphrase          = "passphrase"           // 私钥口令（未保存口令时连接时询问，不会保存）
+)
+
+// maxPassphraseAttempts 连接时询问私钥口令的最大次数
+const maxPassphraseAttempts = 3
+
+// KeyboardInteractiveChallenge 需要用户回答的一轮认证问题
+type KeyboardInteractiveChallenge struct {
+	Kind        string   // 问题类型（ChallengeKeyboardInteractive/ChallengePassphrase）
+	Host        string   // 发起认证的服务器（经由跳板机时可能是跳板机）
+	User        string   // 登录用户名
+	Name        string   // 服务器提供的标题
+	Instruction string   // 服务器提供的说明
+	Questions   []string // 问题
+	Echos       []bool   // 回答是否可以回显（false 表示密码类输入）
+}
+
+// KeyboardInteractiveHandler 将认证问题转发给用户并返回回答（与 Questions 一一对应）
+type KeyboardInteractiveHandler func(challenge KeyboardInteractiveChallenge) ([]string, error)
+
+// SetKeyboardInteractiveHandler 设置认证问题（键盘交互认证、私钥口令）的转发，需在 Connect 之前调用
+// 未设置时（如监控、批量任务等后台连接）只能使用保存的密码和口令
+func (c *Client) SetKeyboardInteractiveHandler(handler KeyboardInteractiveHandler) {
+	c.interactive = handler
+}
+
+// authMethods 按服务器的认证方式生成 SSH 认证方法
+func (c *Client) authMethods(srv *server.Server, encryptor *crypto.Encryptor) ([]ssh.AuthMethod, error) {
+	switch srv.AuthMethod {
+	case server.AuthMethodPassword:
+		password, err := encryptor.Decrypt(srv.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt password: %w", err)
+		}
+		return []ssh.AuthMethod{ssh.Password(password)}, nil
+
+	case server.AuthMethodKeyboardInteractive:
+		var password string
+		if srv.Password != "" {
+			decrypted, err := encryptor.Decrypt(srv.Password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt password: %w", err)
+			}
+			password = decrypted
+		}
+		return []ssh.AuthMethod{
+			ssh.RetryableAuthMethod(ssh.KeyboardInteractive(c.keyboardInteractive(srv, password)), 3),
+		}, nil
+
+	case server.AuthMethodCertificate:
+		// 连接前检查证书有效期
+		cert, err := server.ParseUserCertificate(srv.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		if err := server.CheckCertificateValidity(cert, time.Now()); err != nil {
+			return nil, err
+		}
+		return []ssh.AuthMethod{ssh.PublicKeysCallback(c.signers(srv, encryptor, func(signer ssh.Signer) (ssh.Signer, error) {
+			return certificateSigner(cert, signer)
+		}))}, nil
+
+	default:
+		return []ssh.AuthMethod{ssh.PublicKeysCallback(c.signers(srv, encryptor, nil))}, nil
+	}
+}
+
+// signers 生成延迟解析私钥的回调：私钥在认证时才解析，此时才能向用户询问口令
+// wrap 不为空时用于包装解析出的私钥（如附加证书），结果会被缓存以便重试时复用
+func (c *Client) signers(srv *server.Server, encryptor *crypto.Encryptor, wrap func(ssh.Signer) (ssh.Signer, error)) func() ([]ssh.Signer, error) {
+	var cached ssh.Signer
+	return func() ([]ssh.Signer, error) {
+		if cached != nil {
+			return []ssh.Signer{cached}, nil
+		}
+
+		signer, err := c.parseSigner(srv, encryptor)
+		if err != nil {
+			return nil, err
+		}
+		if wrap != nil {
+			if signer, err = wrap(signer); err != nil {
+				return nil, err
+			}
+		}
+		cached = signer
+		return []ssh.Signer{signer}, nil
+	}
+}
+
+// parseSigner 解密并解析服务器私钥
+// 私钥有口令时优先使用保存的口令，未保存时通过问题转发向用户询问（不保存）
+func (c *Client) parseSigner(srv *server.Server, encryptor *crypto.Encryptor) (ssh.Signer, error) {
+	privateKey, err := encryptor.Decrypt(srv.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err == nil {
+		return signer, nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	// 使用保存的口令
+	if srv.Passphrase != "" {
+		passphrase, err := encryptor.Decrypt(srv.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key passphrase: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrIncorrectPassphrase, err)
+		}
+		return signer, nil
+	}
+
+	// 向用户询问口令
+	if c.interactive == nil {
+		return nil, ErrPassphraseRequired
+	}
+	for attempt := 1; ; attempt++ {
+		answers, err := c.interactive(KeyboardInteractiveChallenge{
+			Kind:        ChallengePassphrase,
+			Host:        srv.Host,
+			User:        srv.Username,
+			Name:        "Private key passphrase",
+			Instruction: fmt.Sprintf("The private key for %s@%s is encrypted", srv.Username, srv.Host),
+			Questions:   []string{"Passphrase: "},
+			Echos:       []bool{false},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(answers) != 1 {
+			return nil, fmt.Errorf("expected 1 answer, got %d", len(answers))
+		}
+
+		signer, err := ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(answers[0]))
+		if err == nil {
+			return signer, nil
+		}
+		if attempt >= maxPassphraseAttempts {
+			return nil, fmt.Errorf("%w: %v", ErrIncorrectPassphrase, err)
+		}
+	}
+}
+
+// certificateSigner 使用用户证书包装私钥（证书必须与私钥匹配）
+func certificateSigner(cert *ssh.Certificate, signer ssh.Signer) (ssh.Signer, error) {
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, ErrCertificateKeyMismatch
+	}
+
+	return ssh.NewCertSigner(cert, signer)
+}
+
+// keyboardInteractive 生成键盘交互认证回调：
+// 没有问题的轮次直接回答；只有一个密码问题且保存了密码时自动回答；其余转发给用户
+func (c *Client) keyboardInteractive(srv *server.Server, password string) ssh.KeyboardInteractiveChallenge {
+	passwordUsed := false
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+
+		// 保存的密码只自动回答一次，避免密码错误时反复重试
+		if password != "" && !passwordUsed && len(questions) == 1 && !echos[0] &&
+			strings.Contains(strings.ToLower(questions[0]), "password") {
+			passwordUsed = true
+			return []string{password}, nil
+		}
+
+		if c.interactive == nil {
+			return nil, ErrInteractiveAuthRequired
+		}
+
+		answers, err := c.interactive(KeyboardInteractiveChallenge{
+			Kind:        ChallengeKeyboardInteractive,
+			Host:        srv.Host,
+			User:        srv.Username,
+			Name:        name,
+			Instruction: instruction,
+			Questions:   questions,
+			Echos:       echos,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(answers) != len(questions) {
+			return nil, fmt.Errorf("expected %d answers, got %d", len(questions), len(answers))
+		}
+		return answers, nil
+	}
+}
@@ -1,85 +1,156 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"time"
 
 	"github.com/easyssh/server/internal/domain/server"
 	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/easyssh/server/internal/pkg/netproxy"
 	"golang.org/x/crypto/ssh"
 )
 
 // Client SSH 客户端封装
 type Client struct {
-	conn      *ssh.Client
-	serverID  string
-	config    *ssh.ClientConfig
-	connected bool
-	createdAt time.Time
+	conn        *ssh.Client
+	serverID    string
+	config      *ssh.ClientConfig
+	jumps       []jumpHop                  // 依次经过的跳板机（最外层在前）
+	jumpClients []*ssh.Client              // 已建立的跳板机连接，关闭时一并关闭
+	proxy       *netproxy.Config           // 第一跳使用的出站代理（为空时直连）
+	interactive KeyboardInteractiveHandler // 认证问题转发（键盘交互认证、私钥口令），为空时无法询问用户
+	connected   bool
+	createdAt   time.Time
+}
+
+// jumpHop 跳板机连接参数
+type jumpHop struct {
+	addr   string
+	config *ssh.ClientConfig
 }
 
 // NewClient 创建 SSH 客户端
 // hostKeyCallback: 可选的主机密钥验证回调，如果为 nil 则使用不安全的模式（不推荐）
+// 服务器配置了跳板机链（srv.JumpServer）时，连接会依次经过各跳板机，每一跳都使用同一回调验证主机密钥
+// 第一跳（最外层跳板机或服务器本身）按其代理设置经由 SOCKS5/HTTP 代理连接
 func NewClient(srv *server.Server, encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback) (*Client, error) {
-	// 解密认证信息
-	var authMethods []ssh.AuthMethod
+	// 如果没有提供主机密钥验证回调，显式使用不安全的模式（不推荐）
+	if hostKeyCallback == nil {
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	}
 
-	if srv.AuthMethod == server.AuthMethodPassword {
-		password, err := encryptor.Decrypt(srv.Password)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt password: %w", err)
-		}
-		authMethods = append(authMethods, ssh.Password(password))
-	} else {
-		privateKey, err := encryptor.Decrypt(srv.PrivateKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
-		}
+	client := &Client{
+		serverID:  srv.ID.String(),
+		connected: false,
+		createdAt: time.Now(),
+	}
 
-		signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	config, err := client.newClientConfig(srv, encryptor, hostKeyCallback)
+	if err != nil {
+		return nil, err
+	}
+	client.config = config
+
+	chain := srv.JumpChain()
+	first := srv
+	if len(chain) > 0 {
+		first = chain[0]
+	}
+	if client.proxy, err = resolveProxy(first, encryptor); err != nil {
+		return nil, err
+	}
+
+	for _, jump := range chain {
+		jumpConfig, err := client.newClientConfig(jump, encryptor, hostKeyCallback)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse private key: %w", err)
+			return nil, fmt.Errorf("jump server %s: %w", jump.Host, err)
 		}
-		authMethods = append(authMethods, ssh.PublicKeys(signer))
+		client.jumps = append(client.jumps, jumpHop{
+			addr:   net.JoinHostPort(jump.Host, strconv.Itoa(jump.Port)),
+			config: jumpConfig,
+		})
 	}
 
-	// 配置 SSH 客户端
-	// 如果没有提供主机密钥验证回调，显式使用不安全的模式（不推荐）
-	if hostKeyCallback == nil {
-		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	return client, nil
+}
+
+// newClientConfig 解密服务器认证信息并生成 SSH 客户端配置
+func (c *Client) newClientConfig(srv *server.Server, encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback) (*ssh.ClientConfig, error) {
+	authMethods, err := c.authMethods(srv, encryptor)
+	if err != nil {
+		return nil, err
 	}
 
-	config := &ssh.ClientConfig{
+	return &ssh.ClientConfig{
 		User:            srv.Username,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
 		Timeout:         30 * time.Second,
-	}
+	}, nil
+}
 
-	client := &Client{
-		serverID:  srv.ID.String(),
-		config:    config,
-		connected: false,
-		createdAt: time.Now(),
+// Connect 连接到服务器（配置了跳板机时依次经由跳板机转发）
+func (c *Client) Connect(host string, port int) error {
+	var via *ssh.Client
+	var jumpClients []*ssh.Client
+	closeJumps := func() {
+		for i := len(jumpClients) - 1; i >= 0; i-- {
+			jumpClients[i].Close()
+		}
 	}
 
-	return client, nil
-}
+	for _, hop := range c.jumps {
+		jumpClient, err := c.dialHop(via, hop.addr, hop.config)
+		if err != nil {
+			closeJumps()
+			return fmt.Errorf("failed to connect to jump server %s: %w", hop.addr, err)
+		}
+		jumpClients = append(jumpClients, jumpClient)
+		via = jumpClient
+	}
 
-// Connect 连接到服务器
-func (c *Client) Connect(host string, port int) error {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := ssh.Dial("tcp", addr, c.config)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := c.dialHop(via, addr, c.config)
 	if err != nil {
+		closeJumps()
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 
 	c.conn = conn
+	c.jumpClients = jumpClients
 	c.connected = true
 	return nil
 }
 
+// dialHop 建立一跳 SSH 连接：via 为空时直接拨号（配置了代理时经由代理），否则通过 via 的 direct-tcpip 通道连接
+func (c *Client) dialHop(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var conn net.Conn
+	var err error
+	if via == nil {
+		conn, err = netproxy.Dial(c.proxy, addr, config.Timeout)
+	} else {
+		conn, err = via.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 通道和代理连接的握手超时通过关闭连接实现
+	timer := time.AfterFunc(config.Timeout, func() { conn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	timer.Stop()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
 // NewSession 创建新会话
 func (c *Client) NewSession() (*ssh.Session, error) {
 	if !c.connected || c.conn == nil {
@@ -94,11 +165,15 @@ func (c *Client) NewSession() (*ssh.Session, error) {
 	return session, nil
 }
 
-// Close 关闭连接
+// Close 关闭连接（包括跳板机连接）
 func (c *Client) Close() error {
 	if c.conn != nil {
 		c.connected = false
-		return c.conn.Close()
+		err := c.conn.Close()
+		for i := len(c.jumpClients) - 1; i >= 0; i-- {
+			c.jumpClients[i].Close()
+		}
+		return err
 	}
 	return nil
 }
@@ -134,6 +209,30 @@ func (c *Client) ExecuteCommand(cmd string) (string, error) {
 	return string(output), nil
 }
 
+// ExecuteStream 执行命令并将输出实时写入指定 Writer，返回远程退出码
+// stdin 可为 nil；命令正常结束但退出码非零时 err 为 nil，由调用方根据退出码判断
+func (c *Client) ExecuteStream(cmd string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	session, err := c.NewSession()
+	if err != nil {
+		return -1, err
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	if err := session.Run(cmd); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitStatus(), nil
+		}
+		return -1, fmt.Errorf("command execution failed: %w", err)
+	}
+
+	return 0, nil
+}
+
 // CopyTo 复制文件到远程服务器
 func (c *Client) CopyTo(localReader io.Reader, remotePath string, size int64) error {
 	session, err := c.NewSession()
@@ -0,0 +1,49 @@
+package ssh
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/cmdguard"
+)
+
+// CommandLine 会话当前命令行的重建状态（危险命令防护使用）
+// 同一会话的所有客户端共享：一个客户端输入的命令可以由另一个客户端（或重新连接后的客户端）按回车执行
+// 处理输入时需持有锁，保证重建、检查和写入远端的顺序与实际输入一致
+type CommandLine struct {
+	sync.Mutex
+	Buffer  cmdguard.LineBuffer
+	Pending *PendingCommand // 等待确认的命令，期间所有客户端的输入都会被丢弃
+
+	altScreen atomic.Bool // 由输出分发更新，不需要持有锁
+}
+
+// PendingCommand 等待用户确认的命令
+type PendingCommand struct {
+	ID       string
+	ClientID string // 触发确认的客户端，只有它可以确认或取消
+	Command  string
+	Match    *cmdguard.Match
+	Rest     []byte // 回车及其后尚未发送的输入
+	Timer    *time.Timer
+}
+
+// AltScreen 终端是否处于备用屏幕（vim、less 等全屏程序）
+// 该状态来自远端输出，可以被伪造，只能作为提示，不能据此跳过检查
+func (l *CommandLine) AltScreen() bool {
+	return l.altScreen.Load()
+}
+
+// trackOutput 根据终端输出更新备用屏幕状态
+func (l *CommandLine) trackOutput(data []byte) {
+	current := l.altScreen.Load()
+	if next := cmdguard.TrackAltScreen(data, current); next != current {
+		l.altScreen.Store(next)
+	}
+}
+
+// CommandLine 返回会话的命令行重建状态
+func (s *Session) CommandLine() *CommandLine {
+	return &s.commandLine
+}
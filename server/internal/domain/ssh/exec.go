@@ -0,0 +1,144 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// DefaultOutputLimit 非交互执行时每路输出保留的默认上限
+	DefaultOutputLimit = 64 * 1024
+	// DefaultMaxParallel 在多台服务器上并行执行时同时执行的最大服务器数
+	DefaultMaxParallel = 10
+)
+
+var (
+	// ErrExecTimeout 远程执行超时
+	ErrExecTimeout = errors.New("execution timed out")
+	// ErrExecCanceled 远程执行被取消（如服务关闭）
+	ErrExecCanceled = errors.New("execution canceled")
+)
+
+// RemoteExec 一次非交互的远程执行（批量任务、定时任务、脚本执行共用）
+type RemoteExec struct {
+	Command string        // 远程命令（脚本执行时为解释器命令）
+	Stdin   io.Reader     // 可为 nil
+	Stdout  io.Writer     // 可为 nil
+	Stderr  io.Writer     // 可为 nil
+	Timeout time.Duration // 为 0 时只受 ctx 控制
+}
+
+// RunEach 对 count 台服务器调用 run（参数为服务器下标），阻塞直到全部结束
+// maxParallel 为同时执行的最大数量，小于等于 1 时按顺序逐台执行
+func RunEach(count, maxParallel int, run func(i int)) {
+	if maxParallel <= 1 {
+		for i := 0; i < count; i++ {
+			run(i)
+		}
+		return
+	}
+
+	sem := make(chan struct{}, maxParallel)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// RunRemote 建立独立的 SSH 连接执行一条命令，结束后关闭连接，返回远程退出码
+// 超时或 ctx 取消时关闭连接使阻塞中的命令返回，分别返回 ErrExecTimeout 和 ErrExecCanceled
+func RunRemote(ctx context.Context, srv *server.Server, encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback, req *RemoteExec) (int, error) {
+	client, err := NewClient(srv, encryptor, hostKeyCallback)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create ssh client: %w", err)
+	}
+
+	if err := client.Connect(srv.Host, srv.Port); err != nil {
+		return -1, err
+	}
+	defer client.Close()
+
+	runCtx, cancel := ctx, context.CancelFunc(func() {})
+	if req.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, req.Timeout)
+	}
+	defer cancel()
+	go func() {
+		<-runCtx.Done()
+		client.Close()
+	}()
+
+	exitCode, err := client.ExecuteStream(req.Command, req.Stdin, req.Stdout, req.Stderr)
+	if ctx.Err() != nil {
+		return -1, ErrExecCanceled
+	}
+	if runCtx.Err() != nil {
+		return -1, fmt.Errorf("%w after %s", ErrExecTimeout, req.Timeout)
+	}
+	if err != nil {
+		return -1, err
+	}
+	return exitCode, nil
+}
+
+// OutputBuffer 有上限的输出缓冲区，超出上限后丢弃多余内容，可并发写入
+type OutputBuffer struct {
+	mu        sync.Mutex
+	buf       []byte
+	limit     int
+	truncated bool
+}
+
+// NewOutputBuffer 创建输出缓冲区
+func NewOutputBuffer(limit int) *OutputBuffer {
+	return &OutputBuffer{limit: limit}
+}
+
+// Write 实现 io.Writer
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	remaining := b.limit - len(b.buf)
+	if remaining <= 0 {
+		b.truncated = true
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		b.buf = append(b.buf, p[:remaining]...)
+		b.truncated = true
+		return len(p), nil
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+// String 返回已保留的输出内容（清理无效 UTF-8 和 NUL 字符，便于写入数据库）
+func (b *OutputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.ReplaceAll(strings.ToValidUTF8(string(b.buf), "\uFFFD"), "\x00", "")
+}
+
+// Truncated 是否发生过截断
+func (b *OutputBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
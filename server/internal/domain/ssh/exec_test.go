@@ -0,0 +1,63 @@
+package ssh
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunEachSequential(t *testing.T) {
+	var order []int
+	RunEach(5, 1, func(i int) {
+		order = append(order, i)
+	})
+
+	if len(order) != 5 {
+		t.Fatalf("RunEach() ran %d times, want 5", len(order))
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("RunEach() order = %v, want ascending", order)
+		}
+	}
+}
+
+func TestRunEachParallelLimit(t *testing.T) {
+	const count, limit = 20, 3
+
+	var (
+		mu      sync.Mutex
+		seen    = make(map[int]bool)
+		current atomic.Int32
+		peak    atomic.Int32
+	)
+	RunEach(count, limit, func(i int) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		current.Add(-1)
+
+		mu.Lock()
+		seen[i] = true
+		mu.Unlock()
+	})
+
+	if len(seen) != count {
+		t.Errorf("RunEach() ran %d distinct servers, want %d", len(seen), count)
+	}
+	if p := peak.Load(); p > limit {
+		t.Errorf("RunEach() peak concurrency = %d, want at most %d", p, limit)
+	}
+}
+
+func TestRunEachEmpty(t *testing.T) {
+	RunEach(0, DefaultMaxParallel, func(i int) {
+		t.Errorf("RunEach() called run(%d) with no servers", i)
+	})
+}
@@ -0,0 +1,85 @@
+package ssh
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// idleCheckInterval 检查空闲会话的间隔
+const idleCheckInterval = 30 * time.Second
+
+// IdleWarningPeriod 空闲会话关闭前提前警告客户端的时间
+const IdleWarningPeriod = 2 * time.Minute
+
+// IdleTimeoutFunc 返回终端空闲超时时间（不大于 0 时不限制）
+type IdleTimeoutFunc func(ctx context.Context) time.Duration
+
+// IdleWatcher 定期检查终端会话，关闭超过空闲时间没有输入输出的会话
+// 关闭前 IdleWarningPeriod 通知已连接的客户端，期间有任何输入输出则重新计时
+type IdleWatcher struct {
+	manager *SessionManager
+	timeout IdleTimeoutFunc
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewIdleWatcher 创建空闲会话检查器
+func NewIdleWatcher(manager *SessionManager, timeout IdleTimeoutFunc) *IdleWatcher {
+	return &IdleWatcher{
+		manager: manager,
+		timeout: timeout,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start 启动后台检查
+func (w *IdleWatcher) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(idleCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.check()
+			}
+		}
+	}()
+}
+
+// Stop 停止后台检查
+func (w *IdleWatcher) Stop() {
+	close(w.stop)
+	w.wg.Wait()
+}
+
+// check 执行一次检查
+func (w *IdleWatcher) check() {
+	timeout := w.timeout(context.Background())
+	if timeout <= 0 {
+		return
+	}
+
+	for _, session := range w.manager.GetAll() {
+		if !session.IsActive() {
+			continue
+		}
+
+		idle := session.IdleTime()
+		switch {
+		case idle >= timeout:
+			log.Printf("[IdleWatcher] 关闭空闲会话: session=%s, user=%s, idle=%s", session.ID, session.UserID, idle.Round(time.Second))
+			session.CloseWithReason(CloseReasonIdleTimeout)
+		case idle >= timeout-IdleWarningPeriod:
+			session.warnIdle(timeout - idle)
+		}
+	}
+}
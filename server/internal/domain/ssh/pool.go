@@ -0,0 +1,471 @@
+package ssh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// defaultConnIdleTimeout 没有使用者时连接保留的时间
+	defaultConnIdleTimeout = 5 * time.Minute
+	// connCheckInterval 空闲清理和健康检查的间隔
+	connCheckInterval = time.Minute
+	// connKeepaliveTimeout 健康检查等待响应的时间
+	connKeepaliveTimeout = 10 * time.Second
+	// maxSessionsPerConn 单个连接上同时使用的会话通道（终端、命令、SFTP）上限
+	// OpenSSH 默认 MaxSessions 为 10，超过上限时为同一用户和服务器再建立一个连接
+	maxSessionsPerConn = 8
+)
+
+// pooledConn 连接管理器中的一个 SSH 连接
+type pooledConn struct {
+	key         string
+	userID      string
+	serverID    string
+	host        string
+	signature   string // 建立连接时的配置版本（见 connSignature），配置修改后不再复用
+	interactive bool   // 建立连接时提供了交互认证处理
+
+	ready  chan struct{} // 连接建立完成（成功或失败）后关闭，之后 client/err 不再改变
+	client *Client       // 在 ConnectionManager.mu 内写入，持有 mu 或 ready 关闭后可读
+	err    error
+
+	// 以下字段由 ConnectionManager.mu 保护
+	refs      int   // 正在使用的租用数
+	sessions  int   // 其中会话通道的租用数
+	leases    int64 // 累计租用次数
+	createdAt time.Time
+	lastUsed  time.Time
+	evicted   bool // 已从池中移除，引用归零后关闭
+}
+
+// dialed 连接是否已建立完成
+func (pc *pooledConn) dialed() bool {
+	select {
+	case <-pc.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// reusable 连接是否可以分配给新的使用者（调用方持有 mu）
+func (pc *pooledConn) reusable(signature string, session bool) bool {
+	if pc.evicted || pc.signature != signature {
+		return false
+	}
+	if pc.dialed() && pc.err != nil {
+		return false
+	}
+	return !session || pc.sessions < maxSessionsPerConn
+}
+
+// connSignature 连接配置的版本：服务器及其跳板机链各自的修改时间，以及沿用全局设置时的出站代理
+// 服务器级代理设置的修改已体现在服务器的修改时间中；代理密码参与摘要，不以明文保存
+func connSignature(srv *server.Server) string {
+	h := sha256.New()
+	chain := srv.JumpChain()
+	for _, s := range append(chain, srv) {
+		fmt.Fprintf(h, "%s@%d\n", s.ID, s.UpdatedAt.UnixNano())
+	}
+
+	first := srv
+	if len(chain) > 0 {
+		first = chain[0]
+	}
+	if first.ProxyType == "" {
+		if cfg := globalProxyConfig(); cfg != nil {
+			fmt.Fprintf(h, "proxy=%s://%s:%s@%s:%d\n", cfg.Type, cfg.Username, cfg.Password, cfg.Host, cfg.Port)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ConnectionStats 连接管理器统计信息
+type ConnectionStats struct {
+	Total          int              `json:"total"`           // 当前连接数
+	Active         int              `json:"active"`          // 正在使用的连接数
+	Idle           int              `json:"idle"`            // 空闲连接数
+	Leases         int              `json:"leases"`          // 正在使用的租用数
+	Dials          int64            `json:"dials"`           // 累计建立连接次数
+	Reuses         int64            `json:"reuses"`          // 累计复用已有连接次数
+	DialFailures   int64            `json:"dial_failures"`   // 累计建立连接失败次数
+	Evictions      int64            `json:"evictions"`       // 累计因空闲关闭的连接数
+	HealthFailures int64            `json:"health_failures"` // 累计因健康检查失败关闭的连接数
+	Connections    []ConnectionInfo `json:"connections"`
+}
+
+// ConnectionInfo 单个连接的信息
+type ConnectionInfo struct {
+	UserID     string    `json:"user_id"`
+	ServerID   string    `json:"server_id"`
+	Host       string    `json:"host"`
+	Refs       int       `json:"refs"`     // 正在使用的租用数
+	Sessions   int       `json:"sessions"` // 正在使用的会话通道租用数
+	Leases     int64     `json:"leases"`   // 累计租用次数
+	Dialing    bool      `json:"dialing"`  // 正在建立连接
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// ConnectionManager 按用户和服务器复用 SSH 连接
+// 终端、SFTP、监控和反向代理在同一连接上打开各自的通道，省去重复的握手和认证；
+// 同一用户和服务器的并发请求共享同一次连接建立，没有使用者的连接空闲一段时间后关闭，
+// 正在使用的连接定期发送 keepalive 检查，失效的连接会被移除
+type ConnectionManager struct {
+	encryptor       *crypto.Encryptor
+	hostKeyCallback ssh.HostKeyCallback
+	idleTimeout     time.Duration
+
+	mu    sync.Mutex
+	conns map[string][]*pooledConn // key: userID:serverID
+	stats ConnectionStats          // 只使用累计计数字段
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewConnectionManager 创建连接管理器并启动后台清理和健康检查
+func NewConnectionManager(encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback) *ConnectionManager {
+	m := &ConnectionManager{
+		encryptor:       encryptor,
+		hostKeyCallback: hostKeyCallback,
+		idleTimeout:     defaultConnIdleTimeout,
+		conns:           make(map[string][]*pooledConn),
+		stop:            make(chan struct{}),
+	}
+
+	m.wg.Add(1)
+	go m.checkLoop()
+	return m
+}
+
+// Lease 一次连接使用，用完后必须调用 Release
+type Lease struct {
+	manager *ConnectionManager
+	conn    *pooledConn
+	session bool
+	once    sync.Once
+}
+
+// Client 获取共享的 SSH 客户端（不能关闭，使用结束后调用 Release）
+func (l *Lease) Client() *Client {
+	return l.conn.client
+}
+
+// Release 释放租用（重复调用无效）
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		l.manager.release(l.conn, l.session)
+	})
+}
+
+// Discard 连接已失效（如打开通道失败）时调用：不再分配给新的使用者，并释放租用
+func (l *Lease) Discard() {
+	l.manager.mu.Lock()
+	l.manager.removeLocked(l.conn)
+	l.manager.mu.Unlock()
+	l.Release()
+}
+
+// Acquire 获取用于会话通道（终端、命令执行、SFTP）的连接
+// srv 需由 server.Service.GetByID 加载（包含跳板机链）；interactive 仅在需要建立新连接时用于转发认证问题，可为空
+// 建立新连接时不响应 ctx 取消（由 SSH 连接超时控制），等待其他请求建立的连接时可取消
+func (m *ConnectionManager) Acquire(ctx context.Context, userID string, srv *server.Server, interactive KeyboardInteractiveHandler) (*Lease, error) {
+	return m.acquire(ctx, userID, srv, interactive, true)
+}
+
+// AcquireForward 获取用于端口转发通道（direct-tcpip）的连接，不占用会话通道数
+func (m *ConnectionManager) AcquireForward(ctx context.Context, userID string, srv *server.Server) (*Lease, error) {
+	return m.acquire(ctx, userID, srv, nil, false)
+}
+
+// acquire 分配可复用的连接，没有时建立新连接
+// 等待的连接由未提供交互认证的请求建立且失败时，失败可能只是因为无法回答认证问题，
+// 提供了交互认证的请求不沿用该结果，改为自行建立连接重试一次
+func (m *ConnectionManager) acquire(ctx context.Context, userID string, srv *server.Server, interactive KeyboardInteractiveHandler, session bool) (*Lease, error) {
+	retry := false
+	for {
+		pc, dial := m.reserve(userID, srv, interactive != nil, session, retry)
+		lease := &Lease{manager: m, conn: pc, session: session}
+		if dial {
+			m.dial(pc, srv, interactive)
+		} else {
+			select {
+			case <-pc.ready:
+			case <-ctx.Done():
+				lease.Release()
+				return nil, ctx.Err()
+			}
+		}
+
+		if pc.err != nil {
+			lease.Release()
+			if !dial && !retry && interactive != nil && !pc.interactive {
+				retry = true
+				continue
+			}
+			return nil, pc.err
+		}
+		return lease, nil
+	}
+}
+
+// reserve 在池中选择可复用的连接并增加引用，没有时登记一个待建立的连接，返回是否需要由调用方建立
+// skipNonInteractive 为 true 时不等待由未提供交互认证的请求正在建立的连接
+func (m *ConnectionManager) reserve(userID string, srv *server.Server, interactive, session, skipNonInteractive bool) (*pooledConn, bool) {
+	key := userID + ":" + srv.ID.String()
+	signature := connSignature(srv)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pc *pooledConn
+	for _, candidate := range m.conns[key] {
+		if skipNonInteractive && !candidate.interactive && !candidate.dialed() {
+			continue
+		}
+		if candidate.reusable(signature, session) {
+			pc = candidate
+			break
+		}
+	}
+	dial := pc == nil
+	if dial {
+		pc = &pooledConn{
+			key:         key,
+			userID:      userID,
+			serverID:    srv.ID.String(),
+			host:        srv.Host,
+			signature:   signature,
+			interactive: interactive,
+			ready:       make(chan struct{}),
+			createdAt:   now,
+		}
+		m.conns[key] = append(m.conns[key], pc)
+		m.stats.Dials++
+	} else {
+		m.stats.Reuses++
+	}
+	pc.refs++
+	if session {
+		pc.sessions++
+	}
+	pc.leases++
+	pc.lastUsed = now
+	return pc, dial
+}
+
+// dial 建立 SSH 连接，失败时从池中移除
+func (m *ConnectionManager) dial(pc *pooledConn, srv *server.Server, interactive KeyboardInteractiveHandler) {
+	client, err := NewClient(srv, m.encryptor, m.hostKeyCallback)
+	if err == nil {
+		client.SetKeyboardInteractiveHandler(interactive)
+		err = client.Connect(srv.Host, srv.Port)
+	}
+
+	// client/err 在 mu 内写入：release、removeLocked 在持有 mu 时读取 client，不等待 ready
+	m.mu.Lock()
+	if err != nil {
+		pc.err = err
+		m.removeLocked(pc)
+		m.stats.DialFailures++
+		m.mu.Unlock()
+		close(pc.ready)
+		return
+	}
+	pc.client = client
+	m.mu.Unlock()
+	close(pc.ready)
+	log.Printf("[ConnectionManager] 建立 SSH 连接: key=%s, host=%s", pc.key, pc.host)
+
+	go m.watch(pc)
+}
+
+// watch SSH 连接断开后从池中移除
+func (m *ConnectionManager) watch(pc *pooledConn) {
+	pc.client.GetRawConnection().Wait()
+
+	m.mu.Lock()
+	m.removeLocked(pc)
+	m.mu.Unlock()
+	pc.client.Close()
+}
+
+// removeLocked 从池中移除连接，不再分配给新的使用者；没有使用者时立即关闭（调用方持有 mu）
+func (m *ConnectionManager) removeLocked(pc *pooledConn) {
+	if pc.evicted {
+		return
+	}
+	pc.evicted = true
+
+	conns := m.conns[pc.key]
+	for i, candidate := range conns {
+		if candidate == pc {
+			conns = append(conns[:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(m.conns, pc.key)
+	} else {
+		m.conns[pc.key] = conns
+	}
+
+	if pc.refs == 0 && pc.client != nil {
+		go pc.client.Close()
+	}
+}
+
+// release 减少使用计数，已移除的连接在引用归零后关闭
+func (m *ConnectionManager) release(pc *pooledConn, session bool) {
+	m.mu.Lock()
+	pc.refs--
+	if session {
+		pc.sessions--
+	}
+	pc.lastUsed = time.Now()
+	closeNow := pc.evicted && pc.refs == 0 && pc.client != nil
+	m.mu.Unlock()
+
+	if closeNow {
+		pc.client.Close()
+	}
+}
+
+// checkLoop 定期关闭空闲连接并检查正在使用的连接
+func (m *ConnectionManager) checkLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(connCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+// check 执行一次空闲清理和健康检查
+func (m *ConnectionManager) check() {
+	var probe []*pooledConn
+
+	m.mu.Lock()
+	for _, conns := range m.conns {
+		for _, pc := range append([]*pooledConn(nil), conns...) {
+			if !pc.dialed() || pc.err != nil {
+				continue
+			}
+			if pc.refs == 0 && time.Since(pc.lastUsed) > m.idleTimeout {
+				m.removeLocked(pc)
+				m.stats.Evictions++
+				continue
+			}
+			probe = append(probe, pc)
+		}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, pc := range probe {
+		wg.Add(1)
+		go func(pc *pooledConn) {
+			defer wg.Done()
+			if err := keepalive(pc.client); err != nil {
+				log.Printf("[ConnectionManager] 健康检查失败，关闭连接: key=%s, error=%v", pc.key, err)
+				m.mu.Lock()
+				m.stats.HealthFailures++
+				m.mu.Unlock()
+				// 关闭后 watch 将其移除，使用者的通道随之结束
+				pc.client.Close()
+			}
+		}(pc)
+	}
+	wg.Wait()
+}
+
+// keepalive 发送 keepalive 请求检查连接是否可用
+func keepalive(client *Client) error {
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := client.GetRawConnection().SendRequest("keepalive@openssh.com", true, nil)
+		errc <- err
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(connKeepaliveTimeout):
+		return errors.New("keepalive timeout")
+	}
+}
+
+// Stats 获取统计信息
+func (m *ConnectionManager) Stats() ConnectionStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats
+	stats.Connections = make([]ConnectionInfo, 0)
+	for _, conns := range m.conns {
+		for _, pc := range conns {
+			stats.Total++
+			stats.Leases += pc.refs
+			if pc.refs > 0 {
+				stats.Active++
+			} else {
+				stats.Idle++
+			}
+			stats.Connections = append(stats.Connections, ConnectionInfo{
+				UserID:     pc.userID,
+				ServerID:   pc.serverID,
+				Host:       pc.host,
+				Refs:       pc.refs,
+				Sessions:   pc.sessions,
+				Leases:     pc.leases,
+				Dialing:    !pc.dialed(),
+				CreatedAt:  pc.createdAt,
+				LastUsedAt: pc.lastUsed,
+			})
+		}
+	}
+	return stats
+}
+
+// Close 停止后台检查并关闭所有连接（服务关闭时调用）
+func (m *ConnectionManager) Close() {
+	close(m.stop)
+	m.wg.Wait()
+
+	m.mu.Lock()
+	var clients []*Client
+	for _, conns := range m.conns {
+		for _, pc := range conns {
+			pc.evicted = true
+			if pc.dialed() && pc.client != nil {
+				clients = append(clients, pc.client)
+			}
+		}
+	}
+	m.conns = make(map[string][]*pooledConn)
+	m.mu.Unlock()
+
+	for _, client := range clients {
+		client.Close()
+	}
+}
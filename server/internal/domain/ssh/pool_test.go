@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/easyssh/server/internal/pkg/netproxy"
+	"github.com/google/uuid"
+)
+
+func TestConnSignature(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newChain := func() *server.Server {
+		outer := &server.Server{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), UpdatedAt: base}
+		inner := &server.Server{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), UpdatedAt: base, JumpServer: outer}
+		return &server.Server{ID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), UpdatedAt: base, JumpServer: inner}
+	}
+
+	proxy := &netproxy.Config{Type: netproxy.TypeSOCKS5, Host: "proxy.local", Port: 1080}
+	setGlobalProxy(t, func(ctx context.Context) (*netproxy.Config, error) {
+		return proxy, nil
+	})
+
+	want := connSignature(newChain())
+
+	tests := []struct {
+		name   string
+		change func(srv *server.Server)
+		same   bool
+	}{
+		{
+			name:   "unchanged",
+			change: func(srv *server.Server) {},
+			same:   true,
+		},
+		{
+			name:   "target server updated",
+			change: func(srv *server.Server) { srv.UpdatedAt = base.Add(time.Second) },
+		},
+		{
+			name:   "nearest jump server updated",
+			change: func(srv *server.Server) { srv.JumpServer.UpdatedAt = base.Add(time.Second) },
+		},
+		{
+			name:   "outermost jump server updated",
+			change: func(srv *server.Server) { srv.JumpServer.JumpServer.UpdatedAt = base.Add(time.Second) },
+		},
+		{
+			name:   "jump server removed",
+			change: func(srv *server.Server) { srv.JumpServer = nil },
+		},
+		{
+			name: "global proxy changed",
+			change: func(srv *server.Server) {
+				proxy = &netproxy.Config{Type: netproxy.TypeHTTP, Host: "proxy.local", Port: 8080}
+			},
+		},
+		{
+			name: "global proxy password changed",
+			change: func(srv *server.Server) {
+				proxy = &netproxy.Config{Type: netproxy.TypeSOCKS5, Host: "proxy.local", Port: 1080, Password: "secret"}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := proxy
+			defer func() { proxy = saved }()
+
+			srv := newChain()
+			tt.change(srv)
+			if got := connSignature(srv); (got == want) != tt.same {
+				t.Errorf("connSignature() same = %v, want %v", got == want, tt.same)
+			}
+		})
+	}
+}
+
+func TestConnSignatureIgnoresGlobalProxyWhenServerHasOwn(t *testing.T) {
+	proxy := &netproxy.Config{Type: netproxy.TypeSOCKS5, Host: "proxy.local", Port: 1080}
+	setGlobalProxy(t, func(ctx context.Context) (*netproxy.Config, error) {
+		return proxy, nil
+	})
+
+	srv := &server.Server{ID: uuid.New(), UpdatedAt: time.Now(), ProxyType: netproxy.TypeHTTP}
+	before := connSignature(srv)
+	proxy = &netproxy.Config{Type: netproxy.TypeHTTP, Host: "other.local", Port: 3128}
+	if after := connSignature(srv); after != before {
+		t.Errorf("connSignature() changed with the global proxy although the server has its own proxy")
+	}
+}
+
+// setGlobalProxy 在测试期间替换全局代理配置来源
+func setGlobalProxy(t *testing.T, provider ProxyProvider) {
+	t.Helper()
+	previous := globalProxy.Load()
+	SetProxyProvider(provider)
+	t.Cleanup(func() { globalProxy.Store(previous) })
+}
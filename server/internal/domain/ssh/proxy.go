@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/easyssh/server/internal/pkg/netproxy"
+)
+
+// ProxyProvider 获取全局出站代理配置
+type ProxyProvider func(ctx context.Context) (*netproxy.Config, error)
+
+// globalProxy 全局出站代理配置来源，所有 SSH 客户端共用
+var globalProxy atomic.Pointer[ProxyProvider]
+
+// SetProxyProvider 设置全局出站代理配置来源（启动时调用一次）
+func SetProxyProvider(provider ProxyProvider) {
+	globalProxy.Store(&provider)
+}
+
+// resolveProxy 获取连接服务器使用的出站代理：服务器级设置优先，未设置时沿用全局设置
+func resolveProxy(srv *server.Server, encryptor *crypto.Encryptor) (*netproxy.Config, error) {
+	if srv.ProxyType != "" {
+		cfg := &netproxy.Config{
+			Type:     srv.ProxyType,
+			Host:     srv.ProxyHost,
+			Port:     srv.ProxyPort,
+			Username: srv.ProxyUsername,
+		}
+		if srv.ProxyPassword != "" {
+			password, err := encryptor.Decrypt(srv.ProxyPassword)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt proxy password: %w", err)
+			}
+			cfg.Password = password
+		}
+		return cfg, nil
+	}
+
+	return globalProxyConfig(), nil
+}
+
+// globalProxyConfig 获取全局出站代理配置，未设置或读取失败时返回 nil（直连）
+func globalProxyConfig() *netproxy.Config {
+	provider := globalProxy.Load()
+	if provider == nil || *provider == nil {
+		return nil
+	}
+	cfg, err := (*provider)(context.Background())
+	if err != nil {
+		// 读取失败时直连，避免因配置读取问题导致所有连接中断
+		log.Printf("[SSH] 读取全局代理配置失败，使用直连: %v", err)
+		return nil
+	}
+	return cfg
+}
@@ -0,0 +1,58 @@
+package ssh
+
+import "unicode/utf8"
+
+// RingBuffer 固定容量的环形缓冲区，保存最近写入的数据（非并发安全）
+type RingBuffer struct {
+	buf  []byte
+	pos  int  // 下一次写入的位置
+	full bool // 是否已经写满过一轮
+}
+
+// NewRingBuffer 创建指定容量的环形缓冲区
+func NewRingBuffer(size int) *RingBuffer {
+	return &RingBuffer{buf: make([]byte, size)}
+}
+
+// Write 写入数据，超出容量时覆盖最早的数据
+func (r *RingBuffer) Write(p []byte) {
+	size := len(r.buf)
+	if size == 0 || len(p) == 0 {
+		return
+	}
+
+	if len(p) >= size {
+		copy(r.buf, p[len(p)-size:])
+		r.pos = 0
+		r.full = true
+		return
+	}
+
+	n := copy(r.buf[r.pos:], p)
+	if n < len(p) {
+		copy(r.buf, p[n:])
+	}
+	if r.pos+len(p) >= size {
+		r.full = true
+	}
+	r.pos = (r.pos + len(p)) % size
+}
+
+// Bytes 返回缓冲区内容的副本（按写入顺序）
+// 缓冲区写满后最早的数据可能从多字节字符中间开始，开头不完整的字符会被丢弃
+func (r *RingBuffer) Bytes() []byte {
+	if !r.full {
+		return append([]byte(nil), r.buf[:r.pos]...)
+	}
+
+	data := make([]byte, 0, len(r.buf))
+	data = append(data, r.buf[r.pos:]...)
+	data = append(data, r.buf[:r.pos]...)
+
+	for i := 0; i < len(data) && i < utf8.UTFMax; i++ {
+		if utf8.RuneStart(data[i]) {
+			return data[i:]
+		}
+	}
+	return data
+}
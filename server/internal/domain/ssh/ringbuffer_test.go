@@ -0,0 +1,103 @@
+package ssh
+
+import "testing"
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes []string
+		want   string
+	}{
+		{
+			name:   "empty",
+			size:   8,
+			writes: nil,
+			want:   "",
+		},
+		{
+			name:   "below capacity",
+			size:   8,
+			writes: []string{"abc", "de"},
+			want:   "abcde",
+		},
+		{
+			name:   "exactly full",
+			size:   4,
+			writes: []string{"ab", "cd"},
+			want:   "abcd",
+		},
+		{
+			name:   "wraps around",
+			size:   5,
+			writes: []string{"abcd", "efg"},
+			want:   "cdefg",
+		},
+		{
+			name:   "wraps around several times",
+			size:   4,
+			writes: []string{"abc", "def", "gh", "i"},
+			want:   "fghi",
+		},
+		{
+			name:   "single write larger than capacity",
+			size:   4,
+			writes: []string{"abcdefgh"},
+			want:   "efgh",
+		},
+		{
+			name:   "large write after wraparound",
+			size:   4,
+			writes: []string{"abc", "defghij"},
+			want:   "ghij",
+		},
+		{
+			name:   "zero capacity",
+			size:   0,
+			writes: []string{"abc"},
+			want:   "",
+		},
+		{
+			name: "drops partial character at the start after wraparound",
+			size: 6,
+			// "中" 和 "文" 各占 3 字节，保留的最后 6 字节从 "中" 的最后一个字节开始
+			writes: []string{"中", "文", "ab"},
+			want:   "文ab",
+		},
+		{
+			name:   "keeps complete character at the start",
+			size:   5,
+			writes: []string{"abc", "中"},
+			want:   "bc中",
+		},
+		{
+			name:   "keeps partial character at the end",
+			size:   8,
+			writes: []string{"ab", "\xe4\xb8"},
+			want:   "ab\xe4\xb8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRingBuffer(tt.size)
+			for _, w := range tt.writes {
+				r.Write([]byte(w))
+			}
+			if got := string(r.Bytes()); got != tt.want {
+				t.Errorf("Bytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRingBufferBytesReturnsCopy(t *testing.T) {
+	r := NewRingBuffer(4)
+	r.Write([]byte("abcdef"))
+
+	got := r.Bytes()
+	got[0] = 'x'
+	if again := string(r.Bytes()); again != "cdef" {
+		t.Errorf("Bytes() after modifying previous result = %q, want %q", again, "cdef")
+	}
+}
@@ -1,11 +1,12 @@
 package ssh
 
 import (
-	"context"
 	"errors"
+	"io"
 	"sync"
 	"time"
 
+	"github.com/easyssh/server/internal/domain/server"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/ssh"
 )
@@ -23,22 +24,55 @@ const (
 	SessionStatusClosed SessionStatus = "closed"
 )
 
+// 会话关闭原因
+const (
+	CloseReasonTimeout       = "session timed out"           // 超时关闭
+	CloseReasonIdleTimeout   = "idle timeout"                // 超过空闲时间没有输入输出
+	CloseReasonClientGone    = "client disconnected"         // 客户端断开且未启用断开保持
+	CloseReasonDetachExpired = "detach grace period expired" // 客户端断开后超过保持时间未重新连接
+	CloseReasonClosedByUser  = "closed by user"              // 用户主动关闭
+
+	// CloseReasonTerminatedPrefix 管理员强制结束会话的原因前缀，后接管理员填写的原因
+	CloseReasonTerminatedPrefix = "terminated by administrator: "
+)
+
 // Session SSH 会话
 type Session struct {
-	ID        string        `json:"id"`
-	UserID    string        `json:"user_id"`
-	ServerID  string        `json:"server_id"`
-	Client    *Client       `json:"-"`
-	SSHSession *ssh.Session `json:"-"`
-	Status    SessionStatus `json:"status"`
-	CreatedAt time.Time     `json:"created_at"`
-	ClosedAt  *time.Time    `json:"closed_at,omitempty"`
+	ID         string         `json:"id"`
+	UserID     string         `json:"user_id"`
+	ServerID   string         `json:"server_id"`
+	Server     *server.Server `json:"-"` // 会话所属的服务器（创建时加载，用于匹配危险命令规则的分组和标签）
+	Client     *Client        `json:"-"`
+	SSHSession *ssh.Session   `json:"-"`
+	Status     SessionStatus  `json:"status"`
+	ClientIP   string         `json:"client_ip"`
+	CreatedAt  time.Time      `json:"created_at"`
+	ClosedAt   *time.Time     `json:"closed_at,omitempty"`
 
 	// 终端相关
 	Cols int `json:"cols"`
 	Rows int `json:"rows"`
 
-	mu sync.RWMutex
+	lease        *Lease                   // 共享连接的租用（为空时会话独占 Client）
+	closeReason  string                   // 服务端主动关闭会话的原因
+	lastActivity time.Time                // 最后一次输入或输出的时间
+	idleWarned   bool                     // 本次空闲已发送过关闭警告
+	stdin        io.Writer                // 远端输入
+	hooks        SessionHooks             // I/O 回调
+	ended        bool                     // 输出已结束（远端退出或连接关闭）
+	done         chan struct{}            // 会话结束时关闭
+	detachedAt   *time.Time               // 最后一个客户端断开的时间
+	detachGen    uint64                   // 断开保持计时的代数，重新连接或结束时递增使旧计时失效
+	shares       map[string]*SessionShare // 共享授权（按用户 ID）
+	mu           sync.RWMutex
+
+	// 输出分发（锁顺序：ioMu 在 mu 之前）
+	scrollback  *RingBuffer
+	attachments map[string]*Attachment
+	ioMu        sync.Mutex
+
+	// 命令行重建（所有客户端共享，锁顺序：commandLine 在 mu 之前）
+	commandLine CommandLine
 }
 
 // NewSession 创建新会话
@@ -52,25 +86,47 @@ func NewSession(userID, serverID string, client *Client, cols, rows int) *Sessio
 		CreatedAt: time.Now(),
 		Cols:      cols,
 		Rows:      rows,
+
+		lastActivity: time.Now(),
+		done:         make(chan struct{}),
+		shares:       make(map[string]*SessionShare),
+		scrollback:   NewRingBuffer(scrollbackSize),
+		attachments:  make(map[string]*Attachment),
 	}
 }
 
+// NewLeasedSession 创建使用共享连接的会话，会话关闭时只释放租用，不影响同一连接上的其他通道
+func NewLeasedSession(userID string, srv *server.Server, lease *Lease, cols, rows int) *Session {
+	s := NewSession(userID, srv.ID.String(), lease.Client(), cols, rows)
+	s.Server = srv
+	s.lease = lease
+	return s
+}
+
 // Close 关闭会话
 func (s *Session) Close() error {
+	return s.CloseWithReason("")
+}
+
+// CloseWithReason 关闭会话并记录关闭原因
+func (s *Session) CloseWithReason(reason string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if s.Status == SessionStatusClosed {
 		return ErrSessionClosed
 	}
+	s.closeReason = reason
 
 	// 关闭 SSH 会话
 	if s.SSHSession != nil {
 		s.SSHSession.Close()
 	}
 
-	// 关闭客户端
-	if s.Client != nil {
+	// 关闭客户端（共享连接只释放租用）
+	if s.lease != nil {
+		s.lease.Release()
+	} else if s.Client != nil {
 		s.Client.Close()
 	}
 
@@ -81,6 +137,18 @@ func (s *Session) Close() error {
 	return nil
 }
 
+// Terminate 管理员强制结束会话，原因会推送给已连接的客户端并写入会话记录
+func (s *Session) Terminate(reason string) error {
+	return s.CloseWithReason(CloseReasonTerminatedPrefix + reason)
+}
+
+// CloseReason 获取服务端主动关闭会话的原因（未关闭或正常关闭时为空）
+func (s *Session) CloseReason() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closeReason
+}
+
 // IsActive 检查会话是否活跃
 func (s *Session) IsActive() bool {
 	s.mu.RLock()
@@ -99,26 +167,41 @@ func (s *Session) GetUptime() time.Duration {
 	return time.Since(s.CreatedAt)
 }
 
+// IdleTime 获取距最后一次输入或输出的时间
+func (s *Session) IdleTime() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.lastActivity)
+}
+
 // ResizeTerminal 调整终端大小
 func (s *Session) ResizeTerminal(cols, rows int) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	if s.Status == SessionStatusClosed {
+		s.mu.Unlock()
 		return ErrSessionClosed
 	}
 
 	if s.SSHSession == nil {
+		s.mu.Unlock()
 		return errors.New("SSH session not initialized")
 	}
 
 	// 更新终端尺寸
 	if err := s.SSHSession.WindowChange(rows, cols); err != nil {
+		s.mu.Unlock()
 		return err
 	}
 
 	s.Cols = cols
 	s.Rows = rows
+	onResize := s.hooks.OnResize
+	s.mu.Unlock()
+
+	if onResize != nil {
+		onResize(cols, rows)
+	}
 	return nil
 }
 
@@ -266,36 +349,10 @@ func (m *SessionManager) CountByUserID(userID string) int {
 	return count
 }
 
-// CleanupInactive 清理不活跃的会话
-func (m *SessionManager) CleanupInactive(ctx context.Context, maxAge time.Duration) {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			m.cleanupOldSessions(maxAge)
-		}
-	}
-}
-
-func (m *SessionManager) cleanupOldSessions(maxAge time.Duration) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	now := time.Now()
-	for id, session := range m.sessions {
-		if session.IsActive() && now.Sub(session.CreatedAt) > maxAge {
-			session.Close()
-			delete(m.sessions, id)
-		}
-	}
-}
-
 // ToPublic 转换为公开信息
 func (s *Session) ToPublic() map[string]interface{} {
+	attached := s.AttachmentCount()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -304,15 +361,23 @@ func (s *Session) ToPublic() map[string]interface{} {
 		"user_id":    s.UserID,
 		"server_id":  s.ServerID,
 		"status":     s.Status,
+		"client_ip":  s.ClientIP,
 		"cols":       s.Cols,
 		"rows":       s.Rows,
 		"created_at": s.CreatedAt,
 		"uptime":     s.GetUptime().Seconds(),
+		"idle":       time.Since(s.lastActivity).Seconds(),
 	}
 
 	if s.ClosedAt != nil {
 		result["closed_at"] = s.ClosedAt
 	}
 
+	// 客户端断开后仍在保持的会话可以重新连接
+	result["attached_clients"] = attached
+	if s.detachedAt != nil {
+		result["detached_at"] = s.detachedAt
+	}
+
 	return result
 }
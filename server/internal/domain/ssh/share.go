@@ -0,0 +1,135 @@
+package ssh
+
+import (
+	"errors"
+	"time"
+)
+
+// ShareMode 共享会话的访问模式
+type ShareMode string
+
+const (
+	ShareModeView        ShareMode = "view"        // 只读观看
+	ShareModeInteractive ShareMode = "interactive" // 可以输入
+)
+
+var ErrInvalidShareMode = errors.New("share mode must be view or interactive")
+
+// SessionShare 会话共享授权
+type SessionShare struct {
+	UserID    string    `json:"user_id"`
+	Mode      ShareMode `json:"mode"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// Valid 检查访问模式是否合法
+func (m ShareMode) Valid() bool {
+	return m == ShareModeView || m == ShareModeInteractive
+}
+
+// Grant 授权其他用户加入会话（已授权时更新访问模式）
+func (s *Session) Grant(userID string, mode ShareMode) (*SessionShare, error) {
+	if !mode.Valid() {
+		return nil, ErrInvalidShareMode
+	}
+
+	s.mu.Lock()
+	if s.ended || s.Status == SessionStatusClosed {
+		s.mu.Unlock()
+		return nil, ErrSessionClosed
+	}
+
+	previous, exists := s.shares[userID]
+	share := &SessionShare{UserID: userID, Mode: mode, GrantedAt: time.Now()}
+	s.shares[userID] = share
+	s.mu.Unlock()
+
+	// 访问模式变更时断开该用户已连接的客户端，重新加入后按新模式生效
+	if exists && previous.Mode != mode {
+		s.kickUser(userID, "share mode changed")
+	}
+	return share, nil
+}
+
+// Revoke 撤销用户的共享授权，并断开该用户已连接的客户端
+func (s *Session) Revoke(userID string, reason string) bool {
+	s.mu.Lock()
+	_, exists := s.shares[userID]
+	delete(s.shares, userID)
+	s.mu.Unlock()
+
+	s.kickUser(userID, reason)
+	return exists
+}
+
+// Access 获取用户对会话的访问模式：所有者为 interactive，未授权时返回 false
+func (s *Session) Access(userID string) (ShareMode, bool) {
+	if userID == s.UserID {
+		return ShareModeInteractive, true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	share, exists := s.shares[userID]
+	if !exists {
+		return "", false
+	}
+	return share.Mode, true
+}
+
+// Shares 获取会话的所有共享授权
+func (s *Session) Shares() []SessionShare {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shares := make([]SessionShare, 0, len(s.shares))
+	for _, share := range s.shares {
+		shares = append(shares, *share)
+	}
+	return shares
+}
+
+// SharedWith 检查会话是否共享给指定用户
+func (s *Session) SharedWith(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.shares[userID]
+	return exists
+}
+
+// Attachments 获取当前连接的客户端
+func (s *Session) Attachments() []*Attachment {
+	s.ioMu.Lock()
+	defer s.ioMu.Unlock()
+
+	attachments := make([]*Attachment, 0, len(s.attachments))
+	for _, a := range s.attachments {
+		attachments = append(attachments, a)
+	}
+	return attachments
+}
+
+// kickUser 断开指定用户的所有客户端（客户端随后自行调用 Detach）
+func (s *Session) kickUser(userID string, reason string) {
+	for _, a := range s.Attachments() {
+		if a.UserID == userID && a.Kick != nil {
+			a.Kick(reason)
+		}
+	}
+}
+
+// GetSharedWithUser 获取共享给指定用户的所有会话
+func (m *SessionManager) GetSharedWithUser(userID string) []*Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []*Session
+	for _, session := range m.sessions {
+		if session.SharedWith(userID) && session.IsActive() {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions
+}
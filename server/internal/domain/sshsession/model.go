@@ -16,13 +16,16 @@ type SSHSession struct {
 	ClientIP     string         `gorm:"type:varchar(50)" json:"client_ip"`
 	ClientPort   int            `json:"client_port"`
 	TerminalType string         `gorm:"type:varchar(50)" json:"terminal_type"`
-	Status       string         `gorm:"type:varchar(20);default:'active';index" json:"status"` // active/closed/timeout
+	Status       string         `gorm:"type:varchar(20);default:'active';index" json:"status"` // active/closed/timeout/terminated
 	ConnectedAt  time.Time      `gorm:"not null" json:"connected_at"`
 	DisconnectedAt *time.Time   `json:"disconnected_at,omitempty"`
 	Duration     int            `json:"duration,omitempty"` // 连接时长(秒)
-	BytesSent    int64          `gorm:"default:0" json:"bytes_sent"`
-	BytesReceived int64         `gorm:"default:0" json:"bytes_received"`
-	ErrorMessage string         `gorm:"type:text" json:"error_message,omitempty"`
+	BytesSent    int64          `gorm:"default:0" json:"bytes_sent"`     // 发送到服务器的字节数（用户输入）
+	BytesReceived int64         `gorm:"default:0" json:"bytes_received"` // 从服务器接收的字节数（终端输出）
+	ErrorMessage string         `gorm:"type:text" json:"error_message,omitempty"` // 断开原因
+	HasRecording  bool          `gorm:"default:false;index" json:"has_recording"`     // 是否有终端录像
+	RecordingPath string        `gorm:"type:varchar(500)" json:"-"`                   // 录像文件相对路径
+	RecordingSize int64         `gorm:"default:0" json:"recording_size,omitempty"`    // 录像文件大小（字节）
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
@@ -41,6 +44,31 @@ func (SSHSession) TableName() string {
 	return "ssh_sessions"
 }
 
+// SSHSessionTranscript 终端输出文本片段（已去除 ANSI 控制序列），用于全文检索
+// 片段通过 session_id 关联到 ssh_sessions.session_id，Offset 与会话录像的时间轴一致
+type SSHSessionTranscript struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	SessionID string    `gorm:"type:varchar(100);not null;index:idx_ssh_session_transcripts_session,priority:1" json:"session_id"`
+	Seq       int       `gorm:"not null;index:idx_ssh_session_transcripts_session,priority:2" json:"seq"` // 片段序号
+	Offset    float64   `gorm:"column:offset_seconds;not null" json:"offset"`                             // 片段起始时间距会话开始的秒数
+	StartedAt time.Time `gorm:"not null;index" json:"started_at"`                                         // 片段起始时间
+	Content   string    `gorm:"type:text;not null;index:idx_ssh_session_transcripts_search,type:gin,expression:to_tsvector('simple'\\, content)" json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeCreate GORM钩子：创建前生成UUID
+func (t *SSHSessionTranscript) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName 指定表名
+func (SSHSessionTranscript) TableName() string {
+	return "ssh_session_transcripts"
+}
+
 // SSHSessionWithServer SSH会话及服务器信息
 type SSHSessionWithServer struct {
 	ID             uuid.UUID  `json:"id"`
@@ -59,6 +87,8 @@ type SSHSessionWithServer struct {
 	BytesSent      int64      `json:"bytes_sent"`
 	BytesReceived  int64      `json:"bytes_received"`
 	ErrorMessage   string     `json:"error_message,omitempty"`
+	HasRecording   bool       `json:"has_recording"`
+	RecordingSize  int64      `json:"recording_size,omitempty"`
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
@@ -109,3 +139,38 @@ type SSHSessionStatistics struct {
 	TotalBytesReceived int64       `json:"total_bytes_received"` // 总接收字节数
 	ByServer        map[string]int `json:"by_server"` // 按服务器统计
 }
+
+// SearchTranscriptsRequest 终端输出全文检索请求
+type SearchTranscriptsRequest struct {
+	Query     string     `form:"q" json:"q"`
+	UserID    string     `form:"user_id" json:"user_id"`       // 仅管理员可按用户筛选
+	ServerID  string     `form:"server_id" json:"server_id"`
+	StartTime *time.Time `form:"start_time" json:"start_time"`
+	EndTime   *time.Time `form:"end_time" json:"end_time"`
+	Page      int        `form:"page" json:"page"`
+	Limit     int        `form:"limit" json:"limit"`
+}
+
+// TranscriptSearchResult 全文检索命中结果（每个命中的输出片段一条）
+type TranscriptSearchResult struct {
+	ID           uuid.UUID `json:"id"`         // SSH会话记录ID
+	SessionID    string    `json:"session_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	ServerID     uuid.UUID `json:"server_id"`
+	ServerName   string    `json:"server_name"`
+	ServerHost   string    `json:"server_host"`
+	ConnectedAt  time.Time `json:"connected_at"`
+	HasRecording bool      `json:"has_recording"`
+	Offset       float64   `json:"offset"`     // 命中片段距会话开始的秒数，可作为回放的 t 参数
+	MatchedAt    time.Time `json:"matched_at"` // 命中片段的起始时间
+	Snippet      string    `json:"snippet"`    // 命中上下文，关键词以 [[ ]] 标记
+}
+
+// SearchTranscriptsResponse 全文检索响应
+type SearchTranscriptsResponse struct {
+	Data       []TranscriptSearchResult `json:"data"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	PageSize   int                      `json:"page_size"`
+	TotalPages int                      `json:"total_pages"`
+}
@@ -0,0 +1,252 @@
+package sshsession
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxRecordingLineSize 录像单行（单个事件）的长度上限，超出的行会被跳过
+	maxRecordingLineSize = 16 << 20
+	// recordingReadBufferSize 读取录像文件的缓冲区大小
+	recordingReadBufferSize = 64 * 1024
+	// recordingCheckpointGap 相邻重放起点的最小时间间隔（秒），限制索引大小
+	recordingCheckpointGap = 1.0
+)
+
+// terminalFullReset 终端完全复位序列（RIS），其后的输出不依赖之前的任何终端状态
+const terminalFullReset = "\x1bc"
+
+// RecordingEvent 录像中的单个事件
+type RecordingEvent struct {
+	Time float64 // 距会话开始的秒数
+	Type string  // o/i/r
+	Data string
+}
+
+// recordingCheckpoint 重放起点：从该偏移开始重放即可还原之后任意时刻的终端画面
+type recordingCheckpoint struct {
+	Time   float64
+	Offset int64
+	Width  int
+	Height int
+}
+
+// Recording 以流式方式读取的会话录像
+// 打开时扫描一遍文件建立索引（时长和重放起点），事件在回放时按需从文件读取，不整体载入内存
+// 非并发安全，由单个回放连接独占使用
+type Recording struct {
+	Header RecordingHeader
+
+	file     *os.File
+	reader   *bufio.Reader
+	offset   int64 // reader 下一次读取的文件偏移
+	duration float64
+	events   int
+	index    []recordingCheckpoint // 按时间升序，第一项为首个事件
+}
+
+// OpenRecordingFile 打开 asciicast v2 录像文件并建立索引，使用完毕后需调用 Close
+// 会话异常中断时文件末尾可能不完整，无法解析的事件行会被跳过
+func OpenRecordingFile(path string) (*Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, ErrRecordingNotFound
+	}
+
+	recording := &Recording{
+		file:   file,
+		reader: bufio.NewReaderSize(file, recordingReadBufferSize),
+	}
+	if err := recording.buildIndex(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return recording, nil
+}
+
+// buildIndex 解析文件头并扫描全部事件，记录时长和重放起点
+func (r *Recording) buildIndex() error {
+	line, _, err := r.readLine()
+	if err != nil {
+		if err == io.EOF {
+			return errors.New("recording file is empty")
+		}
+		return err
+	}
+	if err := json.Unmarshal(line, &r.Header); err != nil {
+		return fmt.Errorf("invalid recording header: %w", err)
+	}
+	if r.Header.Version != 2 {
+		return fmt.Errorf("unsupported recording version: %d", r.Header.Version)
+	}
+
+	width, height := r.Header.Width, r.Header.Height
+	r.index = []recordingCheckpoint{{Offset: r.offset, Width: width, Height: height}}
+
+	for {
+		event, offset, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		r.events++
+		if event.Time > r.duration {
+			r.duration = event.Time
+		}
+
+		switch event.Type {
+		case RecordingEventResize:
+			if cols, rows, ok := ParseResize(event.Data); ok {
+				width, height = cols, rows
+			}
+		case RecordingEventOutput:
+			if !strings.Contains(event.Data, terminalFullReset) {
+				continue
+			}
+			last := &r.index[len(r.index)-1]
+			if event.Time < last.Time {
+				continue
+			}
+			checkpoint := recordingCheckpoint{Time: event.Time, Offset: offset, Width: width, Height: height}
+			// 短时间内多次复位只保留最后一次
+			if len(r.index) > 1 && event.Time-last.Time < recordingCheckpointGap {
+				*last = checkpoint
+			} else {
+				r.index = append(r.index, checkpoint)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Duration 录像时长（秒）
+func (r *Recording) Duration() float64 {
+	return r.duration
+}
+
+// Len 录像中的事件数
+func (r *Recording) Len() int {
+	return r.events
+}
+
+// Rewind 将读取位置移动到 position 之前最近的重放起点，返回该处的终端尺寸
+// 调用方清屏后从这里依次重放到 position 即可得到该时刻的画面
+func (r *Recording) Rewind(position float64) (int, int, error) {
+	i := sort.Search(len(r.index), func(i int) bool {
+		return r.index[i].Time > position
+	})
+	if i > 0 {
+		i--
+	}
+	checkpoint := r.index[i]
+
+	if _, err := r.file.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	r.reader.Reset(r.file)
+	r.offset = checkpoint.Offset
+	return checkpoint.Width, checkpoint.Height, nil
+}
+
+// Next 读取下一个事件，读完时返回 io.EOF
+func (r *Recording) Next() (RecordingEvent, error) {
+	event, _, err := r.next()
+	return event, err
+}
+
+// Close 关闭录像文件
+func (r *Recording) Close() error {
+	return r.file.Close()
+}
+
+// next 读取下一个可解析的事件及其所在行的起始偏移
+func (r *Recording) next() (RecordingEvent, int64, error) {
+	for {
+		line, offset, err := r.readLine()
+		if err != nil {
+			return RecordingEvent{}, offset, err
+		}
+		if event, ok := parseRecordingEvent(line); ok {
+			return event, offset, nil
+		}
+	}
+}
+
+// readLine 读取一行（不含换行符）及其起始偏移，超过长度上限的行会被跳过
+func (r *Recording) readLine() ([]byte, int64, error) {
+	for {
+		start := r.offset
+		var line []byte
+		tooLong := false
+
+		for {
+			chunk, err := r.reader.ReadSlice('\n')
+			r.offset += int64(len(chunk))
+			if !tooLong {
+				if len(line)+len(chunk) > maxRecordingLineSize {
+					tooLong, line = true, nil
+				} else {
+					line = append(line, chunk...)
+				}
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil {
+				// 文件末尾没有换行的最后一行（可能不完整，由调用方解析时判断）
+				if err == io.EOF && len(line) > 0 && !tooLong {
+					return line, start, nil
+				}
+				return nil, start, err
+			}
+			break
+		}
+
+		if !tooLong {
+			return bytes.TrimRight(line, "\r\n"), start, nil
+		}
+	}
+}
+
+// parseRecordingEvent 解析事件行：[秒数, 类型, 数据]
+func parseRecordingEvent(line []byte) (RecordingEvent, bool) {
+	var raw [3]json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return RecordingEvent{}, false
+	}
+
+	var event RecordingEvent
+	if json.Unmarshal(raw[0], &event.Time) != nil ||
+		json.Unmarshal(raw[1], &event.Type) != nil ||
+		json.Unmarshal(raw[2], &event.Data) != nil {
+		return RecordingEvent{}, false
+	}
+	return event, true
+}
+
+// ParseResize 解析尺寸变化事件的 "COLSxROWS" 数据
+func ParseResize(data string) (int, int, bool) {
+	parts := strings.SplitN(data, "x", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	cols, err1 := strconv.Atoi(parts[0])
+	rows, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, false
+	}
+	return cols, rows, true
+}
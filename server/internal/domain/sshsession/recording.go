@@ -0,0 +1,277 @@
+package sshsession
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// recordingFlushInterval 录像缓冲区刷盘间隔（进程异常退出时最多丢失该时间段内的内容）
+	recordingFlushInterval = 2 * time.Second
+	// recordingTerm 录像头中记录的终端类型
+	recordingTerm = "xterm-256color"
+)
+
+// asciicast v2 事件类型
+const (
+	RecordingEventOutput = "o" // 终端输出
+	RecordingEventInput  = "i" // 用户输入
+	RecordingEventResize = "r" // 终端尺寸变化，数据格式为 "COLSxROWS"
+)
+
+var ErrRecordingNotFound = errors.New("session recording not found")
+
+// RecordingHeader asciicast v2 文件头
+type RecordingHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// RecordingStore 终端录像文件存储
+// 录像按日期分目录保存，数据库中只记录相对路径
+type RecordingStore struct {
+	dir string
+}
+
+// NewRecordingStore 创建录像存储
+func NewRecordingStore(dir string) *RecordingStore {
+	return &RecordingStore{dir: dir}
+}
+
+// Create 为会话创建录像文件并写入文件头
+func (s *RecordingStore) Create(sessionID string, width, height int, title string, recordInput bool) (*Recorder, error) {
+	now := time.Now()
+	relPath := filepath.Join(now.Format("2006-01-02"), sessionID+".cast")
+	fullPath := s.Path(relPath)
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	file, err := os.OpenFile(fullPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	r := &Recorder{
+		file:        file,
+		w:           bufio.NewWriter(file),
+		path:        relPath,
+		start:       now,
+		lastFlush:   now,
+		recordInput: recordInput,
+		pending:     make(map[string][]byte),
+	}
+
+	header, _ := json.Marshal(RecordingHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: now.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": recordingTerm},
+	})
+	r.writeLine(header)
+	if r.err != nil {
+		file.Close()
+		os.Remove(fullPath)
+		return nil, r.err
+	}
+
+	return r, nil
+}
+
+// Path 返回录像文件的完整路径
+func (s *RecordingStore) Path(relPath string) string {
+	return filepath.Join(s.dir, filepath.Clean(string(filepath.Separator)+relPath))
+}
+
+// Remove 删除录像文件（文件不存在时不返回错误）
+func (s *RecordingStore) Remove(relPath string) error {
+	if err := os.Remove(s.Path(relPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Recorder 单个终端会话的 asciicast v2 录像写入器
+// 所有方法都可以在 nil 上调用（未开启录像时直接忽略），并发安全
+type Recorder struct {
+	mu          sync.Mutex
+	file        *os.File
+	w           *bufio.Writer
+	path        string
+	start       time.Time
+	lastFlush   time.Time
+	recordInput bool
+	pending     map[string][]byte // 各事件流中尚未凑成完整 UTF-8 字符的尾部字节
+	closed      bool
+	err         error
+}
+
+// Path 返回录像文件的相对路径
+func (r *Recorder) Path() string {
+	if r == nil {
+		return ""
+	}
+	return r.path
+}
+
+// StartedAt 返回录像开始时间（事件时间均相对于该时间）
+func (r *Recorder) StartedAt() time.Time {
+	if r == nil {
+		return time.Time{}
+	}
+	return r.start
+}
+
+// Output 记录终端输出
+func (r *Recorder) Output(data []byte) {
+	r.writeData(RecordingEventOutput, data)
+}
+
+// Input 记录用户输入（未开启输入录制时忽略）
+func (r *Recorder) Input(data []byte) {
+	if r == nil || !r.recordInput {
+		return
+	}
+	r.writeData(RecordingEventInput, data)
+}
+
+// Resize 记录终端尺寸变化
+func (r *Recorder) Resize(cols, rows int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writeEvent(RecordingEventResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close 刷盘并关闭录像文件，返回文件大小
+func (r *Recorder) Close() (int64, error) {
+	if r == nil {
+		return 0, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, r.err
+	}
+	r.closed = true
+
+	// 输出残留的不完整字节（按原样替换为 U+FFFD）
+	for _, code := range []string{RecordingEventOutput, RecordingEventInput} {
+		if rest := r.pending[code]; len(rest) > 0 {
+			r.writeEvent(code, string(rest))
+		}
+	}
+
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	name := r.file.Name()
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	r.file = nil // 关闭后仍在进行中的读取不再写入
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), r.err
+}
+
+// writeData 写入输出/输入事件，跨数据块的多字节字符会被拼接完整后再写入
+func (r *Recorder) writeData(code string, data []byte) {
+	if r == nil || len(data) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buf := data
+	if rest := r.pending[code]; len(rest) > 0 {
+		buf = append(append([]byte{}, rest...), data...)
+	}
+
+	complete, rest := splitIncompleteUTF8(buf)
+	r.pending[code] = append(r.pending[code][:0], rest...)
+	if len(complete) > 0 {
+		r.writeEvent(code, string(complete))
+	}
+}
+
+// writeEvent 写入一条事件：[秒数, 类型, 数据]（调用方需持有锁）
+func (r *Recorder) writeEvent(code, data string) {
+	if r.err != nil || r.file == nil {
+		return
+	}
+
+	encoded, _ := json.Marshal(data)
+	elapsed := strconv.FormatFloat(time.Since(r.start).Seconds(), 'f', 6, 64)
+
+	line := make([]byte, 0, len(encoded)+len(elapsed)+10)
+	line = append(line, '[')
+	line = append(line, elapsed...)
+	line = append(line, `, "`...)
+	line = append(line, code...)
+	line = append(line, `", `...)
+	line = append(line, encoded...)
+	line = append(line, ']')
+	r.writeLine(line)
+}
+
+// writeLine 写入一行并按间隔刷盘；写入失败后停止录制（调用方需持有锁）
+func (r *Recorder) writeLine(line []byte) {
+	if r.err != nil {
+		return
+	}
+
+	if _, err := r.w.Write(line); err == nil {
+		err = r.w.WriteByte('\n')
+		if err != nil {
+			r.err = err
+		}
+	} else {
+		r.err = err
+	}
+
+	if r.err == nil && time.Since(r.lastFlush) >= recordingFlushInterval {
+		r.err = r.w.Flush()
+		r.lastFlush = time.Now()
+	}
+
+	if r.err != nil {
+		log.Printf("[Recording] 写入录像失败，停止录制: path=%s, error=%v", r.path, r.err)
+	}
+}
+
+// splitIncompleteUTF8 将数据拆分为完整部分和末尾不完整的多字节字符
+func splitIncompleteUTF8(p []byte) ([]byte, []byte) {
+	// UTF-8 字符最长 4 字节，只需检查末尾 3 个字节
+	for i := len(p) - 1; i >= 0 && i >= len(p)-3; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return p[:i], p[i:]
+			}
+			break
+		}
+	}
+	return p, nil
+}
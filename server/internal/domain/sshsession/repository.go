@@ -1,6 +1,8 @@
 package sshsession
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -16,6 +18,11 @@ type Repository interface {
 	GetStatistics(userID uuid.UUID) (*SSHSessionStatistics, error)
 	CloseSession(id uuid.UUID) error
 	GetActiveSessions() ([]SSHSession, error)
+	ListRecordingsBefore(before time.Time, limit int) ([]SSHSession, error)
+	ClearRecording(id uuid.UUID) error
+	CreateTranscript(transcript *SSHSessionTranscript) error
+	SearchTranscripts(scopeUserID uuid.UUID, req *SearchTranscriptsRequest) ([]TranscriptSearchResult, int64, error)
+	DeleteTranscriptsBefore(before time.Time) (int64, error)
 }
 
 type repository struct {
@@ -74,6 +81,7 @@ func (r *repository) List(userID uuid.UUID, req *ListSSHSessionsRequest) ([]SSHS
 			ssh_sessions.terminal_type, ssh_sessions.status, ssh_sessions.connected_at,
 			ssh_sessions.disconnected_at, ssh_sessions.duration, ssh_sessions.bytes_sent,
 			ssh_sessions.bytes_received, ssh_sessions.error_message,
+			ssh_sessions.has_recording, ssh_sessions.recording_size,
 			ssh_sessions.created_at, ssh_sessions.updated_at,
 			COALESCE(servers.name, '') as server_name,
 			COALESCE(servers.host, '') as server_host`).
@@ -192,3 +200,94 @@ func (r *repository) GetActiveSessions() ([]SSHSession, error) {
 	err := r.db.Where("status = ?", "active").Find(&sessions).Error
 	return sessions, err
 }
+
+// ListRecordingsBefore 获取在指定时间之前开始、且仍保留录像的会话（包括已软删除的会话）
+func (r *repository) ListRecordingsBefore(before time.Time, limit int) ([]SSHSession, error) {
+	var sessions []SSHSession
+	err := r.db.Unscoped().
+		Where("has_recording = ? AND connected_at < ?", true, before).
+		Order("connected_at ASC").
+		Limit(limit).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// ClearRecording 清除会话的录像信息（包括已软删除的会话）
+func (r *repository) ClearRecording(id uuid.UUID) error {
+	return r.db.Unscoped().Model(&SSHSession{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"has_recording":  false,
+		"recording_path": "",
+		"recording_size": 0,
+	}).Error
+}
+
+// CreateTranscript 保存终端输出文本片段
+func (r *repository) CreateTranscript(transcript *SSHSessionTranscript) error {
+	return r.db.Create(transcript).Error
+}
+
+// SearchTranscripts 全文检索终端输出，scopeUserID 不为空时只检索该用户的会话
+// 使用 simple 分词配置与短语匹配，"rm -rf" 会匹配相邻的 rm、rf 两个词
+func (r *repository) SearchTranscripts(scopeUserID uuid.UUID, req *SearchTranscriptsRequest) ([]TranscriptSearchResult, int64, error) {
+	var results []TranscriptSearchResult
+	var total int64
+
+	query := r.db.Table("ssh_session_transcripts").
+		Joins("JOIN ssh_sessions ON ssh_sessions.session_id = ssh_session_transcripts.session_id AND ssh_sessions.deleted_at IS NULL").
+		Joins("LEFT JOIN servers ON ssh_sessions.server_id = servers.id").
+		Where("to_tsvector('simple', ssh_session_transcripts.content) @@ phraseto_tsquery('simple', ?)", req.Query)
+
+	// 筛选条件
+	if scopeUserID != uuid.Nil {
+		query = query.Where("ssh_sessions.user_id = ?", scopeUserID)
+	} else if req.UserID != "" {
+		uid, err := uuid.Parse(req.UserID)
+		if err == nil {
+			query = query.Where("ssh_sessions.user_id = ?", uid)
+		}
+	}
+
+	if req.ServerID != "" {
+		serverID, err := uuid.Parse(req.ServerID)
+		if err == nil {
+			query = query.Where("ssh_sessions.server_id = ?", serverID)
+		}
+	}
+
+	if req.StartTime != nil {
+		query = query.Where("ssh_session_transcripts.started_at >= ?", *req.StartTime)
+	}
+	if req.EndTime != nil {
+		query = query.Where("ssh_session_transcripts.started_at <= ?", *req.EndTime)
+	}
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页
+	offset := (req.Page - 1) * req.Limit
+	if err := query.Select(`ssh_sessions.id, ssh_sessions.session_id, ssh_sessions.user_id,
+			ssh_sessions.server_id, ssh_sessions.connected_at, ssh_sessions.has_recording,
+			COALESCE(servers.name, '') as server_name,
+			COALESCE(servers.host, '') as server_host,
+			ssh_session_transcripts.offset_seconds as offset,
+			ssh_session_transcripts.started_at as matched_at,
+			ts_headline('simple', ssh_session_transcripts.content, phraseto_tsquery('simple', ?),
+				'StartSel=[[, StopSel=]], MaxWords=35, MinWords=15, MaxFragments=2') as snippet`, req.Query).
+		Order("ssh_session_transcripts.started_at DESC").
+		Offset(offset).
+		Limit(req.Limit).
+		Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// DeleteTranscriptsBefore 删除在指定时间之前记录的终端输出文本
+func (r *repository) DeleteTranscriptsBefore(before time.Time) (int64, error) {
+	result := r.db.Where("started_at < ?", before).Delete(&SSHSessionTranscript{})
+	return result.RowsAffected, result.Error
+}
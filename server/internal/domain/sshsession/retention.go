@@ -0,0 +1,83 @@
+package sshsession
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// recordingCleanupInterval 过期录像清理间隔
+const recordingCleanupInterval = time.Hour
+
+// RetentionDaysFunc 返回录像保留天数（0 表示永久保留）
+type RetentionDaysFunc func(ctx context.Context) int
+
+// RecordingJanitor 按保留天数定期清理过期的终端录像和输出文本
+type RecordingJanitor struct {
+	service       Service
+	retentionDays RetentionDaysFunc
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRecordingJanitor 创建录像清理器
+func NewRecordingJanitor(service Service, retentionDays RetentionDaysFunc) *RecordingJanitor {
+	return &RecordingJanitor{
+		service:       service,
+		retentionDays: retentionDays,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start 启动后台清理（启动时立即执行一次）
+func (j *RecordingJanitor) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(recordingCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			j.cleanup()
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台清理
+func (j *RecordingJanitor) Stop() {
+	close(j.stop)
+	j.wg.Wait()
+}
+
+// cleanup 执行一次清理
+func (j *RecordingJanitor) cleanup() {
+	days := j.retentionDays(context.Background())
+	if days <= 0 {
+		return
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	removed, err := j.service.CleanupRecordings(before)
+	if err != nil {
+		log.Printf("[Recording] 清理过期录像失败: error=%v", err)
+	}
+	if removed > 0 {
+		log.Printf("[Recording] 已清理过期录像: count=%d, before=%s", removed, before.Format(time.RFC3339))
+	}
+
+	deleted, err := j.service.CleanupTranscripts(before)
+	if err != nil {
+		log.Printf("[Transcript] 清理过期终端输出失败: error=%v", err)
+	}
+	if deleted > 0 {
+		log.Printf("[Transcript] 已清理过期终端输出: count=%d, before=%s", deleted, before.Format(time.RFC3339))
+	}
+}
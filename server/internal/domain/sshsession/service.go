@@ -2,7 +2,11 @@ package sshsession
 
 import (
 	"errors"
+	"log"
+	"os"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -11,8 +15,12 @@ var (
 	ErrSSHSessionNotFound    = errors.New("ssh session not found")
 	ErrInvalidSSHSessionData = errors.New("invalid ssh session data")
 	ErrUnauthorized          = errors.New("unauthorized access to ssh session")
+	ErrInvalidSearchQuery    = errors.New("search query must be 1-200 characters")
 )
 
+// maxSearchQueryLength 全文检索关键词的最大长度（字符）
+const maxSearchQueryLength = 200
+
 // Service SSH会话业务逻辑接口
 type Service interface {
 	CreateSSHSession(req *CreateSSHSessionRequest) (*SSHSession, error)
@@ -24,10 +32,18 @@ type Service interface {
 	GetStatistics(userID uuid.UUID) (*SSHSessionStatistics, error)
 	CloseSession(userID uuid.UUID, id uuid.UUID) error
 	UpdateSessionMetrics(sessionID string, bytesSent, bytesReceived int64) error
+	AttachRecording(sessionID string, path string, size int64) error
+	GetRecordingFile(userID uuid.UUID, id uuid.UUID, isAdmin bool) (*SSHSession, string, error)
+	OpenRecording(userID uuid.UUID, id uuid.UUID, isAdmin bool) (*SSHSession, *Recording, error)
+	CleanupRecordings(before time.Time) (int, error)
+	StartTranscript(sessionID string, start time.Time) *TranscriptWriter
+	SearchTranscripts(userID uuid.UUID, isAdmin bool, req *SearchTranscriptsRequest) (*SearchTranscriptsResponse, error)
+	CleanupTranscripts(before time.Time) (int64, error)
 }
 
 type service struct {
-	repo Repository
+	repo           Repository
+	recordingStore *RecordingStore
 }
 
 // NewService 创建SSH会话服务实例
@@ -35,6 +51,11 @@ func NewService(repo Repository) Service {
 	return &service{repo: repo}
 }
 
+// SetRecordingStore 设置录像存储
+func (s *service) SetRecordingStore(store *RecordingStore) {
+	s.recordingStore = store
+}
+
 // CreateSSHSession 创建SSH会话记录
 func (s *service) CreateSSHSession(req *CreateSSHSessionRequest) (*SSHSession, error) {
 	// 验证必填字段
@@ -80,13 +101,13 @@ func (s *service) UpdateSSHSession(userID uuid.UUID, id uuid.UUID, req *UpdateSS
 	updates := make(map[string]interface{})
 
 	if req.Status != "" {
-		validStatuses := map[string]bool{"active": true, "closed": true, "timeout": true}
+		validStatuses := map[string]bool{"active": true, "closed": true, "timeout": true, "terminated": true}
 		if !validStatuses[req.Status] {
 			return nil, errors.New("invalid status")
 		}
 		updates["status"] = req.Status
 
-		if req.Status == "closed" || req.Status == "timeout" {
+		if req.Status == "closed" || req.Status == "timeout" || req.Status == "terminated" {
 			now := time.Now()
 			updates["disconnected_at"] = now
 			duration := int(now.Sub(existingSession.ConnectedAt).Seconds())
@@ -225,3 +246,144 @@ func (s *service) UpdateSessionMetrics(sessionID string, bytesSent, bytesReceive
 
 	return s.repo.Update(session.ID, updates)
 }
+
+// AttachRecording 关联会话录像（由终端处理器在会话结束时调用）
+func (s *service) AttachRecording(sessionID string, path string, size int64) error {
+	session, err := s.repo.GetBySessionID(sessionID)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"has_recording":  true,
+		"recording_path": path,
+		"recording_size": size,
+	}
+
+	return s.repo.Update(session.ID, updates)
+}
+
+// GetRecordingFile 获取会话录像文件的完整路径（管理员可以访问所有用户的录像用于审计）
+func (s *service) GetRecordingFile(userID uuid.UUID, id uuid.UUID, isAdmin bool) (*SSHSession, string, error) {
+	session, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, "", ErrSSHSessionNotFound
+	}
+
+	// 验证所有权
+	if !isAdmin && session.UserID != userID {
+		return nil, "", ErrUnauthorized
+	}
+
+	if !session.HasRecording || session.RecordingPath == "" || s.recordingStore == nil {
+		return nil, "", ErrRecordingNotFound
+	}
+
+	path := s.recordingStore.Path(session.RecordingPath)
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", ErrRecordingNotFound
+	}
+
+	return session, path, nil
+}
+
+// OpenRecording 打开会话录像用于回放（调用方负责关闭）
+func (s *service) OpenRecording(userID uuid.UUID, id uuid.UUID, isAdmin bool) (*SSHSession, *Recording, error) {
+	session, path, err := s.GetRecordingFile(userID, id, isAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recording, err := OpenRecordingFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return session, recording, nil
+}
+
+// CleanupRecordings 删除在指定时间之前开始的会话录像，返回删除数量
+func (s *service) CleanupRecordings(before time.Time) (int, error) {
+	if s.recordingStore == nil {
+		return 0, nil
+	}
+
+	const batchSize = 500
+	removed := 0
+	for {
+		sessions, err := s.repo.ListRecordingsBefore(before, batchSize)
+		if err != nil {
+			return removed, err
+		}
+
+		for _, session := range sessions {
+			if err := s.recordingStore.Remove(session.RecordingPath); err != nil {
+				log.Printf("[Recording] 删除录像文件失败: session=%s, error=%v", session.ID, err)
+			}
+			if err := s.repo.ClearRecording(session.ID); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+
+		if len(sessions) < batchSize {
+			return removed, nil
+		}
+	}
+}
+
+// StartTranscript 开始记录会话的终端输出文本（用于全文检索），start 为空时使用当前时间
+func (s *service) StartTranscript(sessionID string, start time.Time) *TranscriptWriter {
+	if start.IsZero() {
+		start = time.Now()
+	}
+	return newTranscriptWriter(sessionID, start, s.repo.CreateTranscript)
+}
+
+// SearchTranscripts 全文检索终端输出（管理员可以检索所有用户的会话）
+func (s *service) SearchTranscripts(userID uuid.UUID, isAdmin bool, req *SearchTranscriptsRequest) (*SearchTranscriptsResponse, error) {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" || utf8.RuneCountInString(req.Query) > maxSearchQueryLength {
+		return nil, ErrInvalidSearchQuery
+	}
+
+	// 设置默认值
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 20
+	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+
+	scopeUserID := userID
+	if isAdmin {
+		scopeUserID = uuid.Nil
+	}
+
+	results, total, err := s.repo.SearchTranscripts(scopeUserID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 计算总页数
+	totalPages := int(total) / req.Limit
+	if int(total)%req.Limit > 0 {
+		totalPages++
+	}
+
+	return &SearchTranscriptsResponse{
+		Data:       results,
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.Limit,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// CleanupTranscripts 删除在指定时间之前记录的终端输出文本，返回删除数量
+func (s *service) CleanupTranscripts(before time.Time) (int64, error) {
+	return s.repo.DeleteTranscriptsBefore(before)
+}
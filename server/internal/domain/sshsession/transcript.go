@@ -0,0 +1,204 @@
+package sshsession
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// transcriptChunkSize 单个文本片段的最大字节数
+	transcriptChunkSize = 4096
+	// transcriptChunkInterval 单个文本片段覆盖的最长时间，决定检索结果的时间精度
+	transcriptChunkInterval = 10 * time.Second
+	// transcriptQueueSize 等待写入数据库的片段队列长度
+	transcriptQueueSize = 64
+)
+
+// ANSI 解析状态
+const (
+	ansiStateText         = iota // 普通文本
+	ansiStateEscape              // 收到 ESC
+	ansiStateCSI                 // ESC [ 控制序列
+	ansiStateString              // OSC/DCS 等字符串序列，以 BEL 或 ESC \ 结束
+	ansiStateStringEscape        // 字符串序列中收到 ESC
+)
+
+// TranscriptWriter 将终端输出去除 ANSI 控制序列后按片段写入数据库，用于全文检索
+// 所有方法都可以在 nil 上调用，并发安全
+type TranscriptWriter struct {
+	mu        sync.Mutex
+	sessionID string
+	start     time.Time
+	seq       int
+	buf       []byte
+	chunkAt   time.Time // 当前片段第一个字符的时间
+	state     int
+	dropped   int // 因队列已满被丢弃的片段数
+	closed    bool
+
+	queue chan *SSHSessionTranscript
+	done  chan struct{}
+}
+
+// newTranscriptWriter 创建文本记录器，片段由后台协程通过 save 写入
+func newTranscriptWriter(sessionID string, start time.Time, save func(*SSHSessionTranscript) error) *TranscriptWriter {
+	w := &TranscriptWriter{
+		sessionID: sessionID,
+		start:     start,
+		queue:     make(chan *SSHSessionTranscript, transcriptQueueSize),
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		for chunk := range w.queue {
+			if err := save(chunk); err != nil {
+				log.Printf("[Transcript] 保存终端输出失败: session=%s, seq=%d, error=%v", chunk.SessionID, chunk.Seq, err)
+			}
+		}
+	}()
+
+	return w
+}
+
+// Write 记录一段终端输出
+func (w *TranscriptWriter) Write(data []byte) {
+	if w == nil || len(data) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	now := time.Now()
+	for _, b := range data {
+		w.feed(b, now)
+	}
+
+	if len(w.buf) >= transcriptChunkSize || (len(w.buf) > 0 && now.Sub(w.chunkAt) >= transcriptChunkInterval) {
+		w.flush(false)
+	}
+}
+
+// Close 写入剩余内容并等待所有片段保存完成
+func (w *TranscriptWriter) Close() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	w.flush(true)
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+}
+
+// feed 处理一个字节，丢弃控制序列和不可见控制字符（调用方需持有锁）
+func (w *TranscriptWriter) feed(b byte, now time.Time) {
+	switch w.state {
+	case ansiStateEscape:
+		switch b {
+		case '[':
+			w.state = ansiStateCSI
+		case ']', 'P', 'X', '^', '_':
+			w.state = ansiStateString
+		default:
+			// 其他两字节序列（如 ESC =、ESC 7）
+			w.state = ansiStateText
+		}
+		return
+
+	case ansiStateCSI:
+		// 参数和中间字节之后以 0x40-0x7E 结束
+		if b >= 0x40 && b <= 0x7e {
+			w.state = ansiStateText
+		}
+		return
+
+	case ansiStateString:
+		switch b {
+		case 0x07:
+			w.state = ansiStateText
+		case 0x1b:
+			w.state = ansiStateStringEscape
+		}
+		return
+
+	case ansiStateStringEscape:
+		if b == '\\' {
+			w.state = ansiStateText
+		} else {
+			w.state = ansiStateString
+		}
+		return
+	}
+
+	switch {
+	case b == 0x1b:
+		w.state = ansiStateEscape
+		return
+	case b == '\b':
+		// 退格：删除当前片段中的最后一个字符
+		if len(w.buf) > 0 {
+			_, size := utf8.DecodeLastRune(w.buf)
+			w.buf = w.buf[:len(w.buf)-size]
+		}
+		return
+	case b == '\n' || b == '\t':
+	case b < 0x20 || b == 0x7f:
+		// 回车及其他控制字符直接丢弃
+		return
+	}
+
+	if len(w.buf) == 0 {
+		w.chunkAt = now
+	}
+	w.buf = append(w.buf, b)
+}
+
+// flush 将当前缓冲区作为一个片段加入保存队列（调用方需持有锁）
+// 非最终刷新时，末尾不完整的多字节字符保留到下一个片段
+func (w *TranscriptWriter) flush(final bool) {
+	complete, rest := w.buf, []byte(nil)
+	if !final {
+		complete, rest = splitIncompleteUTF8(w.buf)
+	}
+
+	content := strings.ToValidUTF8(string(complete), "")
+	if strings.TrimSpace(content) != "" {
+		chunk := &SSHSessionTranscript{
+			SessionID: w.sessionID,
+			Seq:       w.seq,
+			Offset:    w.chunkAt.Sub(w.start).Seconds(),
+			StartedAt: w.chunkAt,
+			Content:   content,
+		}
+		w.seq++
+
+		if final {
+			// 会话结束时等待队列腾出空间，保证最后的内容被保存
+			w.queue <- chunk
+		} else {
+			// 终端输出路径上不能等待数据库：队列已满说明保存跟不上，丢弃该片段
+			select {
+			case w.queue <- chunk:
+			default:
+				w.dropped++
+				log.Printf("[Transcript] 保存队列已满，丢弃终端输出片段: session=%s, seq=%d, dropped=%d", w.sessionID, chunk.Seq, w.dropped)
+			}
+		}
+	}
+
+	w.buf = append(w.buf[:0], rest...)
+}
@@ -12,8 +12,9 @@ type TabSessionSettings struct {
 	MaxTabs         int            `gorm:"not null;default:50;check:max_tabs > 0 AND max_tabs <= 200" json:"max_tabs"`
 	InactiveMinutes int            `gorm:"not null;default:60;check:inactive_minutes >= 5 AND inactive_minutes <= 1440" json:"inactive_minutes"`
 	Hibernate       bool           `gorm:"not null;default:true" json:"hibernate"`
-	SessionTimeout  int            `gorm:"not null;default:30;check:session_timeout >= 5 AND session_timeout <= 1440" json:"session_timeout"`    // 会话超时时间（分钟）
+	SessionTimeout  int            `gorm:"not null;default:30;check:session_timeout >= 5 AND session_timeout <= 1440" json:"session_timeout"`    // 登录会话无操作自动退出的时间（分钟，由前端执行，不影响终端会话）
 	RememberLogin   bool           `gorm:"not null;default:true" json:"remember_login"`                                                            // 是否允许记住登录状态
+	DetachGraceMinutes int         `gorm:"not null;default:10;check:detach_grace_minutes >= 1 AND detach_grace_minutes <= 1440" json:"detach_grace_minutes"` // 终端断线后保持会话等待重连的时间（分钟，仅在启用休眠时生效）
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
@@ -24,14 +25,31 @@ func (TabSessionSettings) TableName() string {
 	return "tab_session_settings"
 }
 
+// DefaultDetachGraceMinutes 默认的终端断线保持时间（分钟）
+const DefaultDetachGraceMinutes = 10
+
+// DetachGracePeriod 终端断线后保持会话的时间，未启用休眠时为 0（断线即关闭会话）
+func (t *TabSessionSettings) DetachGracePeriod() time.Duration {
+	if !t.Hibernate {
+		return 0
+	}
+	return time.Duration(t.DetachGraceMinutes) * time.Minute
+}
+
+// IdleTimeout 终端会话空闲（没有输入输出）超过该时间后由服务端断开
+func (t *TabSessionSettings) IdleTimeout() time.Duration {
+	return time.Duration(t.InactiveMinutes) * time.Minute
+}
+
 // DefaultTabSessionSettings 返回默认配置
 func DefaultTabSessionSettings() *TabSessionSettings {
 	return &TabSessionSettings{
-		MaxTabs:         50,
-		InactiveMinutes: 60,
-		Hibernate:       true,
-		SessionTimeout:  30,
-		RememberLogin:   true,
+		MaxTabs:            50,
+		InactiveMinutes:    60,
+		Hibernate:          true,
+		SessionTimeout:     30,
+		RememberLogin:      true,
+		DetachGraceMinutes: DefaultDetachGraceMinutes,
 	}
 }
 
@@ -46,14 +64,18 @@ func (t *TabSessionSettings) Validate() error {
 	if t.SessionTimeout < 5 || t.SessionTimeout > 1440 {
 		return ErrInvalidSessionTimeout
 	}
+	if t.DetachGraceMinutes < 1 || t.DetachGraceMinutes > 1440 {
+		return ErrInvalidDetachGraceMinutes
+	}
 	return nil
 }
 
 // 错误定义
 var (
-	ErrInvalidMaxTabs         = NewValidationError("max_tabs", "最大标签页数必须在1-200之间")
-	ErrInvalidInactiveMinutes = NewValidationError("inactive_minutes", "非活动时间必须在5-1440分钟之间")
-	ErrInvalidSessionTimeout  = NewValidationError("session_timeout", "会话超时时间必须在5-1440分钟之间")
+	ErrInvalidMaxTabs            = NewValidationError("max_tabs", "最大标签页数必须在1-200之间")
+	ErrInvalidInactiveMinutes    = NewValidationError("inactive_minutes", "非活动时间必须在5-1440分钟之间")
+	ErrInvalidSessionTimeout     = NewValidationError("session_timeout", "会话超时时间必须在5-1440分钟之间")
+	ErrInvalidDetachGraceMinutes = NewValidationError("detach_grace_minutes", "断线保持时间必须在1-1440分钟之间")
 )
 
 // ValidationError 验证错误
@@ -45,17 +45,25 @@ func (s *service) GetTabSessionConfig(ctx context.Context) (*TabSessionSettings,
 
 // SaveTabSessionConfig 保存标签/会话配置
 func (s *service) SaveTabSessionConfig(ctx context.Context, config *TabSessionSettings) error {
-	// 验证配置
-	if err := config.Validate(); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
-	}
-
 	// 获取现有配置
 	existing, err := s.repo.GetFirst(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get existing config: %w", err)
 	}
 
+	// 未提交断线保持时间时沿用现有值（兼容旧版客户端）
+	if config.DetachGraceMinutes == 0 {
+		config.DetachGraceMinutes = DefaultDetachGraceMinutes
+		if existing != nil {
+			config.DetachGraceMinutes = existing.DetachGraceMinutes
+		}
+	}
+
+	// 验证配置
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
 	if existing == nil {
 		// 创建新配置
 		return s.repo.Create(ctx, config)
@@ -0,0 +1,456 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/easyssh/server/internal/domain/auditlog"
+	"github.com/easyssh/server/internal/domain/auth"
+	"github.com/easyssh/server/internal/domain/server"
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/easyssh/server/internal/domain/user"
+	"github.com/easyssh/server/internal/pkg/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+const (
+	// minReconnectDelay 断线后首次重连的等待时间，之后每次翻倍
+	minReconnectDelay = 2 * time.Second
+	// maxReconnectDelay 重连等待时间上限
+	maxReconnectDelay = time.Minute
+	// keepaliveInterval SSH 保活请求间隔，用于及时发现断线
+	keepaliveInterval = 30 * time.Second
+	// dialTimeout 远程转发连接目标的超时时间
+	dialTimeout = 10 * time.Second
+)
+
+var errConnectionLost = errors.New("ssh connection lost")
+
+// Manager 隧道运行管理器，负责建立 SSH 连接、监听和转发，并在断线后自动重连
+type Manager struct {
+	repo            Repository
+	serverService   server.Service
+	encryptor       *crypto.Encryptor
+	hostKeyCallback ssh.HostKeyCallback
+	auditLogService auditlog.Service
+	policy          *Policy
+	userService     user.Service
+
+	mu      sync.Mutex
+	runners map[uuid.UUID]*runner
+}
+
+// NewManager 创建隧道运行管理器
+func NewManager(repo Repository, serverService server.Service, encryptor *crypto.Encryptor, hostKeyCallback ssh.HostKeyCallback) *Manager {
+	return &Manager{
+		repo:            repo,
+		serverService:   serverService,
+		encryptor:       encryptor,
+		hostKeyCallback: hostKeyCallback,
+		runners:         make(map[uuid.UUID]*runner),
+	}
+}
+
+// SetAuditLogService 设置审计日志服务（记录后台断线和失败）
+func (m *Manager) SetAuditLogService(auditLogService auditlog.Service) {
+	m.auditLogService = auditLogService
+}
+
+// SetPolicy 设置非管理员用户的隧道限制，userService 用于判断隧道所有者是否为管理员
+func (m *Manager) SetPolicy(policy *Policy, userService user.Service) {
+	m.policy = policy
+	m.userService = userService
+}
+
+// restricted 隧道所有者是否受限制（无法确认是管理员时按受限处理）
+func (m *Manager) restricted(ctx context.Context, userID uuid.UUID) bool {
+	if m.policy == nil {
+		return false
+	}
+	if m.userService == nil {
+		return true
+	}
+	u, err := m.userService.GetUser(ctx, userID)
+	return err != nil || u.Role != auth.RoleAdmin
+}
+
+// CheckPolicy 检查隧道配置是否符合所有者的限制
+func (m *Manager) CheckPolicy(ctx context.Context, tunnel *Tunnel) error {
+	if !m.restricted(ctx, tunnel.UserID) {
+		return nil
+	}
+	return m.policy.Check(ctx, tunnel)
+}
+
+// Restore 启动所有期望运行的隧道（服务启动时调用）
+func (m *Manager) Restore() error {
+	tunnels, err := m.repo.GetEnabled()
+	if err != nil {
+		return fmt.Errorf("failed to load tunnels: %w", err)
+	}
+
+	for i := range tunnels {
+		m.Start(&tunnels[i])
+	}
+	log.Printf("[Tunnel] 已恢复隧道: count=%d", len(tunnels))
+	return nil
+}
+
+// Start 启动隧道（已在运行时先停止再按新配置启动）
+func (m *Manager) Start(tunnel *Tunnel) {
+	m.Stop(tunnel.ID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	r := &runner{
+		tunnel:    *tunnel,
+		manager:   m,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		status:    StatusConnecting,
+		startedAt: now,
+	}
+
+	m.mu.Lock()
+	m.runners[tunnel.ID] = r
+	m.mu.Unlock()
+
+	go r.run(ctx)
+}
+
+// Stop 停止隧道并等待所有转发连接关闭（未运行时忽略）
+func (m *Manager) Stop(id uuid.UUID) {
+	m.mu.Lock()
+	r, ok := m.runners[id]
+	delete(m.runners, id)
+	m.mu.Unlock()
+
+	if ok {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// StopAll 停止所有隧道（服务关闭时调用）
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	ids := make([]uuid.UUID, 0, len(m.runners))
+	for id := range m.runners {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	for _, id := range ids {
+		m.Stop(id)
+	}
+}
+
+// State 获取隧道运行时状态（未运行时返回 stopped）
+func (m *Manager) State(id uuid.UUID) RuntimeState {
+	m.mu.Lock()
+	r, ok := m.runners[id]
+	m.mu.Unlock()
+
+	if !ok {
+		return RuntimeState{Status: StatusStopped}
+	}
+	return r.state()
+}
+
+// audit 记录隧道后台事件
+func (m *Manager) audit(tunnel *Tunnel, action auditlog.ActionType, status auditlog.Status, errMsg string, details map[string]interface{}) {
+	if m.auditLogService == nil {
+		return
+	}
+
+	data, _ := json.Marshal(details)
+	serverID := tunnel.ServerID
+	req := &auditlog.CreateAuditLogRequest{
+		UserID:   tunnel.UserID,
+		ServerID: &serverID,
+		Action:   action,
+		Resource: tunnel.ID.String(),
+		Status:   status,
+		Details:  string(data),
+		ErrorMsg: errMsg,
+	}
+	if err := m.auditLogService.Log(context.Background(), req); err != nil {
+		log.Printf("[Tunnel] 写入审计日志失败: %v", err)
+	}
+}
+
+// runner 单条运行中的隧道
+type runner struct {
+	tunnel  Tunnel
+	manager *Manager
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu          sync.Mutex
+	status      Status
+	lastError   string
+	reconnects  int
+	startedAt   time.Time
+	connectedAt *time.Time
+
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+	activeConns   atomic.Int64
+	totalConns    atomic.Int64
+}
+
+// state 生成运行时状态快照
+func (r *runner) state() RuntimeState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	startedAt := r.startedAt
+	return RuntimeState{
+		Status:        r.status,
+		BytesSent:     r.bytesSent.Load(),
+		BytesReceived: r.bytesReceived.Load(),
+		ActiveConns:   r.activeConns.Load(),
+		TotalConns:    r.totalConns.Load(),
+		Reconnects:    r.reconnects,
+		LastError:     r.lastError,
+		StartedAt:     &startedAt,
+		ConnectedAt:   r.connectedAt,
+	}
+}
+
+// setStatus 更新运行状态
+func (r *runner) setStatus(status Status, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status = status
+	switch status {
+	case StatusRunning:
+		now := time.Now()
+		r.connectedAt = &now
+		r.lastError = ""
+	case StatusReconnecting:
+		r.reconnects++
+	}
+	if err != nil {
+		r.lastError = err.Error()
+	}
+}
+
+// run 建立连接并转发，断线后按指数退避重连，直到隧道被停止
+func (r *runner) run(ctx context.Context) {
+	defer close(r.done)
+
+	t := &r.tunnel
+	delay := minReconnectDelay
+	for {
+		connectedAt := time.Now()
+		err := r.serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 服务器已删除、无权访问或隧道不再被允许时重连没有意义
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, server.ErrServerNotFound) || errors.Is(err, server.ErrUnauthorized) ||
+			errors.Is(err, ErrTunnelNotAllowed) {
+			log.Printf("[Tunnel] 隧道无法启动，已停止重连: tunnel=%s, error=%v", t.ID, err)
+			r.setStatus(StatusFailed, err)
+			r.manager.audit(t, auditlog.ActionTunnelDisconnect, auditlog.StatusFailure, err.Error(), map[string]interface{}{
+				"name":      t.Name,
+				"reconnect": false,
+			})
+			return
+		}
+
+		// 连接稳定运行过一段时间后，重新从最短等待时间开始退避
+		if time.Since(connectedAt) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+
+		log.Printf("[Tunnel] 隧道断开，%s 后重连: tunnel=%s, error=%v", delay, t.ID, err)
+		r.setStatus(StatusReconnecting, err)
+		r.manager.audit(t, auditlog.ActionTunnelDisconnect, auditlog.StatusWarning, err.Error(), map[string]interface{}{
+			"name":      t.Name,
+			"reconnect": true,
+			"delay_ms":  delay.Milliseconds(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+		r.setStatus(StatusConnecting, nil)
+	}
+}
+
+// serve 建立一次 SSH 连接并开始监听，直到连接断开或隧道被停止
+// 每次连接都重新读取服务器配置，跳板机、代理和认证信息的修改在重连后生效
+func (r *runner) serve(ctx context.Context) error {
+	t := &r.tunnel
+	srv, err := r.manager.serverService.GetByID(ctx, t.UserID, t.ServerID)
+	if err != nil {
+		return err
+	}
+
+	// 每次连接都重新检查（服务重启恢复的隧道、所有者不再是管理员等）
+	restricted := r.manager.restricted(ctx, t.UserID)
+	if restricted {
+		if err := r.manager.policy.Check(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	client, err := sshDomain.NewClient(srv, r.manager.encryptor, r.manager.hostKeyCallback)
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(srv.Host, srv.Port); err != nil {
+		return err
+	}
+	defer client.Close()
+	conn := client.GetRawConnection()
+
+	var listener net.Listener
+	var dial func() (net.Conn, error)
+	switch t.Type {
+	case TypeLocal:
+		listener, err = net.Listen("tcp", t.BindAddr())
+		dial = func() (net.Conn, error) { return conn.Dial("tcp", t.TargetAddr()) }
+	case TypeRemote:
+		listener, err = conn.Listen("tcp", t.BindAddr())
+		dial = func() (net.Conn, error) { return net.DialTimeout("tcp", t.TargetAddr(), dialTimeout) }
+		if restricted {
+			dial = func() (net.Conn, error) { return r.manager.policy.DialTarget(ctx, t) }
+		}
+	default:
+		err = fmt.Errorf("unsupported tunnel type: %s", t.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", t.BindAddr(), err)
+	}
+	defer listener.Close()
+
+	// 隧道停止或 SSH 连接断开时关闭监听，使 Accept 返回
+	lost := make(chan struct{})
+	go func() {
+		conn.Wait()
+		close(lost)
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-lost:
+		}
+		listener.Close()
+	}()
+	go r.keepalive(conn, lost)
+
+	r.setStatus(StatusRunning, nil)
+	log.Printf("[Tunnel] 隧道已建立: tunnel=%s, type=%s, bind=%s, target=%s", t.ID, t.Type, t.BindAddr(), t.TargetAddr())
+
+	var forwards sync.WaitGroup
+	defer forwards.Wait()
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			select {
+			case <-lost:
+				return errConnectionLost
+			default:
+				return err
+			}
+		}
+
+		forwards.Add(1)
+		go func() {
+			defer forwards.Done()
+			r.forward(ctx, local, dial)
+		}()
+	}
+}
+
+// keepalive 定期发送保活请求，失败时关闭连接触发重连
+func (r *runner) keepalive(conn *ssh.Client, lost <-chan struct{}) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lost:
+			return
+		case <-ticker.C:
+			if _, _, err := conn.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// forward 连接目标并双向复制数据，统计流量
+func (r *runner) forward(ctx context.Context, local net.Conn, dial func() (net.Conn, error)) {
+	defer local.Close()
+	r.totalConns.Add(1)
+
+	remote, err := dial()
+	if err != nil {
+		log.Printf("[Tunnel] 连接转发目标失败: tunnel=%s, target=%s, error=%v", r.tunnel.ID, r.tunnel.TargetAddr(), err)
+		return
+	}
+	defer remote.Close()
+
+	r.activeConns.Add(1)
+	defer r.activeConns.Add(-1)
+
+	// 隧道停止时关闭两端，使复制立即结束
+	stop := context.AfterFunc(ctx, func() {
+		local.Close()
+		remote.Close()
+	})
+	defer stop()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(&countingWriter{w: remote, n: &r.bytesSent}, local)
+		remote.Close()
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(&countingWriter{w: local, n: &r.bytesReceived}, remote)
+		local.Close()
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
+
+// countingWriter 统计写入字节数
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,121 @@
+package tunnel
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Type 转发类型
+type Type string
+
+const (
+	TypeLocal  Type = "local"  // 本地转发：在 API 主机监听，经由服务器连接目标（ssh -L）
+	TypeRemote Type = "remote" // 远程转发：在服务器上监听，由 API 主机连接目标（ssh -R）
+)
+
+// Status 隧道运行状态（仅存在于内存中）
+type Status string
+
+const (
+	StatusStopped      Status = "stopped"      // 未运行
+	StatusConnecting   Status = "connecting"   // 正在建立 SSH 连接和监听
+	StatusRunning      Status = "running"      // 正在转发
+	StatusReconnecting Status = "reconnecting" // 连接断开，等待重连
+	StatusFailed       Status = "failed"       // 无法恢复的错误（如服务器已删除），不再重连
+)
+
+// defaultBindHost 未指定监听地址时只监听回环地址，与 OpenSSH 的默认行为一致
+const defaultBindHost = "127.0.0.1"
+
+// Tunnel 端口转发隧道模型
+type Tunnel struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	ServerID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"server_id"`
+	Name        string         `gorm:"type:varchar(100);not null" json:"name"`
+	Type        Type           `gorm:"type:varchar(10);not null" json:"type"` // local/remote
+	BindHost    string         `gorm:"type:varchar(255);not null" json:"bind_host"`
+	BindPort    int            `gorm:"not null" json:"bind_port"`
+	TargetHost  string         `gorm:"type:varchar(255);not null" json:"target_host"`
+	TargetPort  int            `gorm:"not null" json:"target_port"`
+	Enabled     bool           `gorm:"default:false;index" json:"enabled"` // 期望运行状态，服务重启后自动恢复
+	Description string         `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate GORM钩子：创建前生成UUID
+func (t *Tunnel) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName 指定表名
+func (Tunnel) TableName() string {
+	return "tunnels"
+}
+
+// BindAddr 监听地址（本地转发在 API 主机上，远程转发在服务器上）
+func (t *Tunnel) BindAddr() string {
+	return net.JoinHostPort(t.BindHost, strconv.Itoa(t.BindPort))
+}
+
+// TargetAddr 转发目标地址（本地转发从服务器连接，远程转发从 API 主机连接）
+func (t *Tunnel) TargetAddr() string {
+	return net.JoinHostPort(t.TargetHost, strconv.Itoa(t.TargetPort))
+}
+
+// RuntimeState 隧道运行时状态
+type RuntimeState struct {
+	Status        Status     `json:"status"`
+	BytesSent     int64      `json:"bytes_sent"`     // 监听端 -> 目标
+	BytesReceived int64      `json:"bytes_received"` // 目标 -> 监听端
+	ActiveConns   int64      `json:"active_conns"`   // 正在转发的连接数
+	TotalConns    int64      `json:"total_conns"`    // 启动以来接受的连接数
+	Reconnects    int        `json:"reconnects"`     // 启动以来的重连次数
+	LastError     string     `json:"last_error,omitempty"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	ConnectedAt   *time.Time `json:"connected_at,omitempty"` // 最近一次连接成功的时间
+}
+
+// TunnelDetail 隧道配置及运行时状态
+type TunnelDetail struct {
+	*Tunnel
+	Runtime RuntimeState `json:"runtime"`
+}
+
+// CreateTunnelRequest 创建隧道请求
+type CreateTunnelRequest struct {
+	ServerID    string `json:"server_id" binding:"required"`
+	Name        string `json:"name" binding:"required,max=100"`
+	Type        Type   `json:"type" binding:"required,oneof=local remote"`
+	BindHost    string `json:"bind_host,omitempty"` // 默认 127.0.0.1
+	BindPort    int    `json:"bind_port" binding:"required,min=1,max=65535"`
+	TargetHost  string `json:"target_host" binding:"required,max=255"`
+	TargetPort  int    `json:"target_port" binding:"required,min=1,max=65535"`
+	Start       bool   `json:"start,omitempty"` // 创建后立即启动
+	Description string `json:"description,omitempty"`
+}
+
+// UpdateTunnelRequest 更新隧道请求（运行中的隧道会按新配置重启）
+type UpdateTunnelRequest struct {
+	Name        string  `json:"name,omitempty" binding:"max=100"`
+	BindHost    *string `json:"bind_host,omitempty"`
+	BindPort    *int    `json:"bind_port,omitempty" binding:"omitempty,min=1,max=65535"`
+	TargetHost  string  `json:"target_host,omitempty" binding:"max=255"`
+	TargetPort  *int    `json:"target_port,omitempty" binding:"omitempty,min=1,max=65535"`
+	Description *string `json:"description,omitempty"`
+}
+
+// ListTunnelsRequest 隧道列表查询请求
+type ListTunnelsRequest struct {
+	ServerID string `form:"server_id" json:"server_id"`
+	Type     Type   `form:"type" json:"type"`
+}
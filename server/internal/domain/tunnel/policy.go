@@ -0,0 +1,121 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var ErrTunnelNotAllowed = errors.New("tunnel is not allowed")
+
+// Policy 非管理员用户的隧道限制（管理员不受限制）
+//   - 本地转发在 API 主机上监听：只能监听回环地址，端口在配置的范围内
+//   - 远程转发由 API 主机连接目标：目标必须在允许列表中，避免借此访问 API 主机本机或内网的服务（如数据库、Redis）
+type Policy struct {
+	minPort int
+	maxPort int
+	nets    []*net.IPNet    // 允许的目标网段（单个 IP 按 /32、/128 处理）
+	hosts   map[string]bool // 允许的目标主机名（小写）
+}
+
+// NewPolicy 创建隧道限制，remoteTargets 为允许远程转发连接的 IP、CIDR 或主机名
+func NewPolicy(minPort, maxPort int, remoteTargets []string) *Policy {
+	p := &Policy{
+		minPort: minPort,
+		maxPort: maxPort,
+		hosts:   make(map[string]bool),
+	}
+
+	for _, target := range remoteTargets {
+		if _, ipNet, err := net.ParseCIDR(target); err == nil {
+			p.nets = append(p.nets, ipNet)
+		} else if ip := net.ParseIP(target); ip != nil {
+			bits := 128
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 32
+			}
+			p.nets = append(p.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		} else {
+			p.hosts[strings.ToLower(target)] = true
+		}
+	}
+	return p
+}
+
+// Check 检查隧道配置是否符合限制（远程转发的主机名目标会解析后检查）
+func (p *Policy) Check(ctx context.Context, t *Tunnel) error {
+	switch t.Type {
+	case TypeLocal:
+		if !isLoopbackHost(t.BindHost) {
+			return fmt.Errorf("%w: local forwards may only listen on a loopback address", ErrTunnelNotAllowed)
+		}
+		if t.BindPort < p.minPort || t.BindPort > p.maxPort {
+			return fmt.Errorf("%w: bind port must be between %d and %d", ErrTunnelNotAllowed, p.minPort, p.maxPort)
+		}
+	case TypeRemote:
+		if _, err := p.resolveTarget(ctx, t.TargetHost); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DialTarget 连接远程转发的目标
+// 每次连接都重新解析并检查，防止域名解析结果变化后绕过限制
+func (p *Policy) DialTarget(ctx context.Context, t *Tunnel) (net.Conn, error) {
+	host, err := p.resolveTarget(ctx, t.TargetHost)
+	if err != nil {
+		return nil, err
+	}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	return dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(t.TargetPort)))
+}
+
+// resolveTarget 返回允许连接的目标地址：允许列表中的主机名原样返回，其他目标返回解析出的第一个允许的 IP
+func (p *Policy) resolveTarget(ctx context.Context, host string) (string, error) {
+	if p.hosts[strings.ToLower(host)] {
+		return host, nil
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		if p.allowsIP(ip) {
+			return ip.String(), nil
+		}
+	}
+	return "", fmt.Errorf("%w: remote forward target %s is not in the allowed list", ErrTunnelNotAllowed, host)
+}
+
+// allowsIP 检查 IP 是否在允许的目标网段内
+func (p *Policy) allowsIP(ip net.IP) bool {
+	for _, ipNet := range p.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopbackHost 是否为回环地址（localhost 或 127.0.0.0/8、::1）
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,88 @@
+package tunnel
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Repository 隧道数据访问接口
+type Repository interface {
+	Create(tunnel *Tunnel) error
+	Save(tunnel *Tunnel) error
+	Delete(id uuid.UUID) error
+	GetByID(id uuid.UUID) (*Tunnel, error)
+	List(userID uuid.UUID, req *ListTunnelsRequest) ([]Tunnel, error)
+	SetEnabled(id uuid.UUID, enabled bool) error
+	GetEnabled() ([]Tunnel, error)
+	CountEnabledLocalByBind(host string, port int, excludeID uuid.UUID) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建隧道仓储实例
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Create 创建隧道
+func (r *repository) Create(tunnel *Tunnel) error {
+	return r.db.Create(tunnel).Error
+}
+
+// Save 保存隧道配置
+func (r *repository) Save(tunnel *Tunnel) error {
+	return r.db.Save(tunnel).Error
+}
+
+// Delete 删除隧道（软删除）
+func (r *repository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&Tunnel{}).Error
+}
+
+// GetByID 根据ID获取隧道
+func (r *repository) GetByID(id uuid.UUID) (*Tunnel, error) {
+	var tunnel Tunnel
+	if err := r.db.Where("id = ?", id).First(&tunnel).Error; err != nil {
+		return nil, err
+	}
+	return &tunnel, nil
+}
+
+// List 获取用户的隧道列表
+func (r *repository) List(userID uuid.UUID, req *ListTunnelsRequest) ([]Tunnel, error) {
+	var tunnels []Tunnel
+
+	query := r.db.Model(&Tunnel{}).Where("user_id = ?", userID)
+	if req.ServerID != "" {
+		query = query.Where("server_id = ?", req.ServerID)
+	}
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+
+	err := query.Order("created_at DESC").Find(&tunnels).Error
+	return tunnels, err
+}
+
+// SetEnabled 更新隧道的期望运行状态
+func (r *repository) SetEnabled(id uuid.UUID, enabled bool) error {
+	return r.db.Model(&Tunnel{}).Where("id = ?", id).Update("enabled", enabled).Error
+}
+
+// GetEnabled 获取所有需要运行的隧道（用于启动时恢复）
+func (r *repository) GetEnabled() ([]Tunnel, error) {
+	var tunnels []Tunnel
+	err := r.db.Where("enabled = ?", true).Find(&tunnels).Error
+	return tunnels, err
+}
+
+// CountEnabledLocalByBind 统计在 API 主机上使用相同监听地址的其他运行中本地转发
+func (r *repository) CountEnabledLocalByBind(host string, port int, excludeID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&Tunnel{}).
+		Where("type = ? AND bind_host = ? AND bind_port = ? AND enabled = ? AND id <> ?", TypeLocal, host, port, true, excludeID).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,243 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/easyssh/server/internal/domain/server"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTunnelNotFound    = errors.New("tunnel not found")
+	ErrUnauthorized      = errors.New("unauthorized access to tunnel")
+	ErrInvalidServer     = errors.New("server not found or not owned by user")
+	ErrInvalidTunnelData = errors.New("invalid tunnel data")
+	ErrBindAddressInUse  = errors.New("another running tunnel is already listening on this address")
+)
+
+// Service 隧道业务逻辑接口
+type Service interface {
+	Create(ctx context.Context, userID uuid.UUID, req *CreateTunnelRequest) (*TunnelDetail, error)
+	Update(ctx context.Context, userID, id uuid.UUID, req *UpdateTunnelRequest) (*TunnelDetail, error)
+	Delete(userID, id uuid.UUID) error
+	Get(userID, id uuid.UUID) (*TunnelDetail, error)
+	List(userID uuid.UUID, req *ListTunnelsRequest) ([]*TunnelDetail, error)
+	Start(userID, id uuid.UUID) (*TunnelDetail, error)
+	Stop(userID, id uuid.UUID) (*TunnelDetail, error)
+}
+
+type service struct {
+	repo          Repository
+	serverService server.Service
+	manager       *Manager
+}
+
+// NewService 创建隧道服务实例
+func NewService(repo Repository, serverService server.Service, manager *Manager) Service {
+	return &service{
+		repo:          repo,
+		serverService: serverService,
+		manager:       manager,
+	}
+}
+
+// detail 附加运行时状态
+func (s *service) detail(tunnel *Tunnel) *TunnelDetail {
+	return &TunnelDetail{Tunnel: tunnel, Runtime: s.manager.State(tunnel.ID)}
+}
+
+// getOwned 获取隧道并验证所有权
+func (s *service) getOwned(userID, id uuid.UUID) (*Tunnel, error) {
+	tunnel, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrTunnelNotFound
+	}
+	if tunnel.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return tunnel, nil
+}
+
+// checkBindAddress 本地转发在 API 主机上监听，同一地址只能有一条运行中的隧道
+func (s *service) checkBindAddress(tunnel *Tunnel) error {
+	if tunnel.Type != TypeLocal {
+		return nil
+	}
+	count, err := s.repo.CountEnabledLocalByBind(tunnel.BindHost, tunnel.BindPort, tunnel.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBindAddressInUse
+	}
+	return nil
+}
+
+// Create 创建隧道
+func (s *service) Create(ctx context.Context, userID uuid.UUID, req *CreateTunnelRequest) (*TunnelDetail, error) {
+	serverID, err := uuid.Parse(req.ServerID)
+	if err != nil {
+		return nil, ErrInvalidServer
+	}
+	if _, err := s.serverService.GetByID(ctx, userID, serverID); err != nil {
+		return nil, ErrInvalidServer
+	}
+
+	tunnel := &Tunnel{
+		UserID:      userID,
+		ServerID:    serverID,
+		Name:        strings.TrimSpace(req.Name),
+		Type:        req.Type,
+		BindHost:    strings.TrimSpace(req.BindHost),
+		BindPort:    req.BindPort,
+		TargetHost:  strings.TrimSpace(req.TargetHost),
+		TargetPort:  req.TargetPort,
+		Description: req.Description,
+	}
+	if tunnel.BindHost == "" {
+		tunnel.BindHost = defaultBindHost
+	}
+	if tunnel.Name == "" || tunnel.TargetHost == "" {
+		return nil, ErrInvalidTunnelData
+	}
+	if err := s.manager.CheckPolicy(ctx, tunnel); err != nil {
+		return nil, err
+	}
+
+	if req.Start {
+		tunnel.Enabled = true
+		if err := s.checkBindAddress(tunnel); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Create(tunnel); err != nil {
+		return nil, err
+	}
+
+	if tunnel.Enabled {
+		s.manager.Start(tunnel)
+	}
+	return s.detail(tunnel), nil
+}
+
+// Update 更新隧道配置，运行中的隧道按新配置重启
+func (s *service) Update(ctx context.Context, userID, id uuid.UUID, req *UpdateTunnelRequest) (*TunnelDetail, error) {
+	tunnel, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		tunnel.Name = name
+	}
+	if req.BindHost != nil {
+		tunnel.BindHost = strings.TrimSpace(*req.BindHost)
+		if tunnel.BindHost == "" {
+			tunnel.BindHost = defaultBindHost
+		}
+	}
+	if req.BindPort != nil {
+		tunnel.BindPort = *req.BindPort
+	}
+	if host := strings.TrimSpace(req.TargetHost); host != "" {
+		tunnel.TargetHost = host
+	}
+	if req.TargetPort != nil {
+		tunnel.TargetPort = *req.TargetPort
+	}
+	if req.Description != nil {
+		tunnel.Description = *req.Description
+	}
+	if err := s.manager.CheckPolicy(ctx, tunnel); err != nil {
+		return nil, err
+	}
+
+	if tunnel.Enabled {
+		if err := s.checkBindAddress(tunnel); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Save(tunnel); err != nil {
+		return nil, err
+	}
+
+	if tunnel.Enabled {
+		s.manager.Start(tunnel)
+	}
+	return s.detail(tunnel), nil
+}
+
+// Delete 停止并删除隧道
+func (s *service) Delete(userID, id uuid.UUID) error {
+	if _, err := s.getOwned(userID, id); err != nil {
+		return err
+	}
+
+	s.manager.Stop(id)
+	return s.repo.Delete(id)
+}
+
+// Get 获取隧道详情
+func (s *service) Get(userID, id uuid.UUID) (*TunnelDetail, error) {
+	tunnel, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(tunnel), nil
+}
+
+// List 获取用户的隧道列表
+func (s *service) List(userID uuid.UUID, req *ListTunnelsRequest) ([]*TunnelDetail, error) {
+	tunnels, err := s.repo.List(userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make([]*TunnelDetail, len(tunnels))
+	for i := range tunnels {
+		details[i] = s.detail(&tunnels[i])
+	}
+	return details, nil
+}
+
+// Start 启动隧道（已在运行时重启），服务重启后会自动恢复
+func (s *service) Start(userID, id uuid.UUID) (*TunnelDetail, error) {
+	tunnel, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.manager.CheckPolicy(context.Background(), tunnel); err != nil {
+		return nil, err
+	}
+	if err := s.checkBindAddress(tunnel); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetEnabled(id, true); err != nil {
+		return nil, err
+	}
+	tunnel.Enabled = true
+
+	s.manager.Start(tunnel)
+	return s.detail(tunnel), nil
+}
+
+// Stop 停止隧道
+func (s *service) Stop(userID, id uuid.UUID) (*TunnelDetail, error) {
+	tunnel, err := s.getOwned(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetEnabled(id, false); err != nil {
+		return nil, err
+	}
+	tunnel.Enabled = false
+
+	s.manager.Stop(id)
+	return s.detail(tunnel), nil
+}
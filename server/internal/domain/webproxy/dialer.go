@@ -0,0 +1,66 @@
+package webproxy
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/easyssh/server/internal/domain/server"
+	sshDomain "github.com/easyssh/server/internal/domain/ssh"
+	"github.com/google/uuid"
+)
+
+// targetHost 反向代理只访问服务器本机监听的服务
+const targetHost = "127.0.0.1"
+
+// Dialer 经由服务器 SSH 连接的 direct-tcpip 通道访问服务器本机端口
+// 浏览器加载一个页面会发起大量请求，SSH 连接由连接管理器按用户和服务器复用（与终端、SFTP 共享）
+type Dialer struct {
+	connManager *sshDomain.ConnectionManager
+}
+
+// NewDialer 创建反向代理拨号器
+func NewDialer(connManager *sshDomain.ConnectionManager) *Dialer {
+	return &Dialer{connManager: connManager}
+}
+
+// TargetAddr 反向代理访问的目标地址
+func TargetAddr(port int) string {
+	return net.JoinHostPort(targetHost, strconv.Itoa(port))
+}
+
+// Dial 连接服务器本机端口（srv 需由 server.Service.GetByID 加载，包含跳板机链）
+// SSH 连接已失效时重新建立一次
+func (d *Dialer) Dial(ctx context.Context, userID uuid.UUID, srv *server.Server, port int) (net.Conn, error) {
+	lease, err := d.connManager.AcquireForward(ctx, userID.String(), srv)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := lease.Client().GetRawConnection().DialContext(ctx, "tcp", TargetAddr(port))
+	if err != nil && ctx.Err() == nil && !lease.Client().IsConnected() {
+		lease.Discard()
+		if lease, err = d.connManager.AcquireForward(ctx, userID.String(), srv); err != nil {
+			return nil, err
+		}
+		conn, err = lease.Client().GetRawConnection().DialContext(ctx, "tcp", TargetAddr(port))
+	}
+	if err != nil {
+		lease.Release()
+		return nil, err
+	}
+
+	return &trackedConn{Conn: conn, lease: lease}, nil
+}
+
+// trackedConn 关闭时释放 SSH 连接的租用
+type trackedConn struct {
+	net.Conn
+	lease *sshDomain.Lease
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.lease.Release()
+	return err
+}
@@ -10,6 +10,22 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// compareAndExpireScript 仅当键的值等于指定值时重新设置过期时间（毫秒）
+var compareAndExpireScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// compareAndDeleteScript 仅当键的值等于指定值时删除
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
 // RedisClient Redis 客户端封装
 type RedisClient struct {
 	client *redis.Client
@@ -70,6 +86,24 @@ func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Du
 	return r.client.Expire(ctx, key, expiration).Err()
 }
 
+// ExpireIfValue 仅当键的值等于 value 时设置过期时间（用于续期自己持有的锁），返回是否设置成功
+func (r *RedisClient) ExpireIfValue(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	n, err := compareAndExpireScript.Run(ctx, r.client, []string{key}, value, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// DeleteIfValue 仅当键的值等于 value 时删除（用于释放自己持有的锁），返回是否删除
+func (r *RedisClient) DeleteIfValue(ctx context.Context, key, value string) (bool, error) {
+	n, err := compareAndDeleteScript.Run(ctx, r.client, []string{key}, value).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
 // TTL 获取剩余过期时间
 func (r *RedisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
 	return r.client.TTL(ctx, key).Result()
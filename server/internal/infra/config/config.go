@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 应用配置
@@ -12,6 +13,7 @@ type Config struct {
 	Database DatabaseConfig
 	Redis    RedisConfig
 	JWT      JWTConfig
+	Tunnel   TunnelConfig
 }
 
 // ServerConfig 服务器配置
@@ -20,6 +22,7 @@ type ServerConfig struct {
 	Env           string // development, production
 	EncryptionKey string // 加密密钥（16、24 或 32 字节用于 AES）
 	WebDevPort    int    // 前端开发端口（从 WEB_PORT 读取）
+	RecordingDir  string // 终端会话录像存储目录
 }
 
 // DatabaseConfig 数据库配置
@@ -45,6 +48,13 @@ type RedisConfig struct {
 	DB       int
 }
 
+// TunnelConfig 端口转发隧道配置（仅限制非管理员用户）
+type TunnelConfig struct {
+	MinPort       int      // 本地转发允许监听的最小端口
+	MaxPort       int      // 本地转发允许监听的最大端口
+	RemoteTargets []string // 远程转发允许连接的目标（IP、CIDR 或主机名），为空时不允许非管理员创建远程转发
+}
+
 // JWTConfig JWT 配置
 type JWTConfig struct {
 	Secret                   string
@@ -63,6 +73,7 @@ func Load() (*Config, error) {
 			Env:           getEnv("ENV", "development"),
 			EncryptionKey: getEnv("ENCRYPTION_KEY", "easyssh-encryption-key-32byte"), // 32 字节
 			WebDevPort:    getEnvInt("WEB_PORT", 8520),
+			RecordingDir:  getEnv("RECORDING_DIR", "data/recordings"),
 		},
 		Database: DatabaseConfig{
 			Host:            getEnv("DB_HOST", "localhost"),
@@ -90,6 +101,11 @@ func Load() (*Config, error) {
 			RefreshRotate:            getEnvBool("JWT_REFRESH_ROTATE", true),               // 默认启用轮换
 			RefreshReuseDetection:    getEnvBool("JWT_REFRESH_REUSE_DETECTION", true),     // 默认启用复用检测
 		},
+		Tunnel: TunnelConfig{
+			MinPort:       getEnvInt("TUNNEL_MIN_PORT", 20000),
+			MaxPort:       getEnvInt("TUNNEL_MAX_PORT", 29999),
+			RemoteTargets: getEnvList("TUNNEL_REMOTE_TARGETS"),
+		},
 	}
 
 	// 根据运行环境自动设置配置
@@ -222,6 +238,11 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT refresh token absolute expiration must be greater than or equal to idle expiration")
 	}
 
+	// 隧道配置验证
+	if c.Tunnel.MinPort < 1 || c.Tunnel.MaxPort > 65535 || c.Tunnel.MinPort > c.Tunnel.MaxPort {
+		return fmt.Errorf("tunnel port range must be within 1-65535 and min port must not exceed max port")
+	}
+
 	return nil
 }
 
@@ -270,3 +291,14 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return value
 }
+
+// 辅助函数：获取环境变量（逗号分隔的列表，忽略空项）
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,146 @@
+package netproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Type 代理类型
+type Type string
+
+const (
+	TypeNone   Type = "none"   // 不使用代理（直连）
+	TypeSOCKS5 Type = "socks5" // SOCKS5 代理
+	TypeHTTP   Type = "http"   // HTTP CONNECT 代理
+)
+
+var (
+	ErrInvalidType  = errors.New("proxy type must be none, socks5 or http")
+	ErrHostRequired = errors.New("proxy host and port are required")
+)
+
+// Config 代理配置
+type Config struct {
+	Type     Type   `json:"type"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Enabled 是否需要经由代理连接
+func (c *Config) Enabled() bool {
+	return c != nil && (c.Type == TypeSOCKS5 || c.Type == TypeHTTP)
+}
+
+// Validate 验证代理配置
+func (c *Config) Validate() error {
+	switch c.Type {
+	case "", TypeNone:
+		return nil
+	case TypeSOCKS5, TypeHTTP:
+		if c.Host == "" || c.Port <= 0 || c.Port > 65535 {
+			return ErrHostRequired
+		}
+		return nil
+	default:
+		return ErrInvalidType
+	}
+}
+
+// Addr 代理服务器地址
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// Dial 建立到 addr 的 TCP 连接，配置了代理时经由代理转发
+func Dial(cfg *Config, addr string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !cfg.Enabled() {
+		return dialer.Dial("tcp", addr)
+	}
+
+	switch cfg.Type {
+	case TypeSOCKS5:
+		var auth *proxy.Auth
+		if cfg.Username != "" {
+			auth = &proxy.Auth{User: cfg.Username, Password: cfg.Password}
+		}
+		socks, err := proxy.SOCKS5("tcp", cfg.Addr(), auth, dialer)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	default:
+		return dialHTTPConnect(dialer, cfg, addr, timeout)
+	}
+}
+
+// dialHTTPConnect 通过 HTTP CONNECT 隧道连接
+func dialHTTPConnect(dialer *net.Dialer, cfg *Config, addr string, timeout time.Duration) (net.Conn, error) {
+	conn, err := dialer.Dial("tcp", cfg.Addr())
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if cfg.Username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT request: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	// 代理在响应后紧接着发送的数据已被读入缓冲区，需要先返回这部分数据
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读取缓冲区中剩余数据的连接
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}